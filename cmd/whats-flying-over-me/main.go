@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/benvon/whats-flying-over-me/internal/cataloger"
@@ -12,10 +16,32 @@ import (
 )
 
 // AircraftFetcher defines the interface for fetching aircraft data.
-type AircraftFetcher func(url string) ([]piaware.Aircraft, error)
+type AircraftFetcher func(ctx context.Context, url string) ([]piaware.Aircraft, error)
+
+// minStreamCycleInterval limits how often streaming position updates trigger
+// a monitoring cycle.
+const minStreamCycleInterval = time.Second
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		err := runReplay(ctx, os.Args[2:])
+		stop()
+		if err != nil {
+			logger.Critical("replay failed", map[string]interface{}{"error": err.Error()})
+			os.Exit(1)
+		}
+		return
+	}
+
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		logger.Critical("invalid configuration", map[string]interface{}{"error": err.Error()})
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
 	n, err := notifier.New(cfg.Notifier)
 	if err != nil {
@@ -38,8 +64,23 @@ func main() {
 		}
 	}()
 
+	source, err := newAircraftSource(ctx, cfg)
+	if err != nil {
+		logger.Critical("failed to initialize data source", map[string]interface{}{"error": err.Error()})
+		return
+	}
+	defer func() {
+		if err := source.Close(); err != nil {
+			logger.Err("failed to close data source", map[string]interface{}{"error": err.Error()})
+		}
+	}()
+
 	// Create monitoring service
-	monitorService := NewMonitorService(cfg, n, deduplicator, stats, piaware.Fetch, catalogerInstance)
+	monitorService := NewMonitorService(cfg, n, deduplicator, stats, source.fetcher, catalogerInstance)
+	if err := monitorService.loadResources(ctx); err != nil {
+		logger.Critical("failed to initialize monitoring", map[string]interface{}{"error": err.Error()})
+		return
+	}
 
 	ticker := time.NewTicker(cfg.ScrapeInterval)
 	defer ticker.Stop()
@@ -53,33 +94,98 @@ func main() {
 		"scrape_interval":   cfg.ScrapeInterval.String(),
 		"radius_km":         cfg.RadiusKm,
 		"altitude_max":      cfg.AltitudeMax,
+		"altitude_min":      cfg.AltitudeMin,
+		"altitude_source":   cfg.AltitudeSource,
+		"qnh":               cfg.QNH,
 		"base_lat":          cfg.BaseLat,
 		"base_lon":          cfg.BaseLon,
+		"distance_mode":     cfg.DistanceMode,
 		"data_url":          cfg.DataURL,
+		"receivers":         len(cfg.Receivers),
+		"geofence_file":     cfg.GeofenceFile,
+		"zones":             len(cfg.Zones),
+		"capture_dir":       cfg.Capture.Dir,
 		"console_logging":   cfg.Notifier.Console,
 		"webhook_enabled":   cfg.Notifier.Webhook.Enabled,
 		"rabbitmq_enabled":  cfg.Notifier.RabbitMQ.Enabled,
 		"dedupe_enabled":    cfg.AlertDedupe.Enabled,
 		"blockout_min":      cfg.AlertDedupe.BlockoutMin.String(),
+		"tracker_enabled":   cfg.Tracker.Enabled,
+		"prediction":        cfg.Prediction.Enabled,
+		"observer_elev_ft":  cfg.Observer.ElevationFt,
+		"min_elevation_deg": cfg.Observer.MinElevationDeg,
+		"registry_file":     cfg.Registry.File,
+		"airlines_file":     cfg.AirlinesFile,
+		"routes_file":       cfg.Routes.File,
+		"alert_rules":       len(cfg.Rules),
+		"watchlist":         !cfg.Watchlist.Empty(),
 		"cataloger_enabled": cfg.Cataloger.Enabled,
 	})
 
+	runCycle := func() {
+		runMonitoringCycle(ctx, monitorService, stats)
+	}
+
 	// Start monitoring loop
+	monitorLoop(ctx, ticker.C, source.updates, heartbeatTicker.C, runCycle, func() { logHeartbeat(stats) }, minStreamCycleInterval)
+	logger.Info("shutting down", nil)
+}
+
+// monitorLoop runs a cycle on every scrape tick and on updates from
+// streaming sources, so alerts are not held back until the next tick, until
+// ctx is cancelled. Updates run at most one cycle per minInterval; an update
+// arriving sooner is held and runs a cycle once the interval has passed.
+func monitorLoop(ctx context.Context, ticks <-chan time.Time, updates <-chan struct{}, heartbeats <-chan time.Time, runCycle, heartbeat func(), minInterval time.Duration) {
+	throttle := time.NewTimer(minInterval)
+	throttle.Stop()
+	defer throttle.Stop()
+	pending := false
+
+	var lastCycle time.Time
+	cycle := func() {
+		if pending {
+			throttle.Stop()
+			pending = false
+		}
+		runCycle()
+		lastCycle = time.Now()
+	}
+
 	for {
 		select {
-		case <-ticker.C:
-			if err := monitorService.RunMonitoringCycle(); err != nil {
-				logger.Err("check failed", map[string]interface{}{"error": err.Error()})
-				stats.RecordScrapeFailure()
-			} else {
-				stats.RecordScrape()
+		case <-ctx.Done():
+			return
+		case <-ticks:
+			cycle()
+		case <-updates:
+			if pending {
+				continue
+			}
+			if wait := minInterval - time.Since(lastCycle); wait > 0 {
+				throttle.Reset(wait)
+				pending = true
+				continue
 			}
-		case <-heartbeatTicker.C:
-			logHeartbeat(stats)
+			cycle()
+		case <-throttle.C:
+			pending = false
+			cycle()
+		case <-heartbeats:
+			heartbeat()
 		}
 	}
 }
 
+// runMonitoringCycle runs one monitoring cycle and records its outcome.
+func runMonitoringCycle(ctx context.Context, monitorService *MonitorService, stats *notifier.Stats) {
+	if err := monitorService.RunMonitoringCycle(ctx); err != nil {
+		logger.Err("check failed", map[string]interface{}{"error": err.Error()})
+		stats.RecordScrapeFailure()
+	} else {
+		stats.RecordScrape()
+	}
+}
+
 // logHeartbeat logs periodic heartbeat information about program status.
 func logHeartbeat(stats *notifier.Stats) {
 	statsData := stats.GetStats()
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/benvon/whats-flying-over-me/internal/notifier"
 )
@@ -185,3 +187,41 @@ func TestLogHeartbeatDataTypes(t *testing.T) {
 		}
 	}
 }
+
+func TestMonitorLoopRunsThrottledUpdates(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	updates := make(chan struct{})
+	cycles := make(chan time.Time, 10)
+	done := make(chan struct{})
+	go func() {
+		monitorLoop(ctx, nil, updates, nil, func() { cycles <- time.Now() }, func() {}, 100*time.Millisecond)
+		close(done)
+	}()
+
+	start := time.Now()
+	updates <- struct{}{}
+	<-cycles
+
+	// Updates within the interval are held, then run a single cycle once
+	// it has passed, without waiting for a scrape tick.
+	updates <- struct{}{}
+	updates <- struct{}{}
+	select {
+	case at := <-cycles:
+		if elapsed := at.Sub(start); elapsed < 100*time.Millisecond {
+			t.Errorf("expected the held update to wait for the interval, ran after %v", elapsed)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the held update to run a cycle")
+	}
+	select {
+	case <-cycles:
+		t.Error("expected held updates to run a single cycle")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	cancel()
+	<-done
+}
@@ -2,14 +2,27 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/benvon/whats-flying-over-me/internal/airline"
 	"github.com/benvon/whats-flying-over-me/internal/cataloger"
 	"github.com/benvon/whats-flying-over-me/internal/config"
+	"github.com/benvon/whats-flying-over-me/internal/geofence"
+	"github.com/benvon/whats-flying-over-me/internal/geometry"
+	"github.com/benvon/whats-flying-over-me/internal/icao"
 	"github.com/benvon/whats-flying-over-me/internal/logger"
 	"github.com/benvon/whats-flying-over-me/internal/notifier"
 	"github.com/benvon/whats-flying-over-me/internal/piaware"
+	"github.com/benvon/whats-flying-over-me/internal/registry"
+	"github.com/benvon/whats-flying-over-me/internal/route"
+	"github.com/benvon/whats-flying-over-me/internal/rules"
+	"github.com/benvon/whats-flying-over-me/internal/tracker"
+	"github.com/benvon/whats-flying-over-me/internal/watchlist"
 )
 
 // MonitorService handles the aircraft monitoring logic.
@@ -20,23 +33,133 @@ type MonitorService struct {
 	stats        *notifier.Stats
 	fetcher      AircraftFetcher
 	cataloger    cataloger.Cataloger
+	// zones are the circular areas monitored, from cfg.MonitorZones.
+	zones []config.ZoneConfig
+	// geofence replaces the zones when polygon zones are configured.
+	geofence *geofence.Fence
+	// rules replace the zones and geofence when alert rules are configured.
+	rules []*rules.Compiled
+	// registry fills in aircraft details, when an aircraft database is
+	// configured.
+	registry *registry.Database
+	// airlines decodes callsigns into the airline and flight number.
+	airlines *airline.Table
+	// routes adds origin and destination, when a routes file is configured.
+	routes *route.Table
+	// states holds the alert state of each zone, by zone name.
+	states map[string]*zoneState
+	// predictions blocks repeated approach warnings for each circular zone,
+	// by zone name. They are kept apart from the zone states, so a warning
+	// does not hold back the alert when the aircraft arrives.
+	predictions map[string]*notifier.Deduplicator
+	// observer is where look angles are measured from.
+	observer geometry.Observer
+	// emergencies holds the emergency each aircraft was last alerted for, by
+	// hex, so an emergency is alerted once and again when it changes.
+	emergencies map[string]emergency
+	// watchlist picks out aircraft to alert on wherever they are, when one
+	// is configured, and watchlistDedupe blocks repeated sightings.
+	watchlist       *watchlist.List
+	watchlistDedupe *notifier.Deduplicator
+	// overheadDedupe blocks repeated elevation alerts.
+	overheadDedupe *notifier.Deduplicator
+	now            func() time.Time
+}
+
+// zoneState is the alert state kept for one zone, so aircraft are alerted
+// and tracked independently in each zone.
+type zoneState struct {
+	deduplicator *notifier.Deduplicator
+	tracker      *tracker.Tracker
 }
 
 // NewMonitorService creates a new monitoring service.
-func NewMonitorService(cfg config.Config, notifier notifier.Notifier, deduplicator *notifier.Deduplicator, stats *notifier.Stats, fetcher AircraftFetcher, cataloger cataloger.Cataloger) *MonitorService {
-	return &MonitorService{
+// Aircraft passing through the area are tracked when cfg.Tracker is enabled,
+// and aircraft approaching a circular zone are warned about when
+// cfg.Prediction is enabled.
+// Named zones each get their own deduplicator using the zone's blockout;
+// the unnamed default zone and geofence zones share deduplicator, which
+// keeps each zone's alerts apart. Aircraft high in the sky are alerted on
+// when cfg.Observer.MinElevationDeg is set.
+func NewMonitorService(cfg config.Config, n notifier.Notifier, deduplicator *notifier.Deduplicator, stats *notifier.Stats, fetcher AircraftFetcher, cataloger cataloger.Cataloger) *MonitorService {
+	m := &MonitorService{
 		cfg:          cfg,
-		notifier:     notifier,
+		notifier:     n,
 		deduplicator: deduplicator,
 		stats:        stats,
 		fetcher:      fetcher,
 		cataloger:    cataloger,
+		zones:        cfg.MonitorZones(),
+		states:       make(map[string]*zoneState),
+		predictions:  make(map[string]*notifier.Deduplicator),
+		emergencies:  make(map[string]emergency),
+		observer:     cfg.BaseObserver(),
+		airlines:     airline.Bundled(),
+		now:          time.Now,
+	}
+	if cfg.Observer.MinElevationDeg > 0 {
+		m.overheadDedupe = notifier.NewDeduplicatorWithClock(cfg.AlertDedupe, func() time.Time { return m.now() })
+	}
+	return m
+}
+
+// zone returns the alert state for the named zone, creating it on first use.
+func (m *MonitorService) zone(name string) *zoneState {
+	if s, ok := m.states[name]; ok {
+		return s
+	}
+
+	clock := func() time.Time { return m.now() }
+	dedupeCfg := m.cfg.AlertDedupe
+	s := &zoneState{deduplicator: m.deduplicator}
+	for _, z := range m.zones {
+		if z.Name == name {
+			dedupeCfg.BlockoutMin = z.BlockoutMin
+			if name != "" {
+				s.deduplicator = notifier.NewDeduplicatorWithClock(dedupeCfg, clock)
+			}
+			break
+		}
+	}
+	if r := m.rule(name); r != nil {
+		if r.BlockoutMin > 0 {
+			dedupeCfg.BlockoutMin = r.BlockoutMin
+		}
+		s.deduplicator = notifier.NewDeduplicatorWithClock(dedupeCfg, clock)
+	}
+	if m.cfg.Tracker.Enabled {
+		s.tracker = tracker.New(m.cfg.Tracker)
 	}
+	m.states[name] = s
+	return s
+}
+
+// prediction returns the approach warning deduplicator for a circular zone,
+// creating it on first use.
+func (m *MonitorService) prediction(z config.ZoneConfig) *notifier.Deduplicator {
+	if d, ok := m.predictions[z.Name]; ok {
+		return d
+	}
+	dedupeCfg := m.cfg.AlertDedupe
+	dedupeCfg.BlockoutMin = z.BlockoutMin
+	d := notifier.NewDeduplicatorWithClock(dedupeCfg, func() time.Time { return m.now() })
+	m.predictions[z.Name] = d
+	return d
+}
+
+// rule returns the alert rule with the given name, or nil.
+func (m *MonitorService) rule(name string) *rules.Compiled {
+	for _, r := range m.rules {
+		if r.Name == name {
+			return r
+		}
+	}
+	return nil
 }
 
 // RunMonitoringCycle executes one monitoring cycle.
-func (m *MonitorService) RunMonitoringCycle() error {
-	return m.check()
+func (m *MonitorService) RunMonitoringCycle(ctx context.Context) error {
+	return m.check(ctx)
 }
 
 // GetStats returns the current statistics.
@@ -45,11 +168,24 @@ func (m *MonitorService) GetStats() map[string]interface{} {
 }
 
 // check performs the aircraft check logic.
-func (m *MonitorService) check() error {
-	aircraft, err := m.fetcher(m.cfg.DataURL)
+func (m *MonitorService) check(ctx context.Context) error {
+	aircraft, err := m.fetcher(ctx, m.cfg.DataURL)
+	if errors.Is(err, piaware.ErrNotModified) {
+		// The receiver has not written a new snapshot since the last cycle.
+		logger.Debug("aircraft data not modified, skipping cycle", nil)
+		return nil
+	}
 	if err != nil {
 		return err
 	}
+	icao.Enrich(aircraft)
+	m.airlines.Enrich(aircraft)
+	if m.routes != nil {
+		m.routes.Enrich(aircraft)
+	}
+	if m.registry != nil {
+		m.registry.Enrich(aircraft)
+	}
 
 	// Record all aircraft seen for statistics
 	for _, a := range aircraft {
@@ -57,78 +193,659 @@ func (m *MonitorService) check() error {
 	}
 
 	// Catalog all aircraft data
-	if err := m.cataloger.CatalogAircraft(context.Background(), aircraft, m.cfg.BaseLat, m.cfg.BaseLon); err != nil {
+	if err := m.cataloger.CatalogAircraft(ctx, aircraft, m.observer); err != nil {
 		// Log cataloging failure but continue with monitoring
 		logger.Err("failed to catalog aircraft data", map[string]interface{}{
 			"error": err.Error(),
 		})
 	}
 
-	nearby := piaware.FilterAircraft(aircraft, m.cfg.BaseLat, m.cfg.BaseLon, m.cfg.RadiusKm, m.cfg.AltitudeMax)
+	// dump1090 keeps reporting the last position for a while after an
+	// aircraft stops sending them; ignore positions that are too old.
+	fresh, stale := piaware.FreshAircraft(aircraft, m.cfg.MaxPositionAge)
+	if stale > 0 {
+		logger.Debug("ignoring aircraft with stale positions", map[string]interface{}{
+			"stale_aircraft":   stale,
+			"max_position_age": m.cfg.MaxPositionAge.String(),
+		})
+	}
+
+	nearby := m.filter(fresh)
+
+	now := m.now()
+	// Emergencies are looked for in the whole feed, whatever the age of the
+	// position, and go first.
+	alerts := m.emergencyAlerts(now, aircraft)
+	if m.watchlist != nil {
+		alerts = append(alerts, m.watchlistAlerts(now, aircraft)...)
+	}
+	if m.cfg.Tracker.Enabled {
+		// Aircraft without a position, which only rules select, cannot be
+		// tracked and are alerted on as they are seen instead.
+		var located, unlocated []piaware.NearbyAircraft
+		for _, a := range nearby {
+			if a.Lat == 0 && a.Lon == 0 {
+				unlocated = append(unlocated, a)
+			} else {
+				located = append(located, a)
+			}
+		}
+		alerts = append(alerts, m.trackAlerts(now, located)...)
+		alerts = append(alerts, m.nearbyAlerts(now, unlocated)...)
+	} else {
+		alerts = append(alerts, m.nearbyAlerts(now, nearby)...)
+	}
+	if m.cfg.Prediction.Enabled {
+		alerts = append(alerts, m.predictionAlerts(now, fresh, nearby)...)
+	}
+	if m.overheadDedupe != nil {
+		alerts = append(alerts, m.overheadAlerts(now, fresh)...)
+	}
 
 	if len(nearby) == 0 {
 		// Log that no aircraft are in range
-		logger.Info("no aircraft in range", map[string]interface{}{
-			"total_aircraft": len(aircraft),
-			"radius_km":      m.cfg.RadiusKm,
-			"altitude_max":   m.cfg.AltitudeMax,
+		fields := m.rangeFields()
+		fields["total_aircraft"] = len(aircraft)
+		logger.Info("no aircraft in range", fields)
+	} else {
+		logger.Info("aircraft detected in range", map[string]interface{}{
+			"aircraft_count": len(nearby),
+			"total_seen":     len(aircraft),
 		})
-		return nil
 	}
 
 	alertCount := 0
-	logger.Info("aircraft detected in range", map[string]interface{}{
-		"aircraft_count": len(nearby),
-		"total_seen":     len(aircraft),
-	})
+	for _, alert := range alerts {
+		m.applyRule(&alert)
+		m.enrich(&alert)
+		if m.sendAlert(alert) {
+			alertCount++
+		}
+	}
 
+	if alertCount > 0 {
+		logger.Info("monitoring cycle completed", map[string]interface{}{
+			"alerts_sent":       alertCount,
+			"aircraft_in_range": len(nearby),
+		})
+	}
+
+	return nil
+}
+
+// filter returns the aircraft matching the alert rules when rules are
+// configured, the aircraft inside the geofence zones when a geofence is
+// loaded, or else the aircraft inside each circular zone. An aircraft inside
+// several circular zones is returned once for each, with its distance from
+// that zone's center. Circular zones are queried through a spatial index
+// built once per cycle, so large feeds are only distance-tested near each
+// zone.
+func (m *MonitorService) filter(aircraft []piaware.Aircraft) []piaware.NearbyAircraft {
+	if m.rules != nil {
+		return m.match(aircraft)
+	}
+	if m.geofence != nil {
+		return piaware.FilterAircraftInZones(aircraft, m.cfg.BaseLat, m.cfg.BaseLon, m.geofence.Match, m.cfg.DistanceMode)
+	}
+
+	index := piaware.NewIndex(aircraft, 0)
+	var nearby []piaware.NearbyAircraft
+	for _, z := range m.zones {
+		for _, a := range index.Within(z.BaseLat, z.BaseLon, z.RadiusKm, m.cfg.AltitudeFilter(z.Floor(), z.Ceiling()), m.cfg.DistanceMode) {
+			a.Zone = z.Name
+			nearby = append(nearby, a)
+		}
+	}
+	return nearby
+}
+
+// rangeFields describes what decides which aircraft are in range, for
+// logging: the alert rules, geofence zones or named zones when configured,
+// or else the radius and altitude limits.
+func (m *MonitorService) rangeFields() map[string]interface{} {
+	switch {
+	case m.rules != nil:
+		return map[string]interface{}{"alert_rules": len(m.rules)}
+	case m.geofence != nil:
+		return map[string]interface{}{"geofence_zones": len(m.geofence.Zones)}
+	case len(m.cfg.Zones) > 0:
+		return map[string]interface{}{"zones": len(m.zones)}
+	}
+	return map[string]interface{}{
+		"radius_km":    m.cfg.RadiusKm,
+		"altitude_min": m.cfg.AltitudeMin,
+		"altitude_max": m.cfg.AltitudeMax,
+	}
+}
+
+// match returns the aircraft matching each alert rule, once for every rule
+// they match, with their distance from the base. Aircraft without a position
+// are matched too, with their distance and elevation unknown. The rule name
+// doubles as the zone name, so each rule keeps its own alert state.
+func (m *MonitorService) match(aircraft []piaware.Aircraft) []piaware.NearbyAircraft {
+	alt := m.cfg.AltitudeFilter(0, 0)
+	var nearby []piaware.NearbyAircraft
+	for _, a := range aircraft {
+		subject := rules.Subject{
+			Aircraft:     a,
+			DistanceKm:   math.NaN(),
+			Altitude:     alt.Altitude(a),
+			ElevationDeg: math.NaN(),
+		}
+		located := a.Lat != 0 || a.Lon != 0
+		if located {
+			subject.DistanceKm = m.observer.DistanceKm(a.Lat, a.Lon)
+			subject.ElevationDeg = m.observer.LookAt(a).ElevationDeg
+		}
+		for _, r := range m.rules {
+			if !r.Expr.Match(&subject) {
+				continue
+			}
+			na := piaware.NearbyAircraft{Aircraft: a, Zone: r.Name, Rule: r.Name}
+			if located {
+				na.DistanceKm = subject.DistanceKm
+			}
+			nearby = append(nearby, na)
+		}
+	}
+	return nearby
+}
+
+// nearbyAlerts raises an "aircraft_nearby" alert for every aircraft in range
+// that is not blocked by the deduplicator.
+func (m *MonitorService) nearbyAlerts(now time.Time, nearby []piaware.NearbyAircraft) []notifier.AlertData {
+	var alerts []notifier.AlertData
 	for _, a := range nearby {
 		// Check if we should send an alert for this aircraft
-		if !m.deduplicator.ShouldAlert(a) {
+		if !m.zone(a.Zone).deduplicator.ShouldAlert(a) {
 			// Skip duplicate alerts silently
 			continue
 		}
 
-		// Create alert data
-		alert := notifier.AlertData{
-			Timestamp:   time.Now(),
+		where := "position unknown"
+		if a.Lat != 0 || a.Lon != 0 {
+			where = fmt.Sprintf("within %.1f km", a.DistanceKm)
+		}
+		alerts = append(alerts, notifier.AlertData{
+			Timestamp:   now,
 			Aircraft:    a,
 			AlertType:   "aircraft_nearby",
-			Description: fmt.Sprintf("Aircraft %s detected within %.1f km at %d ft altitude", a.Hex, a.DistanceKm, a.AltBaro),
+			Description: fmt.Sprintf("Aircraft %s detected %s %s%s", describeAircraft(a.Aircraft), where, describeAltitude(a.Aircraft), describeZone(a)),
+			Zone:        a.Zone,
+		})
+	}
+	return alerts
+}
+
+// trackAlerts feeds the aircraft in range to each zone's tracker and raises
+// an alert for each lifecycle event. Each event is raised once per track, so
+// the deduplicator is not consulted.
+func (m *MonitorService) trackAlerts(now time.Time, nearby []piaware.NearbyAircraft) []notifier.AlertData {
+	byZone := make(map[string][]piaware.NearbyAircraft)
+	for _, a := range nearby {
+		m.zone(a.Zone)
+		byZone[a.Zone] = append(byZone[a.Zone], a)
+	}
+
+	// Zones with no aircraft left are updated too, so their tracks close.
+	names := make([]string, 0, len(m.states))
+	for name := range m.states {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var events []tracker.Event
+	for _, name := range names {
+		events = append(events, m.states[name].tracker.Update(now, byZone[name])...)
+	}
+
+	alerts := make([]notifier.AlertData, 0, len(events))
+	for _, e := range events {
+		a := e.Aircraft
+		alert := notifier.AlertData{
+			Timestamp:     now,
+			Aircraft:      a,
+			AlertType:     e.Type,
+			TrackID:       e.TrackID,
+			MinDistanceKm: e.MinDistanceKm,
+			Zone:          a.Zone,
 		}
 
-		// Send notification
-		if err := m.notifier.Notify(alert); err != nil {
-			// Log notification failure but continue with other aircraft
-			logger.Err("failed to send notification", map[string]interface{}{
-				"aircraft_hex": a.Hex,
-				"error":        err.Error(),
+		switch e.Type {
+		case tracker.EventEntered:
+			alert.Description = fmt.Sprintf("Aircraft %s entered the area %.1f km away %s%s", describeAircraft(a.Aircraft), a.DistanceKm, describeAltitude(a.Aircraft), describeZone(a))
+		case tracker.EventClosestApproach:
+			alert.Description = fmt.Sprintf("Aircraft %s closest approach %.1f km %s", describeAircraft(a.Aircraft), e.MinDistanceKm, describeAltitude(a.Aircraft))
+		case tracker.EventExited:
+			alert.DwellSeconds = e.Dwell.Seconds()
+			alert.Description = fmt.Sprintf("Aircraft %s left the area after %s, closest approach %.1f km", describeAircraft(a.Aircraft), e.Dwell, e.MinDistanceKm)
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts
+}
+
+// predictionAlerts raises an "aircraft_approaching" alert for aircraft not
+// yet in range of any zone that are predicted to come within a circular
+// zone's radius inside the configured lookahead.
+func (m *MonitorService) predictionAlerts(now time.Time, aircraft []piaware.Aircraft, nearby []piaware.NearbyAircraft) []notifier.AlertData {
+	inRange := make(map[string]bool, len(nearby))
+	for _, a := range nearby {
+		inRange[a.Hex] = true
+	}
+
+	var alerts []notifier.AlertData
+	for _, z := range m.zones {
+		alt := m.cfg.AltitudeFilter(z.Floor(), z.Ceiling())
+		for _, a := range aircraft {
+			if inRange[a.Hex] || a.OnGround || !alt.Allows(a) {
+				continue
+			}
+			approach, ok := piaware.PredictApproach(a, z.BaseLat, z.BaseLon, z.RadiusKm)
+			if !ok || approach.EnterIn > m.cfg.Prediction.Lookahead {
+				continue
+			}
+
+			na := piaware.NearbyAircraft{Aircraft: a, DistanceKm: approach.DistanceKm, Zone: z.Name}
+			if !m.prediction(z).ShouldAlert(na) {
+				continue
+			}
+
+			alerts = append(alerts, notifier.AlertData{
+				Timestamp:              now,
+				Aircraft:               na,
+				AlertType:              "aircraft_approaching",
+				Description:            fmt.Sprintf("Aircraft %s predicted to pass within %.1f km%s in %s %s", describeAircraft(a), approach.MinDistanceKm, describeZone(na), approach.ETA.Round(time.Second), describeAltitude(a)),
+				PredictedMinDistanceKm: approach.MinDistanceKm,
+				ETASeconds:             approach.ETA.Seconds(),
+				Zone:                   z.Name,
 			})
+		}
+	}
+	return alerts
+}
+
+// overheadAlerts raises an "aircraft_overhead" alert for airborne aircraft
+// within the altitude limits that are at least MinElevationDeg above the
+// observer's horizon, wherever they are.
+func (m *MonitorService) overheadAlerts(now time.Time, aircraft []piaware.Aircraft) []notifier.AlertData {
+	alt := m.cfg.AltitudeFilter(m.cfg.AltitudeMin, m.cfg.AltitudeMax)
+	var alerts []notifier.AlertData
+	for _, a := range piaware.FilterAircraftWithin(aircraft, m.cfg.BaseLat, m.cfg.BaseLon, math.Inf(1), alt, m.cfg.DistanceMode) {
+		if a.OnGround {
+			continue
+		}
+		look := m.observer.LookAt(a.Aircraft)
+		if look.ElevationDeg < m.cfg.Observer.MinElevationDeg {
+			continue
+		}
+		if !m.overheadDedupe.ShouldAlert(a) {
 			continue
 		}
 
-		logger.Info("aircraft alert sent", map[string]interface{}{
+		alerts = append(alerts, notifier.AlertData{
+			Timestamp:   now,
+			Aircraft:    a,
+			AlertType:   "aircraft_overhead",
+			Description: fmt.Sprintf("Aircraft %s high overhead %.1f km away %s", describeAircraft(a.Aircraft), a.DistanceKm, describeAltitude(a.Aircraft)),
+		})
+	}
+	return alerts
+}
+
+// emergency is an emergency an aircraft was alerted for.
+type emergency struct {
+	status string
+	squawk string
+}
+
+// emergencyAlerts raises a high priority "aircraft_emergency" alert for any
+// aircraft in the feed declaring an emergency, through its squawk or the
+// emergency field, and again whenever the emergency changes. An
+// "aircraft_emergency_cleared" alert follows when the aircraft stops
+// declaring it. Emergencies are never deduplicated.
+func (m *MonitorService) emergencyAlerts(now time.Time, aircraft []piaware.Aircraft) []notifier.AlertData {
+	var alerts []notifier.AlertData
+	seen := make(map[string]bool, len(aircraft))
+	for _, a := range aircraft {
+		seen[a.Hex] = true
+		current := emergency{status: a.EmergencyStatus(), squawk: a.EmergencySquawk()}
+		previous, declared := m.emergencies[a.Hex]
+		if current == previous {
+			continue
+		}
+
+		na := piaware.NearbyAircraft{Aircraft: a}
+		where := "position unknown"
+		if a.Lat != 0 || a.Lon != 0 {
+			na.DistanceKm = m.observer.DistanceKm(a.Lat, a.Lon)
+			where = fmt.Sprintf("%.1f km away", na.DistanceKm)
+		}
+
+		if current.status == "" {
+			delete(m.emergencies, a.Hex)
+			if declared {
+				alerts = append(alerts, notifier.AlertData{
+					Timestamp:   now,
+					Aircraft:    na,
+					AlertType:   "aircraft_emergency_cleared",
+					Description: fmt.Sprintf("Aircraft %s no longer declaring %s %s %s", describeAircraft(a), describeEmergency(previous), where, describeAltitude(a)),
+				})
+			}
+			continue
+		}
+
+		m.emergencies[a.Hex] = current
+		alerts = append(alerts, notifier.AlertData{
+			Timestamp:   now,
+			Aircraft:    na,
+			AlertType:   "aircraft_emergency",
+			Description: fmt.Sprintf("Aircraft %s declaring %s %s %s", describeAircraft(a), describeEmergency(current), where, describeAltitude(a)),
+			Priority:    notifier.PriorityHigh,
+		})
+	}
+
+	// Aircraft that left the feed are forgotten, so a later emergency is
+	// alerted afresh.
+	for hex := range m.emergencies {
+		if !seen[hex] {
+			delete(m.emergencies, hex)
+		}
+	}
+	return alerts
+}
+
+// watchlistAlerts raises a "watchlist_seen" alert for any aircraft in the
+// feed on the watchlist, wherever it is. Sightings are deduplicated with the
+// watchlist's own blockout.
+func (m *MonitorService) watchlistAlerts(now time.Time, aircraft []piaware.Aircraft) []notifier.AlertData {
+	var alerts []notifier.AlertData
+	for _, a := range aircraft {
+		reason, ok := m.watchlist.Match(a)
+		if !ok {
+			continue
+		}
+
+		na := piaware.NearbyAircraft{Aircraft: a}
+		where := "position unknown"
+		if a.Lat != 0 || a.Lon != 0 {
+			na.DistanceKm = m.observer.DistanceKm(a.Lat, a.Lon)
+			where = fmt.Sprintf("%.1f km away", na.DistanceKm)
+		}
+		if !m.watchlistDedupe.ShouldAlert(na) {
+			continue
+		}
+
+		alerts = append(alerts, notifier.AlertData{
+			Timestamp:   now,
+			Aircraft:    na,
+			AlertType:   "watchlist_seen",
+			Description: fmt.Sprintf("Watched aircraft %s seen by %s %s %s", describeAircraft(a), reason, where, describeAltitude(a)),
+		})
+	}
+	return alerts
+}
+
+// describeEmergency renders an emergency for alert descriptions, with the
+// squawk when it is an emergency code.
+func describeEmergency(e emergency) string {
+	if e.squawk == "" {
+		return piaware.DescribeEmergency(e.status)
+	}
+	return fmt.Sprintf("%s (squawk %s)", piaware.DescribeEmergency(e.status), e.squawk)
+}
+
+// applyRule marks an alert raised for an aircraft selected by a rule with
+// the rule's name and severity, and routes it to the rule's notifiers.
+func (m *MonitorService) applyRule(alert *notifier.AlertData) {
+	r := m.rule(alert.Aircraft.Rule)
+	if r == nil {
+		return
+	}
+	alert.Zone = ""
+	alert.Rule = r.Name
+	alert.Severity = r.Severity
+	alert.Notifiers = r.Notifiers
+}
+
+// enrich adds what is known about the aircraft to an alert before it is
+// sent: its look angles from the observer.
+func (m *MonitorService) enrich(alert *notifier.AlertData) {
+	a := alert.Aircraft
+	if a.Lat == 0 && a.Lon == 0 {
+		return
+	}
+	look := m.observer.LookAt(a.Aircraft)
+	alert.AzimuthDeg = look.AzimuthDeg
+	alert.ElevationDeg = look.ElevationDeg
+	alert.SlantRangeKm = look.SlantRangeKm
+	alert.Direction = look.Direction
+	alert.Description += fmt.Sprintf(", %s at %.0f° elevation", look.Direction, look.ElevationDeg)
+}
+
+// sendAlert delivers an alert and reports whether it was sent.
+func (m *MonitorService) sendAlert(alert notifier.AlertData) bool {
+	a := alert.Aircraft
+	if err := m.notifier.Notify(alert); err != nil {
+		// Log notification failure but continue with other aircraft
+		logger.Err("failed to send notification", map[string]interface{}{
 			"aircraft_hex": a.Hex,
-			"flight":       a.Flight,
-			"distance_km":  a.DistanceKm,
-			"altitude_ft":  a.AltBaro,
-			"lat":          a.Lat,
-			"lon":          a.Lon,
+			"alert_type":   alert.AlertType,
+			"error":        err.Error(),
+		})
+		return false
+	}
+
+	fields := map[string]interface{}{
+		"alert_type":   alert.AlertType,
+		"aircraft_hex": a.Hex,
+		"flight":       a.Flight,
+		"distance_km":  a.DistanceKm,
+		"altitude_ft":  a.AltBaro,
+		"on_ground":    a.OnGround,
+		"ground_speed": a.GS,
+		"track":        a.Track,
+		"squawk":       a.Squawk,
+		"position_age": a.PositionAge,
+		"lat":          a.Lat,
+		"lon":          a.Lon,
+	}
+	if a.Registration != "" {
+		fields["registration"] = a.Registration
+	}
+	if a.TypeCode != "" {
+		fields["type_code"] = a.TypeCode
+	}
+	if a.Airline != "" {
+		fields["airline"] = a.Airline
+	}
+	if a.Origin != "" {
+		fields["origin"] = a.Origin
+		fields["destination"] = a.Destination
+	}
+	if a.Country != "" {
+		fields["country"] = a.Country
+	}
+	if a.Military {
+		fields["military"] = true
+	}
+	if alert.Priority != "" {
+		fields["priority"] = alert.Priority
+	}
+	if alert.TrackID != "" {
+		fields["track_id"] = alert.TrackID
+	}
+	if alert.Rule != "" {
+		fields["rule"] = alert.Rule
+		fields["severity"] = alert.Severity
+	}
+	if alert.Zone != "" {
+		fields["zone"] = alert.Zone
+	}
+	if alert.Direction != "" {
+		fields["direction"] = alert.Direction
+		fields["elevation_deg"] = alert.ElevationDeg
+		fields["slant_range_km"] = alert.SlantRangeKm
+	}
+	logger.Info("aircraft alert sent", fields)
+	return true
+}
+
+// describeAircraft identifies an aircraft for alert descriptions by its
+// callsign, or its hex when it reports none, and its route, followed by what
+// is known of it: the airline and spoken callsign, the hex, registration and
+// type, and whether it is military.
+func describeAircraft(a piaware.Aircraft) string {
+	var known []string
+	name := a.Hex
+	if a.Callsign != "" {
+		name = a.Callsign
+		if a.Origin != "" {
+			name += fmt.Sprintf(" %s→%s", a.Origin, a.Destination)
+		}
+		if a.Airline != "" {
+			known = append(known, a.Airline)
+		}
+		if a.Telephony != "" {
+			known = append(known, a.Telephony+" "+a.FlightNumber)
+		}
+		known = append(known, a.Hex)
+	}
+	for _, s := range []string{a.Registration, a.TypeCode} {
+		if s != "" {
+			known = append(known, s)
+		}
+	}
+	if a.Military {
+		known = append(known, "military")
+	}
+	if len(known) == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s (%s)", name, strings.Join(known, ", "))
+}
+
+// describeAltitude renders the aircraft altitude for alert descriptions.
+func describeAltitude(a piaware.Aircraft) string {
+	if a.OnGround {
+		return "on the ground"
+	}
+	return fmt.Sprintf("at %d ft altitude", a.AltBaro)
+}
+
+// describeZone names the rule or zone an aircraft was matched in, if any.
+func describeZone(a piaware.NearbyAircraft) string {
+	if a.Rule != "" {
+		return fmt.Sprintf(" matching %s", a.Rule)
+	}
+	if a.Zone == "" {
+		return ""
+	}
+	return fmt.Sprintf(" in %s", a.Zone)
+}
+
+// loadResources compiles the alert rules and watchlist, and loads the
+// geofence, aircraft database, airline table and routes named in the
+// configuration. The aircraft database is reloaded in the background when
+// it changes, until ctx is cancelled.
+func (m *MonitorService) loadResources(ctx context.Context) error {
+	if len(m.cfg.Rules) > 0 {
+		compiled, err := rules.CompileAll(m.cfg.Rules, notifier.Names(m.cfg.Notifier))
+		if err != nil {
+			return fmt.Errorf("failed to compile alert rules: %w", err)
+		}
+		m.rules = compiled
+		logger.Info("loaded alert rules", map[string]interface{}{
+			"rules": len(compiled),
+		})
+	}
+
+	if !m.cfg.Watchlist.Empty() {
+		list, err := watchlist.New(m.cfg.Watchlist)
+		if err != nil {
+			return fmt.Errorf("failed to load watchlist: %w", err)
+		}
+		// The watchlist is always deduplicated, as watched aircraft would
+		// otherwise be alerted every cycle.
+		dedupeCfg := config.AlertDedupeConfig{Enabled: true, BlockoutMin: m.cfg.AlertDedupe.BlockoutMin}
+		if m.cfg.Watchlist.BlockoutMin > 0 {
+			dedupeCfg.BlockoutMin = m.cfg.Watchlist.BlockoutMin
+		}
+		m.watchlist = list
+		m.watchlistDedupe = notifier.NewDeduplicatorWithClock(dedupeCfg, func() time.Time { return m.now() })
+		logger.Info("loaded watchlist", map[string]interface{}{
+			"hexes":         len(m.cfg.Watchlist.Hexes),
+			"registrations": len(m.cfg.Watchlist.Registrations),
+			"callsigns":     len(m.cfg.Watchlist.Callsigns),
+			"blockout":      dedupeCfg.BlockoutMin.String(),
 		})
+	}
 
-		alertCount++
+	fence, err := loadGeofence(m.cfg)
+	if err != nil {
+		return fmt.Errorf("failed to load geofence: %w", err)
 	}
+	m.geofence = fence
 
-	if alertCount > 0 {
-		logger.Info("monitoring cycle completed", map[string]interface{}{
-			"alerts_sent":       alertCount,
-			"aircraft_in_range": len(nearby),
+	if m.cfg.Prediction.Enabled && (m.rules != nil || m.geofence != nil) {
+		// Approaches are predicted against the circular zones, which rules
+		// and a geofence replace.
+		logger.Warn("approach prediction needs circular zones and is disabled with alert rules or a geofence", nil)
+		m.cfg.Prediction.Enabled = false
+	}
+
+	if m.cfg.Registry.File != "" {
+		db, err := registry.Load(m.cfg.Registry.File)
+		if err != nil {
+			return fmt.Errorf("failed to load aircraft database: %w", err)
+		}
+		db.Start(ctx, m.cfg.Registry.ReloadInterval)
+		m.registry = db
+		logger.Info("loaded aircraft database", map[string]interface{}{
+			"path":     m.cfg.Registry.File,
+			"aircraft": db.Len(),
 		})
 	}
 
+	if m.cfg.AirlinesFile != "" {
+		table, err := airline.Load(m.cfg.AirlinesFile)
+		if err != nil {
+			return fmt.Errorf("failed to load airline table: %w", err)
+		}
+		m.airlines = table
+		logger.Info("loaded airline table", map[string]interface{}{
+			"path":     m.cfg.AirlinesFile,
+			"airlines": table.Len(),
+		})
+	}
+
+	if m.cfg.Routes.File != "" {
+		table, err := route.Load(m.cfg.Routes)
+		if err != nil {
+			return fmt.Errorf("failed to load routes: %w", err)
+		}
+		m.routes = table
+		logger.Info("loaded routes", map[string]interface{}{
+			"path":   m.cfg.Routes.File,
+			"routes": table.Len(),
+		})
+	}
 	return nil
 }
 
+// loadGeofence loads the configured geofence zones, if any.
+func loadGeofence(cfg config.Config) (*geofence.Fence, error) {
+	if cfg.GeofenceFile == "" {
+		return nil, nil
+	}
+	fence, err := geofence.Load(cfg.GeofenceFile, cfg.AltitudeMin, cfg.AltitudeMax)
+	if err != nil {
+		return nil, err
+	}
+	fence.Altitude = cfg.AltitudeFilter(0, 0).Altitude
+	return fence, nil
+}
+
 // GetAircraftCounts returns the counts of aircraft seen and in range.
 func (m *MonitorService) GetAircraftCounts() (totalSeen, inRange int) {
 	// This would be implemented to return current counts
@@ -1,13 +1,25 @@
 package main
 
 import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/benvon/whats-flying-over-me/internal/cataloger"
 	"github.com/benvon/whats-flying-over-me/internal/config"
+	"github.com/benvon/whats-flying-over-me/internal/geofence"
+	"github.com/benvon/whats-flying-over-me/internal/geometry"
 	"github.com/benvon/whats-flying-over-me/internal/notifier"
 	"github.com/benvon/whats-flying-over-me/internal/piaware"
+	"github.com/benvon/whats-flying-over-me/internal/registry"
+	"github.com/benvon/whats-flying-over-me/internal/route"
+	"github.com/benvon/whats-flying-over-me/internal/rules"
+	"github.com/benvon/whats-flying-over-me/internal/tracker"
+	"github.com/benvon/whats-flying-over-me/internal/watchlist"
 )
 
 func TestNewMonitorService(t *testing.T) {
@@ -26,7 +38,7 @@ func TestNewMonitorService(t *testing.T) {
 	})
 	stats := notifier.NewStats()
 
-	mockFetcher := func(url string) ([]piaware.Aircraft, error) {
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
 		return []piaware.Aircraft{}, nil
 	}
 
@@ -57,13 +69,13 @@ func TestMonitorServiceRunMonitoringCycleNoAircraft(t *testing.T) {
 	})
 	stats := notifier.NewStats()
 
-	mockFetcher := func(url string) ([]piaware.Aircraft, error) {
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
 		return []piaware.Aircraft{}, nil
 	}
 
 	service := NewMonitorService(cfg, mockNotifier, deduplicator, stats, mockFetcher, &cataloger.NoOpCataloger{})
 
-	err := service.RunMonitoringCycle()
+	err := service.RunMonitoringCycle(context.Background())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -90,13 +102,13 @@ func TestMonitorServiceRunMonitoringCycleWithAircraft(t *testing.T) {
 	})
 	stats := notifier.NewStats()
 
-	mockFetcher := func(url string) ([]piaware.Aircraft, error) {
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
 		return piaware.CreateNearbyAircraft(), nil
 	}
 
 	service := NewMonitorService(cfg, mockNotifier, deduplicator, stats, mockFetcher, &cataloger.NoOpCataloger{})
 
-	err := service.RunMonitoringCycle()
+	err := service.RunMonitoringCycle(context.Background())
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -134,13 +146,13 @@ func TestMonitorServiceRunMonitoringCycleWithFetcherError(t *testing.T) {
 	})
 	stats := notifier.NewStats()
 
-	mockFetcher := func(url string) ([]piaware.Aircraft, error) {
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
 		return nil, &mockError{message: "network error"}
 	}
 
 	service := NewMonitorService(cfg, mockNotifier, deduplicator, stats, mockFetcher, &cataloger.NoOpCataloger{})
 
-	err := service.RunMonitoringCycle()
+	err := service.RunMonitoringCycle(context.Background())
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -171,7 +183,7 @@ func TestMonitorServiceGetStats(t *testing.T) {
 	})
 	stats := notifier.NewStats()
 
-	mockFetcher := func(url string) ([]piaware.Aircraft, error) {
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
 		return []piaware.Aircraft{}, nil
 	}
 
@@ -208,7 +220,7 @@ func TestMonitorServiceGetAircraftCounts(t *testing.T) {
 	})
 	stats := notifier.NewStats()
 
-	mockFetcher := func(url string) ([]piaware.Aircraft, error) {
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
 		return []piaware.Aircraft{}, nil
 	}
 
@@ -237,3 +249,1000 @@ type mockError struct {
 func (e *mockError) Error() string {
 	return e.message
 }
+
+func TestMonitorServiceGroundAircraftDescription(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.7128,
+		BaseLon:     -74.0060,
+		RadiusKm:    25.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	deduplicator := notifier.NewDeduplicator(config.AlertDedupeConfig{
+		Enabled:     true,
+		BlockoutMin: 15 * time.Minute,
+	})
+	stats := notifier.NewStats()
+
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "GND1", Flight: "TAXI1", Lat: 40.7128, Lon: -74.0060, OnGround: true},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, stats, mockFetcher, &cataloger.NoOpCataloger{})
+
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 1 {
+		t.Fatalf("expected 1 notification, got %d", len(notifications))
+	}
+	if !strings.Contains(notifications[0].Description, "on the ground") {
+		t.Errorf("expected description to mention ground, got %q", notifications[0].Description)
+	}
+}
+
+func TestMonitorServiceSkipsUnchangedSnapshot(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.7128,
+		BaseLon:     -74.0060,
+		RadiusKm:    25.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	deduplicator := notifier.NewDeduplicator(config.AlertDedupeConfig{Enabled: false})
+	stats := notifier.NewStats()
+	mockCataloger := cataloger.NewMockCataloger()
+
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return nil, piaware.ErrNotModified
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, stats, mockFetcher, mockCataloger)
+
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected unchanged snapshot to be skipped, got %v", err)
+	}
+	if mockNotifier.GetNotificationCount() != 0 {
+		t.Errorf("expected 0 notifications, got %d", mockNotifier.GetNotificationCount())
+	}
+	if mockCataloger.GetCatalogCalls() != 0 {
+		t.Errorf("expected no cataloging, got %d calls", mockCataloger.GetCatalogCalls())
+	}
+}
+
+func TestMonitorServiceIgnoresStalePositions(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:        40.7128,
+		BaseLon:        -74.0060,
+		RadiusKm:       25.0,
+		AltitudeMax:    10000,
+		MaxPositionAge: 30 * time.Second,
+		DataURL:        "http://test.com",
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	deduplicator := notifier.NewDeduplicator(config.AlertDedupeConfig{Enabled: false})
+	stats := notifier.NewStats()
+	mockCataloger := cataloger.NewMockCataloger()
+
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "fresh1", Lat: 40.72, Lon: -74.01, AltBaro: 3000, SeenPos: 2, PositionAge: 2},
+			{Hex: "stale1", Lat: 40.73, Lon: -74.02, AltBaro: 3000, SeenPos: 50, PositionAge: 50},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, stats, mockFetcher, mockCataloger)
+
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 1 || notifications[0].Aircraft.Hex != "fresh1" {
+		t.Fatalf("expected a single alert for the fresh aircraft, got %+v", notifications)
+	}
+	if notifications[0].Aircraft.PositionAge != 2 {
+		t.Errorf("expected position age in alert, got %v", notifications[0].Aircraft.PositionAge)
+	}
+
+	// Stale aircraft are still cataloged, with their position age.
+	records := mockCataloger.GetCatalogedAircraft()
+	if len(records) != 2 || records[1].PositionAge != 50 {
+		t.Errorf("expected both aircraft cataloged with position age, got %+v", records)
+	}
+}
+
+func TestMonitorServiceTrackLifecycleAlerts(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.7128,
+		BaseLon:     -74.0060,
+		RadiusKm:    25.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		Tracker:     tracker.Config{Enabled: true, ExitAfter: time.Minute},
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	deduplicator := notifier.NewDeduplicator(config.AlertDedupeConfig{Enabled: true, BlockoutMin: 15 * time.Minute})
+	stats := notifier.NewStats()
+
+	// The aircraft flies north over the base and out of the area.
+	positions := []float64{40.60, 40.70, 40.75, 40.85}
+	cycle := 0
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		if cycle >= len(positions) {
+			return nil, nil
+		}
+		return []piaware.Aircraft{{Hex: "abc123", Flight: "TEST1", Lat: positions[cycle], Lon: -74.0060, AltBaro: 3000}}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, stats, mockFetcher, &cataloger.NoOpCataloger{})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+
+	for cycle = 0; cycle < len(positions)+2; cycle++ {
+		if err := service.RunMonitoringCycle(context.Background()); err != nil {
+			t.Fatalf("cycle %d: unexpected error %v", cycle, err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	want := []string{"aircraft_entered", "aircraft_closest_approach", "aircraft_exited"}
+	if len(notifications) != len(want) {
+		t.Fatalf("expected %d alerts, got %d: %+v", len(want), len(notifications), notifications)
+	}
+	for i, n := range notifications {
+		if n.AlertType != want[i] {
+			t.Errorf("alert %d: expected %s, got %s", i, want[i], n.AlertType)
+		}
+		if n.TrackID != notifications[0].TrackID || n.TrackID == "" {
+			t.Errorf("alert %d: expected shared track ID, got %q", i, n.TrackID)
+		}
+	}
+	if notifications[1].MinDistanceKm > 2 {
+		t.Errorf("expected closest approach near the base, got %.2f km", notifications[1].MinDistanceKm)
+	}
+	// In the area from 12:00 to 12:03.
+	if notifications[2].DwellSeconds != 180 {
+		t.Errorf("expected dwell of 180s, got %v", notifications[2].DwellSeconds)
+	}
+}
+
+func TestMonitorServicePredictionAlerts(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    10.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		AlertDedupe: config.AlertDedupeConfig{Enabled: true, BlockoutMin: 15 * time.Minute},
+		Prediction:  config.PredictionConfig{Enabled: true, Lookahead: 2 * time.Minute},
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	deduplicator := notifier.NewDeduplicator(cfg.AlertDedupe)
+	stats := notifier.NewStats()
+
+	// Both head for the base from 20 km out; only the faster one reaches the
+	// radius inside the lookahead.
+	lat := 39.82
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "fast", Lat: lat, Lon: -74.0, AltBaro: 5000, GS: 360, Track: 0},
+			{Hex: "slow", Lat: 39.82, Lon: -74.0, AltBaro: 5000, GS: 100, Track: 0},
+			{Hex: "high", Lat: 39.82, Lon: -74.0, AltBaro: 20000, GS: 360, Track: 0},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, stats, mockFetcher, cataloger.NewMockCataloger())
+
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 1 {
+		t.Fatalf("expected one approach alert, got %+v", notifications)
+	}
+	alert := notifications[0]
+	if alert.AlertType != "aircraft_approaching" || alert.Aircraft.Hex != "fast" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	if alert.PredictedMinDistanceKm > 0.1 || alert.ETASeconds < 100 || alert.ETASeconds > 115 {
+		t.Errorf("unexpected prediction %.2f km in %.0fs", alert.PredictedMinDistanceKm, alert.ETASeconds)
+	}
+
+	// The warning is not repeated, and does not block the alert once the
+	// aircraft is in range.
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	lat = 39.95
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	notifications = mockNotifier.GetNotifications()
+	if len(notifications) != 2 || notifications[1].AlertType != "aircraft_nearby" || notifications[1].Aircraft.Hex != "fast" {
+		t.Errorf("expected a nearby alert after the warning, got %+v", notifications)
+	}
+
+	// Alert rules replace the zones, leaving nothing to predict against.
+	cfg.Rules = []rules.Rule{{Name: "heavies", When: `type == "B744"`}}
+	mockNotifier.ClearNotifications()
+	lat = 39.82
+	service = NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), stats, mockFetcher, cataloger.NewMockCataloger())
+	if err := service.loadResources(context.Background()); err != nil {
+		t.Fatalf("loadResources() error = %v", err)
+	}
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n := mockNotifier.GetNotificationCount(); n != 0 {
+		t.Errorf("expected no approach alerts with alert rules, got %+v", mockNotifier.GetNotifications())
+	}
+}
+
+func TestMonitorServiceGeofenceAlerts(t *testing.T) {
+	fence, err := geofence.Parse([]byte(`{
+	  "type": "Feature",
+	  "properties": {"name": "runway 22 approach", "altitude_max": 3000},
+	  "geometry": {"type": "Polygon", "coordinates": [[[-74.10, 40.60], [-74.00, 40.60], [-74.00, 40.70], [-74.10, 40.60]]]}
+	}`), 0, 10000)
+	if err != nil {
+		t.Fatalf("parse geofence: %v", err)
+	}
+
+	cfg := config.Config{
+		BaseLat:     40.7128,
+		BaseLon:     -74.0060,
+		RadiusKm:    25.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	deduplicator := notifier.NewDeduplicator(config.AlertDedupeConfig{Enabled: false})
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "inside", Lat: 40.62, Lon: -74.02, AltBaro: 2000},
+			{Hex: "toohigh", Lat: 40.62, Lon: -74.02, AltBaro: 5000},
+			{Hex: "outside", Lat: 40.71, Lon: -74.00, AltBaro: 2000}, // within the radius, outside the zone
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	service.geofence = fence
+
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 1 {
+		t.Fatalf("expected one alert, got %+v", notifications)
+	}
+	alert := notifications[0]
+	if alert.Aircraft.Hex != "inside" || alert.Zone != "runway 22 approach" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	if !strings.Contains(alert.Description, "in runway 22 approach") {
+		t.Errorf("expected zone in description, got %q", alert.Description)
+	}
+}
+
+func TestMonitorServiceGeofenceZoneChange(t *testing.T) {
+	fence, err := geofence.Parse([]byte(`{
+	  "type": "FeatureCollection",
+	  "features": [
+	    {"type": "Feature", "properties": {"name": "west"},
+	     "geometry": {"type": "Polygon", "coordinates": [[[-74.10, 40.60], [-74.05, 40.60], [-74.05, 40.70], [-74.10, 40.70], [-74.10, 40.60]]]}},
+	    {"type": "Feature", "properties": {"name": "east"},
+	     "geometry": {"type": "Polygon", "coordinates": [[[-74.05, 40.60], [-74.00, 40.60], [-74.00, 40.70], [-74.05, 40.70], [-74.05, 40.60]]]}}
+	  ]
+	}`), 0, 10000)
+	if err != nil {
+		t.Fatalf("parse geofence: %v", err)
+	}
+
+	cfg := config.Config{
+		BaseLat:     40.7128,
+		BaseLon:     -74.0060,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		AlertDedupe: config.AlertDedupeConfig{Enabled: true, BlockoutMin: 15 * time.Minute},
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	lon := -74.08
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{{Hex: "a1b2c3", Flight: "TEST1", Lat: 40.65, Lon: lon, AltBaro: 2000}}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	service.geofence = fence
+
+	// The aircraft is alerted in the west zone once, then again on moving
+	// into the east zone.
+	for _, l := range []float64{-74.08, -74.07, -74.02} {
+		lon = l
+		if err := service.RunMonitoringCycle(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 2 || notifications[0].Zone != "west" || notifications[1].Zone != "east" {
+		t.Errorf("expected an alert in each zone, got %+v", notifications)
+	}
+}
+
+func TestMonitorServiceZones(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.7128,
+		BaseLon:     -74.0060,
+		RadiusKm:    25.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		AlertDedupe: config.AlertDedupeConfig{Enabled: true, BlockoutMin: 15 * time.Minute},
+		Zones: []config.ZoneConfig{
+			{Name: "home", BaseLat: 40.7128, BaseLon: -74.0060, RadiusKm: 5, AltitudeMax: new(3000), BlockoutMin: time.Minute},
+			{Name: "airport approach", BaseLat: 40.6413, BaseLon: -73.7781, RadiusKm: 25, AltitudeMin: new(500), AltitudeMax: new(8000)},
+		},
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	clock := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	deduplicator := notifier.NewDeduplicatorWithClock(cfg.AlertDedupe, func() time.Time { return clock })
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "both", Lat: 40.70, Lon: -73.98, AltBaro: 2000},     // inside both zones
+			{Hex: "approach", Lat: 40.65, Lon: -73.80, AltBaro: 6000}, // above home's ceiling
+			{Hex: "ground", Lat: 40.64, Lon: -73.78, OnGround: true},  // below the approach floor
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	service.now = func() time.Time { return clock }
+
+	alertedZones := func() map[string]string {
+		zones := make(map[string]string)
+		for _, n := range mockNotifier.GetNotifications() {
+			zones[n.Aircraft.Hex+"@"+n.Zone] = n.Description
+		}
+		mockNotifier.ClearNotifications()
+		return zones
+	}
+
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got := alertedZones()
+	for _, want := range []string{"both@home", "both@airport approach", "approach@airport approach"} {
+		if _, ok := got[want]; !ok {
+			t.Errorf("expected alert %s, got %v", want, got)
+		}
+	}
+	if len(got) != 3 {
+		t.Errorf("expected 3 alerts, got %v", got)
+	}
+
+	// Each zone's blockout is independent: home's has run out, the
+	// approach zone's has not.
+	clock = clock.Add(2 * time.Minute)
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got = alertedZones()
+	if _, ok := got["both@home"]; !ok || len(got) != 1 {
+		t.Errorf("expected only the home alert to repeat, got %v", got)
+	}
+}
+
+func TestMonitorServiceAltitudeFloorAndQNH(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:        40.7128,
+		BaseLon:        -74.0060,
+		RadiusKm:       25.0,
+		AltitudeMin:    500,
+		AltitudeMax:    3000,
+		AltitudeSource: piaware.AltitudeBaro,
+		QNH:            1003.25, // low pressure: aircraft are lower than their pressure altitude
+		DataURL:        "http://test.com",
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	deduplicator := notifier.NewDeduplicator(config.AlertDedupeConfig{Enabled: false})
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "ground", Lat: 40.72, Lon: -74.01, OnGround: true},
+			{Hex: "corrected", Lat: 40.72, Lon: -74.01, AltBaro: 3100},
+			{Hex: "low", Lat: 40.72, Lon: -74.01, AltBaro: 700}, // 427 ft once corrected
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 1 || notifications[0].Aircraft.Hex != "corrected" {
+		t.Errorf("expected a single alert for the corrected aircraft, got %+v", notifications)
+	}
+}
+
+func TestMonitorServiceOverheadAlerts(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    2.0,
+		AltitudeMax: 40000,
+		DataURL:     "http://test.com",
+		AlertDedupe: config.AlertDedupeConfig{Enabled: true, BlockoutMin: 15 * time.Minute},
+		Observer:    geometry.Config{ElevationFt: 100, MinElevationDeg: 30},
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	deduplicator := notifier.NewDeduplicator(cfg.AlertDedupe)
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			// 5 km north-east at 35,000 ft: outside the radius, ~64° up.
+			{Hex: "high", Lat: 40.0318, Lon: -73.9586, AltBaro: 35000},
+			// 5 km north at 3,000 ft: outside the radius, ~10° up.
+			{Hex: "low", Lat: 40.045, Lon: -74.0, AltBaro: 3000},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, deduplicator, notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	for i := 0; i < 2; i++ {
+		if err := service.RunMonitoringCycle(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 1 {
+		t.Fatalf("expected a single overhead alert, got %+v", notifications)
+	}
+	alert := notifications[0]
+	if alert.AlertType != "aircraft_overhead" || alert.Aircraft.Hex != "high" {
+		t.Errorf("unexpected alert %+v", alert)
+	}
+	if alert.Direction != "NE" || alert.ElevationDeg < 60 || alert.ElevationDeg > 68 || alert.AzimuthDeg < 40 || alert.AzimuthDeg > 50 {
+		t.Errorf("unexpected look angles: %s, azimuth %.1f, elevation %.1f", alert.Direction, alert.AzimuthDeg, alert.ElevationDeg)
+	}
+	if alert.SlantRangeKm < 11 || alert.SlantRangeKm > 12 {
+		t.Errorf("unexpected slant range %.2f km", alert.SlantRangeKm)
+	}
+	if !strings.Contains(alert.Description, "NE at 65° elevation") {
+		t.Errorf("expected look angles in description, got %q", alert.Description)
+	}
+}
+
+func TestMonitorServiceRegistryEnrichment(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aircraft.csv")
+	db := "a1b2c3;N12345;B738;00;BOEING 737-800;2005;UNITED AIRLINES INC\n"
+	if err := os.WriteFile(path, []byte(db), 0o600); err != nil {
+		t.Fatalf("write database: %v", err)
+	}
+
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    10.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		Registry:    registry.Config{File: path},
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	mockCataloger := cataloger.NewMockCataloger()
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "a1b2c3", Flight: "UAL123  ", Lat: 40.01, Lon: -74.01, AltBaro: 5000},
+			{Hex: "ffffff", Lat: 40.02, Lon: -74.02, AltBaro: 6000},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, mockCataloger)
+	if err := service.loadResources(context.Background()); err != nil {
+		t.Fatalf("loadResources() error = %v", err)
+	}
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(notifications))
+	}
+	known := notifications[0]
+	if a := known.Aircraft; a.Registration != "N12345" || a.TypeCode != "B738" || a.Model != "BOEING 737-800" || a.Operator != "UNITED AIRLINES INC" || a.Year != "2005" {
+		t.Errorf("unexpected enriched aircraft %+v", a)
+	}
+	if !strings.HasPrefix(known.Description, "Aircraft UAL123 (United Airlines, UNITED 123, a1b2c3, N12345, B738) detected") {
+		t.Errorf("expected callsign, registration and type in description, got %q", known.Description)
+	}
+	if !strings.HasPrefix(notifications[1].Description, "Aircraft ffffff detected") {
+		t.Errorf("expected an unknown aircraft to be described by hex, got %q", notifications[1].Description)
+	}
+
+	records := mockCataloger.GetCatalogedAircraft()
+	if len(records) != 2 || records[0].Registration != "N12345" || records[0].Operator != "UNITED AIRLINES INC" {
+		t.Errorf("expected enriched catalog records, got %+v", records)
+	}
+
+	cfg.Registry.File = filepath.Join(t.TempDir(), "missing.csv")
+	service = NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, mockCataloger)
+	if err := service.loadResources(context.Background()); err == nil {
+		t.Error("expected an error for a missing aircraft database")
+	}
+}
+
+func TestMonitorServiceDecodesCallsign(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airlines.csv")
+	table := "designator,name,telephony\nZZZ,Example Air,EXAMPLE\n"
+	if err := os.WriteFile(path, []byte(table), 0o600); err != nil {
+		t.Fatalf("write airline table: %v", err)
+	}
+
+	cfg := config.Config{
+		BaseLat:      40.0,
+		BaseLon:      -74.0,
+		RadiusKm:     10.0,
+		AltitudeMax:  10000,
+		DataURL:      "http://test.com",
+		AirlinesFile: path,
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "a1b2c3", Flight: "UAL123  ", Lat: 40.01, Lon: -74.01, AltBaro: 5000},
+			{Hex: "a1b2c4", Flight: "ZZZ0042 ", Lat: 40.02, Lon: -74.02, AltBaro: 6000},
+			{Hex: "a1b2c5", Flight: "N12345  ", Lat: 40.03, Lon: -74.03, AltBaro: 7000},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.loadResources(context.Background()); err != nil {
+		t.Fatalf("loadResources() error = %v", err)
+	}
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 3 {
+		t.Fatalf("expected 3 alerts, got %d", len(notifications))
+	}
+	if a := notifications[0].Aircraft; a.Callsign != "UAL123" || a.Airline != "United Airlines" || a.Telephony != "UNITED" || a.FlightNumber != "123" {
+		t.Errorf("expected a bundled airline to be decoded, got %+v", a)
+	}
+	if !strings.HasPrefix(notifications[1].Description, "Aircraft ZZZ0042 (Example Air, EXAMPLE 42, a1b2c4) detected") {
+		t.Errorf("expected an airline from the table file in description, got %q", notifications[1].Description)
+	}
+	if !strings.HasPrefix(notifications[2].Description, "Aircraft N12345 (a1b2c5) detected") {
+		t.Errorf("expected an undecoded callsign in description, got %q", notifications[2].Description)
+	}
+
+	cfg.AirlinesFile = filepath.Join(t.TempDir(), "missing.csv")
+	service = NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.loadResources(context.Background()); err == nil {
+		t.Error("expected an error for a missing airline table")
+	}
+}
+
+func TestMonitorServiceRouteLookup(t *testing.T) {
+	dir := t.TempDir()
+	routes := route.Config{File: filepath.Join(dir, "routes.csv"), AirportsFile: filepath.Join(dir, "airports.csv")}
+	if err := os.WriteFile(routes.File, []byte("Callsign,Code,Number,AirlineCode,AirportCodes\nUAL123,UA,123,UAL,KSFO-KORD\n"), 0o600); err != nil {
+		t.Fatalf("write routes: %v", err)
+	}
+	airports := "Code,Name,ICAO,IATA\nKSFO,San Francisco International Airport,KSFO,SFO\nKORD,Chicago O'Hare International Airport,KORD,ORD\n"
+	if err := os.WriteFile(routes.AirportsFile, []byte(airports), 0o600); err != nil {
+		t.Fatalf("write airports: %v", err)
+	}
+
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    10.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		Routes:      routes,
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "a1b2c3", Flight: "UAL123  ", Lat: 40.01, Lon: -74.01, AltBaro: 8000},
+			{Hex: "a1b2c4", Flight: "UAL456  ", Lat: 40.02, Lon: -74.02, AltBaro: 6000},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.loadResources(context.Background()); err != nil {
+		t.Fatalf("loadResources() error = %v", err)
+	}
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(notifications))
+	}
+	if a := notifications[0].Aircraft; a.Origin != "SFO" || a.OriginName != "San Francisco International Airport" || a.Destination != "ORD" || a.DestinationName != "Chicago O'Hare International Airport" {
+		t.Errorf("expected the route in the payload, got %+v", a)
+	}
+	if !strings.HasPrefix(notifications[0].Description, "Aircraft UAL123 SFO→ORD (United Airlines") {
+		t.Errorf("expected the route in description, got %q", notifications[0].Description)
+	}
+	if !strings.HasPrefix(notifications[1].Description, "Aircraft UAL456 (United Airlines") {
+		t.Errorf("expected no route in description, got %q", notifications[1].Description)
+	}
+
+	cfg.Routes.AirportsFile = filepath.Join(dir, "missing.csv")
+	service = NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.loadResources(context.Background()); err == nil {
+		t.Error("expected an error for a missing airports file")
+	}
+}
+
+func TestMonitorServiceDecodesICAOAddress(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    10.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	mockCataloger := cataloger.NewMockCataloger()
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			{Hex: "ae01ce", Lat: 40.01, Lon: -74.01, AltBaro: 5000},
+			{Hex: "406a93", Lat: 40.02, Lon: -74.02, AltBaro: 6000},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, mockCataloger)
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 2 {
+		t.Fatalf("expected 2 alerts, got %d", len(notifications))
+	}
+	if a := notifications[0].Aircraft; a.Country != "United States" || !a.Military {
+		t.Errorf("expected a US military aircraft, got %+v", a)
+	}
+	if !strings.HasPrefix(notifications[0].Description, "Aircraft ae01ce (military) detected") {
+		t.Errorf("expected military in description, got %q", notifications[0].Description)
+	}
+	if a := notifications[1].Aircraft; a.Country != "United Kingdom" || a.Military {
+		t.Errorf("expected a UK civil aircraft, got %+v", a)
+	}
+
+	records := mockCataloger.GetCatalogedAircraft()
+	if len(records) != 2 || records[0].Country != "United States" || !records[0].Military || records[1].Country != "United Kingdom" {
+		t.Errorf("expected decoded addresses in catalog records, got %+v", records)
+	}
+}
+
+func TestMonitorServiceEmergencyAlerts(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    10.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		AlertDedupe: config.AlertDedupeConfig{Enabled: true, BlockoutMin: time.Hour},
+	}
+
+	// The aircraft is well outside the radius and reports no position at
+	// first.
+	snapshots := [][]piaware.Aircraft{
+		{{Hex: "a1b2c3", Flight: "UAL123  ", Squawk: "7700", AltBaro: 8000}},
+		{{Hex: "a1b2c3", Flight: "UAL123  ", Squawk: "7700", Lat: 41.0, Lon: -74.0, AltBaro: 8000}},
+		{{Hex: "a1b2c3", Flight: "UAL123  ", Squawk: "7600", Lat: 41.0, Lon: -74.0, AltBaro: 7000}},
+		{{Hex: "a1b2c3", Flight: "UAL123  ", Squawk: "7600", Emergency: "minfuel", Lat: 41.0, Lon: -74.0, AltBaro: 6000}},
+		{{Hex: "a1b2c3", Flight: "UAL123  ", Squawk: "2301", Emergency: "none", Lat: 41.0, Lon: -74.0, AltBaro: 5000}},
+		{{Hex: "a1b2c3", Flight: "UAL123  ", Squawk: "2301", Lat: 41.0, Lon: -74.0, AltBaro: 5000}},
+		{},
+		{{Hex: "a1b2c3", Flight: "UAL123  ", Squawk: "7500", Lat: 41.0, Lon: -74.0, AltBaro: 5000}},
+	}
+	cycle := 0
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return snapshots[cycle], nil
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+
+	want := []struct {
+		alertType   string
+		description string
+	}{
+		{"aircraft_emergency", "Aircraft UAL123 (United Airlines, UNITED 123, a1b2c3) declaring general emergency (squawk 7700) position unknown at 8000 ft altitude"},
+		{},
+		{"aircraft_emergency", "Aircraft UAL123 (United Airlines, UNITED 123, a1b2c3) declaring radio failure (squawk 7600) 111.2 km away at 7000 ft altitude"},
+		{"aircraft_emergency", "Aircraft UAL123 (United Airlines, UNITED 123, a1b2c3) declaring minimum fuel (squawk 7600) 111.2 km away at 6000 ft altitude"},
+		{"aircraft_emergency_cleared", "Aircraft UAL123 (United Airlines, UNITED 123, a1b2c3) no longer declaring minimum fuel (squawk 7600) 111.2 km away at 5000 ft altitude"},
+		{},
+		{},
+		// Leaving the feed forgets the emergency.
+		{"aircraft_emergency", "Aircraft UAL123 (United Airlines, UNITED 123, a1b2c3) declaring unlawful interference (squawk 7500) 111.2 km away"},
+	}
+	for cycle = range snapshots {
+		mockNotifier.ClearNotifications()
+		if err := service.RunMonitoringCycle(context.Background()); err != nil {
+			t.Fatalf("cycle %d: expected no error, got %v", cycle, err)
+		}
+		notifications := mockNotifier.GetNotifications()
+		if want[cycle].alertType == "" {
+			if len(notifications) != 0 {
+				t.Errorf("cycle %d: expected no alerts, got %+v", cycle, notifications)
+			}
+			continue
+		}
+		if len(notifications) != 1 {
+			t.Fatalf("cycle %d: expected 1 alert, got %d", cycle, len(notifications))
+		}
+		alert := notifications[0]
+		if alert.AlertType != want[cycle].alertType || !strings.HasPrefix(alert.Description, want[cycle].description) {
+			t.Errorf("cycle %d: got %s %q, want %s %q", cycle, alert.AlertType, alert.Description, want[cycle].alertType, want[cycle].description)
+		}
+		if wantPriority := alert.AlertType == "aircraft_emergency"; (alert.Priority == notifier.PriorityHigh) != wantPriority {
+			t.Errorf("cycle %d: unexpected priority %q", cycle, alert.Priority)
+		}
+	}
+}
+
+func TestMonitorServiceEmergencyBypassesDedupe(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    10.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		AlertDedupe: config.AlertDedupeConfig{Enabled: true, BlockoutMin: time.Hour},
+	}
+
+	squawk := "1200"
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{{Hex: "a1b2c3", Squawk: squawk, Lat: 40.01, Lon: -74.01, AltBaro: 5000}}, nil
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// The nearby alert is now blocked, but the emergency is not.
+	squawk = "7700"
+	mockNotifier.ClearNotifications()
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 1 || notifications[0].AlertType != "aircraft_emergency" {
+		t.Fatalf("expected only an emergency alert, got %+v", notifications)
+	}
+	if !strings.Contains(notifications[0].Description, "1.4 km away at 5000 ft altitude, NW at") {
+		t.Errorf("expected distance and look angles in description, got %q", notifications[0].Description)
+	}
+}
+
+func TestMonitorServiceAlertRules(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    1.0,
+		AltitudeMax: 1000,
+		DataURL:     "http://test.com",
+		AlertDedupe: config.AlertDedupeConfig{Enabled: true, BlockoutMin: time.Hour},
+		Notifier:    config.NotifierConfig{Console: true, Webhook: config.WebhookConfig{Enabled: true}},
+		Rules: []rules.Rule{
+			{Name: "heavies", When: `type in ["B744","A388"] && alt < 10000 && distance < 20`, Severity: rules.SeverityWarning, Notifiers: []string{"webhook"}},
+			{Name: "military", When: `military`, Severity: rules.SeverityCritical},
+		},
+	}
+
+	mockNotifier := notifier.NewMockNotifier()
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			// Outside the radius and above the altitude ceiling, which
+			// rules replace.
+			{Hex: "4ca7b5", Flight: "BAW286  ", TypeCode: "B744", Lat: 40.1, Lon: -74.0, AltBaro: 8000},
+			{Hex: "4ca7b6", TypeCode: "B744", Lat: 40.1, Lon: -74.0, AltBaro: 12000},
+			{Hex: "ae01ce", TypeCode: "B744", Lat: 40.15, Lon: -74.0, AltBaro: 9000},
+			{Hex: "a1b2c3", TypeCode: "B738", Lat: 40.001, Lon: -74.0, AltBaro: 500},
+		}, nil
+	}
+
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.loadResources(context.Background()); err != nil {
+		t.Fatalf("loadResources() error = %v", err)
+	}
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 3 {
+		t.Fatalf("expected 3 alerts, got %+v", notifications)
+	}
+	heavy := notifications[0]
+	if heavy.Aircraft.Hex != "4ca7b5" || heavy.Rule != "heavies" || heavy.Severity != rules.SeverityWarning || !slices.Equal(heavy.Notifiers, []string{"webhook"}) || heavy.Zone != "" {
+		t.Errorf("unexpected heavies alert %+v", heavy)
+	}
+	if !strings.HasPrefix(heavy.Description, "Aircraft BAW286 (British Airways, SPEEDBIRD 286, 4ca7b5, B744) detected within 11.1 km at 8000 ft altitude matching heavies") {
+		t.Errorf("unexpected description %q", heavy.Description)
+	}
+	// An aircraft matching two rules is alerted for each.
+	for i, rule := range []string{"heavies", "military"} {
+		if a := notifications[i+1]; a.Aircraft.Hex != "ae01ce" || a.Rule != rule {
+			t.Errorf("expected ae01ce to match %s, got %+v", rule, a)
+		}
+	}
+	if notifications[2].Severity != rules.SeverityCritical || notifications[2].Notifiers != nil {
+		t.Errorf("unexpected military alert %+v", notifications[2])
+	}
+
+	// Each rule deduplicates on its own.
+	mockNotifier.ClearNotifications()
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n := mockNotifier.GetNotificationCount(); n != 0 {
+		t.Errorf("expected repeated matches to be deduplicated, got %d alerts", n)
+	}
+
+	cfg.Rules = []rules.Rule{{Name: "bad", When: "military", Notifiers: []string{"rabbitmq"}}}
+	service = NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.loadResources(context.Background()); err == nil || !strings.Contains(err.Error(), `notifier "rabbitmq" is not enabled`) {
+		t.Errorf("expected an error for a disabled notifier, got %v", err)
+	}
+}
+
+func TestMonitorServiceAlertRulesWithoutPosition(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:  40.0,
+		BaseLon:  -74.0,
+		DataURL:  "http://test.com",
+		Notifier: config.NotifierConfig{Console: true},
+		Rules: []rules.Rule{
+			{Name: "hijack", When: `squawk == "7500"`},
+			{Name: "close", When: `distance < 20`},
+		},
+	}
+
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{{Hex: "a1b2c3", Squawk: "7500", AltBaro: 5000}}, nil
+	}
+
+	for _, tracking := range []bool{false, true} {
+		cfg.Tracker = tracker.Config{Enabled: tracking, ExitAfter: time.Minute}
+		mockNotifier := notifier.NewMockNotifier()
+		service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+		if err := service.loadResources(context.Background()); err != nil {
+			t.Fatalf("loadResources() error = %v", err)
+		}
+		if err := service.RunMonitoringCycle(context.Background()); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+
+		// The emergency alert comes first; the distance rule cannot match
+		// an aircraft without a position.
+		notifications := mockNotifier.GetNotifications()
+		if len(notifications) != 2 {
+			t.Fatalf("tracking %v: expected emergency and rule alerts, got %+v", tracking, notifications)
+		}
+		alert := notifications[1]
+		if alert.AlertType != "aircraft_nearby" || alert.Rule != "hijack" || alert.Aircraft.DistanceKm != 0 {
+			t.Errorf("tracking %v: unexpected rule alert %+v", tracking, alert)
+		}
+		if alert.Description != "Aircraft a1b2c3 detected position unknown at 5000 ft altitude matching hijack" {
+			t.Errorf("tracking %v: unexpected description %q", tracking, alert.Description)
+		}
+	}
+}
+
+func TestMonitorServiceWatchlistAlerts(t *testing.T) {
+	cfg := config.Config{
+		BaseLat:     40.0,
+		BaseLon:     -74.0,
+		RadiusKm:    10.0,
+		AltitudeMax: 10000,
+		DataURL:     "http://test.com",
+		AlertDedupe: config.AlertDedupeConfig{Enabled: true, BlockoutMin: time.Hour},
+		Watchlist: watchlist.Config{
+			Registrations: []string{"N12345"},
+			Callsigns:     []string{"^AF1$"},
+			BlockoutMin:   10 * time.Minute,
+		},
+	}
+
+	mockFetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		return []piaware.Aircraft{
+			// Far outside the radius and above the altitude ceiling.
+			{Hex: "adfdf8", Flight: "AF1     ", Lat: 41.0, Lon: -74.0, AltBaro: 30000},
+			{Hex: "a1b2c3", Registration: "N12345", Lat: 40.01, Lon: -74.01, AltBaro: 5000},
+			{Hex: "a00001", Registration: "N54321", Lat: 40.01, Lon: -74.01, AltBaro: 5000},
+		}, nil
+	}
+
+	clock := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	mockNotifier := notifier.NewMockNotifier()
+	service := NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	service.now = func() time.Time { return clock }
+	if err := service.loadResources(context.Background()); err != nil {
+		t.Fatalf("loadResources() error = %v", err)
+	}
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var seen []notifier.AlertData
+	for _, a := range mockNotifier.GetNotifications() {
+		if a.AlertType == "watchlist_seen" {
+			seen = append(seen, a)
+		}
+	}
+	if len(seen) != 2 || mockNotifier.GetNotificationCount() != 4 {
+		t.Fatalf("expected 2 watchlist alerts alongside 2 nearby alerts, got %+v", mockNotifier.GetNotifications())
+	}
+	if a := seen[0]; a.Aircraft.Hex != "adfdf8" || a.Direction != "N" || a.AzimuthDeg != 0 ||
+		!strings.HasPrefix(a.Description, "Watched aircraft AF1 (adfdf8, military) seen by callsign AF1 111.2 km away at 30000 ft altitude, N at") {
+		t.Errorf("unexpected callsign alert %+v", a)
+	}
+	if a := seen[1]; a.Aircraft.Hex != "a1b2c3" || a.Direction != "NW" ||
+		!strings.HasPrefix(a.Description, "Watched aircraft a1b2c3 (N12345) seen by registration N12345 1.4 km away") {
+		t.Errorf("unexpected registration alert %+v", a)
+	}
+
+	// Sightings are blocked by the watchlist's own blockout, separately from
+	// the nearby alerts.
+	clock = clock.Add(5 * time.Minute)
+	mockNotifier.ClearNotifications()
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if n := mockNotifier.GetNotificationCount(); n != 0 {
+		t.Errorf("expected sightings within the blockout to be deduplicated, got %d alerts", n)
+	}
+
+	clock = clock.Add(10 * time.Minute)
+	mockNotifier.ClearNotifications()
+	if err := service.RunMonitoringCycle(context.Background()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, a := range mockNotifier.GetNotifications() {
+		if a.AlertType != "watchlist_seen" {
+			t.Errorf("expected only watchlist alerts after their blockout, got %+v", a)
+		}
+	}
+	if n := mockNotifier.GetNotificationCount(); n != 2 {
+		t.Errorf("expected 2 watchlist alerts after the blockout, got %d", n)
+	}
+
+	cfg.Watchlist = watchlist.Config{Callsigns: []string{"(AF1"}}
+	service = NewMonitorService(cfg, mockNotifier, notifier.NewDeduplicator(cfg.AlertDedupe), notifier.NewStats(), mockFetcher, cataloger.NewMockCataloger())
+	if err := service.loadResources(context.Background()); err == nil || !strings.Contains(err.Error(), "failed to load watchlist") {
+		t.Errorf("expected an error for an invalid callsign pattern, got %v", err)
+	}
+}
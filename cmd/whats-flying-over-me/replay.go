@@ -0,0 +1,252 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/capture"
+	"github.com/benvon/whats-flying-over-me/internal/cataloger"
+	"github.com/benvon/whats-flying-over-me/internal/config"
+	"github.com/benvon/whats-flying-over-me/internal/logger"
+	"github.com/benvon/whats-flying-over-me/internal/notifier"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// replayPatterns match the files picked up when a directory is replayed.
+var replayPatterns = []string{
+	"capture-*.jsonl.gz",
+	"capture-*.jsonl",
+	"history_*.json",
+	"history_*.json.gz",
+}
+
+// replayClock is the time source for a replay. It follows the recorded
+// timeline rather than the wall clock.
+type replayClock struct {
+	mutex sync.Mutex
+	t     time.Time
+}
+
+func (c *replayClock) now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.t
+}
+
+func (c *replayClock) set(t time.Time) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.t = t
+}
+
+// replayFile is an input file and the time of its first snapshot.
+type replayFile struct {
+	path  string
+	start time.Time
+}
+
+// runReplay implements the replay subcommand: it feeds recorded snapshots
+// through the monitoring service using the configured filters, dedupe
+// settings and notifiers. Nothing is cataloged.
+func runReplay(ctx context.Context, args []string) error {
+	flagSet := flag.NewFlagSet("replay", flag.ContinueOnError)
+	speed := flagSet.Float64("speed", 1, "replay speed multiplier (0 replays as fast as possible)")
+	cfg := config.LoadWithFlagSetAndArgs(flagSet, args)
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	if flagSet.NArg() == 0 {
+		return errors.New("usage: replay [flags] <capture or history files or directories>")
+	}
+	files, err := replayFiles(flagSet.Args())
+	if err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return errors.New("no capture or history files found")
+	}
+
+	n, err := notifier.New(cfg.Notifier)
+	if err != nil {
+		return fmt.Errorf("failed to initialize notifier: %w", err)
+	}
+
+	return replay(ctx, cfg, n, files, *speed)
+}
+
+// replay runs the snapshots in files through a monitoring service whose
+// dedupe and stats follow the recorded timeline. Snapshots are spaced by
+// their recorded gaps divided by speed.
+func replay(ctx context.Context, cfg config.Config, n notifier.Notifier, files []replayFile, speed float64) error {
+	clock := &replayClock{t: files[0].start}
+	deduplicator := notifier.NewDeduplicatorWithClock(cfg.AlertDedupe, clock.now)
+	stats := notifier.NewStatsWithClock(clock.now)
+
+	var current *piaware.Data
+	var ager piaware.PositionAger
+	fetcher := func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		ager.Age(current, clock.now())
+		return current.Aircraft, nil
+	}
+
+	monitorService := NewMonitorService(cfg, n, deduplicator, stats, fetcher, &cataloger.NoOpCataloger{})
+	monitorService.now = clock.now
+	if err := monitorService.loadResources(ctx); err != nil {
+		return err
+	}
+
+	logger.Info("starting replay", map[string]interface{}{
+		"files":      len(files),
+		"speed":      speed,
+		"start_time": files[0].start.Format(time.RFC3339),
+	})
+
+	var last time.Time
+	snapshots, skipped := 0, 0
+	for _, f := range files {
+		err := replaySnapshots(f.path, func(rec capture.Record) error {
+			if !last.IsZero() && rec.Time.Before(last) {
+				// Overlapping inputs, such as a history ring alongside a
+				// capture, can repeat earlier snapshots.
+				skipped++
+				return nil
+			}
+			if !last.IsZero() {
+				if err := waitReplay(ctx, rec.Time.Sub(last), speed); err != nil {
+					return err
+				}
+			}
+
+			last = rec.Time
+			clock.set(rec.Time)
+			current = rec.Data
+			runMonitoringCycle(ctx, monitorService, stats)
+			snapshots++
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.path, err)
+		}
+	}
+
+	statsData := stats.GetStats()
+	logger.Info("replay finished", map[string]interface{}{
+		"snapshots":         snapshots,
+		"skipped_snapshots": skipped,
+		"recorded_duration": statsData["uptime"],
+		"end_time":          last.Format(time.RFC3339),
+		"scrape_failures":   statsData["scrape_failures"],
+		"unique_aircraft":   statsData["unique_aircraft"],
+	})
+	return nil
+}
+
+// replayFiles expands the arguments into capture and history files ordered
+// by the time of their first snapshot. Directories contribute the files
+// matching replayPatterns.
+func replayFiles(args []string) ([]replayFile, error) {
+	var paths []string
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			paths = append(paths, arg)
+			continue
+		}
+		for _, pattern := range replayPatterns {
+			matches, err := filepath.Glob(filepath.Join(arg, pattern))
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, matches...)
+		}
+	}
+
+	files := make([]replayFile, 0, len(paths))
+	for _, path := range paths {
+		start, err := firstSnapshotTime(path)
+		if errors.Is(err, io.EOF) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		files = append(files, replayFile{path: path, start: start})
+	}
+
+	// tar1090 reuses history file names in a ring, so names say nothing
+	// about order; the snapshots do.
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].start.Before(files[j].start)
+	})
+	return files, nil
+}
+
+// firstSnapshotTime returns the time of the first snapshot in path.
+func firstSnapshotTime(path string) (time.Time, error) {
+	r, err := capture.Open(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	rec, err := r.Next()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return rec.Time, nil
+}
+
+// replaySnapshots calls fn for every snapshot in path.
+func replaySnapshots(path string, fn func(capture.Record) error) error {
+	r, err := capture.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = r.Close()
+	}()
+
+	for {
+		rec, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(rec); err != nil {
+			return err
+		}
+	}
+}
+
+// waitReplay sleeps for the recorded gap between snapshots scaled by speed.
+// A speed of zero or less does not wait at all.
+func waitReplay(ctx context.Context, gap time.Duration, speed float64) error {
+	if speed <= 0 || gap <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(time.Duration(float64(gap) / speed))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
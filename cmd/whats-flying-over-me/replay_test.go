@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/capture"
+	"github.com/benvon/whats-flying-over-me/internal/config"
+	"github.com/benvon/whats-flying-over-me/internal/notifier"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func TestReplayFollowsRecordedTimeline(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	// A tar1090 history snapshot a minute before the capture starts.
+	history := `{"now":1714564740,"aircraft":[{"hex":"aaa111","flight":"TEST1","lat":40.72,"lon":-74.01,"alt_baro":3000}]}`
+	if err := os.WriteFile(filepath.Join(dir, "history_7.json"), []byte(history), 0o600); err != nil {
+		t.Fatalf("write history: %v", err)
+	}
+
+	recorder, err := capture.NewRecorder(capture.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	for _, offset := range []time.Duration{0, 5 * time.Minute, 20 * time.Minute} {
+		at := start.Add(offset)
+		data := &piaware.Data{
+			Now: float64(at.Unix()),
+			Aircraft: []piaware.Aircraft{
+				{Hex: "aaa111", Flight: "TEST1", Lat: 40.72, Lon: -74.01, AltBaro: 3000},
+			},
+		}
+		if err := recorder.Record(at, data); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	files, err := replayFiles([]string{dir})
+	if err != nil {
+		t.Fatalf("replayFiles() error = %v", err)
+	}
+	if len(files) != 2 || filepath.Base(files[0].path) != "history_7.json" {
+		t.Fatalf("expected history file first, got %+v", files)
+	}
+
+	cfg := config.Config{
+		BaseLat:     40.7128,
+		BaseLon:     -74.0060,
+		RadiusKm:    25.0,
+		AltitudeMax: 10000,
+		AlertDedupe: config.AlertDedupeConfig{
+			Enabled:     true,
+			BlockoutMin: 15 * time.Minute,
+		},
+	}
+	mockNotifier := notifier.NewMockNotifier()
+
+	if err := replay(context.Background(), cfg, mockNotifier, files, 0); err != nil {
+		t.Fatalf("replay() error = %v", err)
+	}
+
+	// 11:59 alerts, 12:00 and 12:05 fall in the blockout, 12:20 alerts again.
+	notifications := mockNotifier.GetNotifications()
+	if len(notifications) != 2 {
+		t.Fatalf("expected 2 alerts on the recorded timeline, got %d", len(notifications))
+	}
+	wantTimes := []time.Time{start.Add(-time.Minute), start.Add(20 * time.Minute)}
+	for i, n := range notifications {
+		if !n.Timestamp.Equal(wantTimes[i]) {
+			t.Errorf("alert %d: expected recorded timestamp %v, got %v", i, wantTimes[i], n.Timestamp)
+		}
+	}
+}
+
+func TestWaitReplay(t *testing.T) {
+	started := time.Now()
+	if err := waitReplay(context.Background(), time.Second, 100); err != nil {
+		t.Fatalf("waitReplay() error = %v", err)
+	}
+	if elapsed := time.Since(started); elapsed < 5*time.Millisecond || elapsed > 500*time.Millisecond {
+		t.Errorf("expected a 10ms wait at 100x, took %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := waitReplay(ctx, time.Hour, 1); err == nil {
+		t.Error("expected cancelled wait to return an error")
+	}
+}
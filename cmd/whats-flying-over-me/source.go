@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/capture"
+	"github.com/benvon/whats-flying-over-me/internal/config"
+	"github.com/benvon/whats-flying-over-me/internal/feed"
+	"github.com/benvon/whats-flying-over-me/internal/logger"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// aircraftSource is the fetcher selected for the configured data URL or
+// receivers. Updates is nil for polling sources and signals position changes
+// for streaming ones. Recorder is set when snapshots are being captured.
+type aircraftSource struct {
+	fetcher  AircraftFetcher
+	updates  <-chan struct{}
+	recorder *capture.Recorder
+}
+
+// Close finishes the capture file, if any.
+func (s aircraftSource) Close() error {
+	if s.recorder == nil {
+		return nil
+	}
+	return s.recorder.Close()
+}
+
+// dataSource is the ingest path for a single data URL.
+type dataSource struct {
+	fetch   feed.DataFetcher
+	updates <-chan struct{}
+}
+
+// newAircraftSource selects the ingest path for the configured data URL, or
+// merges all configured receivers. Streaming sources are started and run
+// until ctx is cancelled. Fetched snapshots are recorded when a capture
+// directory is configured.
+func newAircraftSource(ctx context.Context, cfg config.Config) (aircraftSource, error) {
+	httpFetcher, err := piaware.NewHTTPFetcher(cfg.Fetcher)
+	if err != nil {
+		return aircraftSource{}, fmt.Errorf("failed to create HTTP fetcher: %w", err)
+	}
+
+	var recorder *capture.Recorder
+	if cfg.Capture.Dir != "" {
+		recorder, err = capture.NewRecorder(cfg.Capture)
+		if err != nil {
+			return aircraftSource{}, fmt.Errorf("failed to create capture recorder: %w", err)
+		}
+	}
+
+	if len(cfg.Receivers) == 0 {
+		src, err := newDataSource(ctx, cfg, httpFetcher, cfg.DataURL)
+		if err != nil {
+			return aircraftSource{}, err
+		}
+		return aircraftSource{
+			fetcher:  aircraftFetcher(src.fetch, recorder),
+			updates:  src.updates,
+			recorder: recorder,
+		}, nil
+	}
+
+	receivers := make([]feed.Receiver, 0, len(cfg.Receivers))
+	var updates []<-chan struct{}
+	for _, r := range cfg.Receivers {
+		src, err := newDataSource(ctx, cfg, httpFetcher, r.URL)
+		if err != nil {
+			return aircraftSource{}, fmt.Errorf("receiver %s: %w", r.Name, err)
+		}
+		receivers = append(receivers, feed.Receiver{Name: r.Name, URL: r.URL, Fetch: src.fetch})
+		if src.updates != nil {
+			updates = append(updates, src.updates)
+		}
+	}
+
+	return aircraftSource{
+		fetcher:  aircraftFetcher(feed.NewMerger(receivers).FetchData, recorder),
+		updates:  fanInUpdates(ctx, updates),
+		recorder: recorder,
+	}, nil
+}
+
+// newDataSource selects the ingest path for dataURL based on its scheme.
+func newDataSource(ctx context.Context, cfg config.Config, httpFetcher *piaware.HTTPFetcher, dataURL string) (dataSource, error) {
+	u, err := url.Parse(dataURL)
+	if err != nil {
+		return dataSource{}, fmt.Errorf("invalid data URL %q: %w", dataURL, err)
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return dataSource{fetch: httpFetcher.FetchData}, nil
+	case "sbs":
+		if u.Host == "" {
+			return dataSource{}, fmt.Errorf("SBS data URL %q must include host:port", dataURL)
+		}
+		return startStream(ctx, feed.NewSBS(u.Host)), nil
+	case "beast":
+		if u.Host == "" {
+			return dataSource{}, fmt.Errorf("beast data URL %q must include host:port", dataURL)
+		}
+		return startStream(ctx, feed.NewBeast(u.Host, cfg.BaseLat, cfg.BaseLon)), nil
+	case "avr":
+		// avr://host:port streams from a receiver; avr:///path replays a capture file.
+		if u.Host != "" {
+			return startStream(ctx, feed.NewAVR(u.Host, cfg.BaseLat, cfg.BaseLon)), nil
+		}
+		if u.Path == "" {
+			return dataSource{}, fmt.Errorf("AVR data URL %q must include host:port or a file path", dataURL)
+		}
+		return dataSource{fetch: feed.NewAVRFile(u.Path, cfg.BaseLat, cfg.BaseLon).FetchData}, nil
+	case "file":
+		// file:///run/readsb/aircraft.json reads the snapshot readsb writes locally.
+		if u.Host != "" && u.Host != "localhost" {
+			return dataSource{}, fmt.Errorf("file data URL %q must not name a remote host", dataURL)
+		}
+		if u.Path == "" {
+			return dataSource{}, fmt.Errorf("file data URL %q must include a path", dataURL)
+		}
+		file := feed.NewFile(u.Path)
+		file.Start(ctx)
+		return dataSource{fetch: file.FetchData, updates: file.Updates()}, nil
+	default:
+		return dataSource{}, fmt.Errorf("unsupported data URL scheme %q", u.Scheme)
+	}
+}
+
+// startStream starts a streaming source and wires it up as a data source.
+func startStream(ctx context.Context, stream *feed.Stream) dataSource {
+	stream.Start(ctx)
+	return dataSource{fetch: stream.FetchData, updates: stream.Updates()}
+}
+
+// aircraftFetcher adapts a snapshot fetcher to the AircraftFetcher seam,
+// recording each snapshot as fetched if recorder is set and stamping each
+// position with its age at the time of the fetch.
+func aircraftFetcher(fetch feed.DataFetcher, recorder *capture.Recorder) AircraftFetcher {
+	var ager piaware.PositionAger
+	return func(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+		data, err := fetch(ctx, url)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		if recorder != nil {
+			if err := recorder.Record(now, data); err != nil {
+				// A capture failure must not stop monitoring.
+				logger.Err("failed to record snapshot", map[string]interface{}{
+					"error": err.Error(),
+				})
+			}
+		}
+
+		ager.Age(data, now)
+		return data.Aircraft, nil
+	}
+}
+
+// fanInUpdates merges several update channels into one coalescing channel.
+// It returns nil when there is nothing to merge.
+func fanInUpdates(ctx context.Context, channels []<-chan struct{}) <-chan struct{} {
+	if len(channels) == 0 {
+		return nil
+	}
+
+	out := make(chan struct{}, 1)
+	for _, ch := range channels {
+		go func(ch <-chan struct{}) {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ch:
+					select {
+					case out <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}(ch)
+	}
+	return out
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/capture"
+	"github.com/benvon/whats-flying-over-me/internal/config"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func TestNewAircraftSource(t *testing.T) {
+	tests := []struct {
+		name        string
+		dataURL     string
+		wantErr     bool
+		wantUpdates bool
+	}{
+		{name: "http polling", dataURL: "http://localhost:8080/data/aircraft.json"},
+		{name: "https polling", dataURL: "https://receiver.example/data/aircraft.json"},
+		{name: "sbs stream", dataURL: "sbs://127.0.0.1:30003", wantUpdates: true},
+		{name: "sbs without host", dataURL: "sbs://", wantErr: true},
+		{name: "beast stream", dataURL: "beast://127.0.0.1:30005", wantUpdates: true},
+		{name: "beast without host", dataURL: "beast://", wantErr: true},
+		{name: "avr stream", dataURL: "avr://127.0.0.1:30002", wantUpdates: true},
+		{name: "avr capture file", dataURL: "avr:///tmp/capture.avr"},
+		{name: "avr without host or path", dataURL: "avr://", wantErr: true},
+		{name: "local file", dataURL: "file:///run/readsb/aircraft.json", wantUpdates: true},
+		{name: "file on remote host", dataURL: "file://receiver/run/readsb/aircraft.json", wantErr: true},
+		{name: "file without path", dataURL: "file://", wantErr: true},
+		{name: "unsupported scheme", dataURL: "ftp://localhost/aircraft.json", wantErr: true},
+		{name: "invalid URL", dataURL: "://bad", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			source, err := newAircraftSource(ctx, config.Config{DataURL: tt.dataURL})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("newAircraftSource() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if source.fetcher == nil {
+				t.Error("expected a fetcher")
+			}
+			if (source.updates != nil) != tt.wantUpdates {
+				t.Errorf("expected updates channel %v, got %v", tt.wantUpdates, source.updates != nil)
+			}
+		})
+	}
+}
+
+func TestNewAircraftSourceReceivers(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cfg := config.Config{
+		DataURL: "http://ignored.example/aircraft.json",
+		Receivers: []config.ReceiverConfig{
+			{Name: "north", URL: "http://north.example/data/aircraft.json"},
+			{Name: "south", URL: "sbs://127.0.0.1:30003"},
+		},
+	}
+
+	source, err := newAircraftSource(ctx, cfg)
+	if err != nil {
+		t.Fatalf("newAircraftSource() error = %v", err)
+	}
+	if source.fetcher == nil {
+		t.Error("expected a fetcher")
+	}
+	if source.updates == nil {
+		t.Error("expected updates from the streaming receiver")
+	}
+
+	cfg.Receivers = append(cfg.Receivers, config.ReceiverConfig{Name: "bad", URL: "ftp://bad"})
+	if _, err := newAircraftSource(ctx, cfg); err == nil {
+		t.Error("expected error for unsupported receiver URL")
+	}
+}
+
+func TestAircraftFetcherRecordsSnapshots(t *testing.T) {
+	dir := t.TempDir()
+	recorder, err := capture.NewRecorder(capture.Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+
+	fetch := func(ctx context.Context, url string) (*piaware.Data, error) {
+		return &piaware.Data{Now: 1000, Aircraft: []piaware.Aircraft{{Hex: "aaa111", Lat: 40.1, Lon: -74.1, SeenPos: 1}}}, nil
+	}
+
+	aircraft, err := aircraftFetcher(fetch, recorder)(context.Background(), "")
+	if err != nil {
+		t.Fatalf("fetch error = %v", err)
+	}
+	if len(aircraft) != 1 || aircraft[0].PositionAge == 0 {
+		t.Errorf("expected aged aircraft, got %+v", aircraft)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "capture-*.jsonl.gz"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected one capture file, got %v (%v)", files, err)
+	}
+}
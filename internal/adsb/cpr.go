@@ -0,0 +1,170 @@
+package adsb
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// cprScale is the number of encoded steps in a CPR zone (2^17).
+	cprScale = 131072.0
+	// cprPairMaxAge is the longest interval between an even and an odd frame
+	// that may be combined for global decoding.
+	cprPairMaxAge = 10 * time.Second
+	// localRefMaxAge is how long a decoded position may serve as the
+	// reference for locally decoding the next frame.
+	localRefMaxAge = 5 * time.Minute
+)
+
+// CPRFrame is one half of a compact position report.
+type CPRFrame struct {
+	Odd bool
+	Lat int
+	Lon int
+}
+
+// GlobalAirborne decodes an airborne position from an even and an odd frame.
+// latestOdd reports whether the odd frame was received last; the position is
+// computed for the most recent frame. ok is false when the frames straddle a
+// longitude zone boundary and cannot be combined.
+func GlobalAirborne(even, odd CPRFrame, latestOdd bool) (lat, lon float64, ok bool) {
+	const dLat0 = 360.0 / 60
+	const dLat1 = 360.0 / 59
+
+	latE := float64(even.Lat) / cprScale
+	latO := float64(odd.Lat) / cprScale
+	lonE := float64(even.Lon) / cprScale
+	lonO := float64(odd.Lon) / cprScale
+
+	j := math.Floor(59*latE - 60*latO + 0.5)
+	rlat0 := dLat0 * (mod(j, 60) + latE)
+	rlat1 := dLat1 * (mod(j, 59) + latO)
+	if rlat0 >= 270 {
+		rlat0 -= 360
+	}
+	if rlat1 >= 270 {
+		rlat1 -= 360
+	}
+	if rlat0 < -90 || rlat0 > 90 || rlat1 < -90 || rlat1 > 90 {
+		return 0, 0, false
+	}
+
+	nl := cprNL(rlat0)
+	if nl != cprNL(rlat1) {
+		return 0, 0, false
+	}
+
+	if latestOdd {
+		ni := math.Max(float64(nl-1), 1)
+		m := math.Floor(lonE*float64(nl-1) - lonO*float64(nl) + 0.5)
+		lat = rlat1
+		lon = (360.0 / ni) * (mod(m, ni) + lonO)
+	} else {
+		ni := math.Max(float64(nl), 1)
+		m := math.Floor(lonE*float64(nl-1) - lonO*float64(nl) + 0.5)
+		lat = rlat0
+		lon = (360.0 / ni) * (mod(m, ni) + lonE)
+	}
+
+	if lon >= 180 {
+		lon -= 360
+	}
+	return lat, lon, true
+}
+
+// LocalAirborne decodes an airborne position from a single frame relative to
+// a reference position within 180 NM of the aircraft.
+func LocalAirborne(refLat, refLon float64, f CPRFrame) (lat, lon float64) {
+	i := 0.0
+	if f.Odd {
+		i = 1
+	}
+
+	latCPR := float64(f.Lat) / cprScale
+	lonCPR := float64(f.Lon) / cprScale
+
+	dLat := 360.0 / (60 - i)
+	j := math.Floor(refLat/dLat) + math.Floor(0.5+mod(refLat, dLat)/dLat-latCPR)
+	lat = dLat * (j + latCPR)
+
+	dLon := 360.0 / math.Max(float64(cprNL(lat))-i, 1)
+	m := math.Floor(refLon/dLon) + math.Floor(0.5+mod(refLon, dLon)/dLon-lonCPR)
+	lon = dLon * (m + lonCPR)
+
+	return lat, lon
+}
+
+// cprNL returns the number of longitude zones at the given latitude.
+func cprNL(lat float64) int {
+	lat = math.Abs(lat)
+	switch {
+	case lat == 0:
+		return 59
+	case lat == 87:
+		return 2
+	case lat > 87:
+		return 1
+	}
+
+	const nz = 15
+	a := 1 - math.Cos(math.Pi/(2*nz))
+	b := math.Pow(math.Cos(math.Pi/180*lat), 2)
+	return int(math.Floor(2 * math.Pi / math.Acos(1-a/b)))
+}
+
+// mod is a floating point modulo that always returns a non-negative result.
+func mod(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r < 0 {
+		r += b
+	}
+	return r
+}
+
+// PositionDecoder resolves the CPR frames of a single aircraft into
+// positions, using global decoding when an even/odd pair is available and
+// local decoding against the last known position otherwise.
+type PositionDecoder struct {
+	even, odd     CPRFrame
+	evenAt, oddAt time.Time
+	lat, lon      float64
+	posAt         time.Time
+}
+
+// Decode adds a frame received at the given time and returns the resulting
+// position. The reference position, typically the receiver location, is used
+// for local decoding when the aircraft has no recent position of its own;
+// pass hasRef false if no reference is known.
+func (d *PositionDecoder) Decode(f CPRFrame, at time.Time, refLat, refLon float64, hasRef bool) (lat, lon float64, ok bool) {
+	if f.Odd {
+		d.odd, d.oddAt = f, at
+	} else {
+		d.even, d.evenAt = f, at
+	}
+
+	switch {
+	case !d.evenAt.IsZero() && !d.oddAt.IsZero() && absDuration(d.evenAt.Sub(d.oddAt)) <= cprPairMaxAge:
+		lat, lon, ok = GlobalAirborne(d.even, d.odd, f.Odd)
+	case !d.posAt.IsZero() && at.Sub(d.posAt) <= localRefMaxAge:
+		lat, lon = LocalAirborne(d.lat, d.lon, f)
+		ok = true
+	case hasRef:
+		lat, lon = LocalAirborne(refLat, refLon, f)
+		ok = true
+	}
+
+	if !ok {
+		return 0, 0, false
+	}
+
+	d.lat, d.lon, d.posAt = lat, lon, at
+	return lat, lon, true
+}
+
+// absDuration returns the absolute value of d.
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
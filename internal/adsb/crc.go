@@ -0,0 +1,38 @@
+package adsb
+
+// crcGenerator is the Mode S CRC-24 generator polynomial.
+const crcGenerator = 0xFFF409
+
+// crcTable holds the CRC-24 remainder for every byte value.
+var crcTable = func() [256]uint32 {
+	var table [256]uint32
+	for i := range table {
+		c := uint32(i) << 16
+		for j := 0; j < 8; j++ {
+			if c&0x800000 != 0 {
+				c = (c << 1) ^ crcGenerator
+			} else {
+				c <<= 1
+			}
+		}
+		table[i] = c & 0xFFFFFF
+	}
+	return table
+}()
+
+// checksum computes the Mode S CRC-24 over data.
+func checksum(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc = ((crc << 8) ^ crcTable[byte(crc>>16)^b]) & 0xFFFFFF
+	}
+	return crc
+}
+
+// parity returns the CRC-24 remainder of a whole frame, which is zero for an
+// undamaged extended squitter and the transmitting address for Mode S replies.
+func parity(frame []byte) uint32 {
+	n := len(frame) - 3
+	pi := uint32(frame[n])<<16 | uint32(frame[n+1])<<8 | uint32(frame[n+2])
+	return checksum(frame[:n]) ^ pi
+}
@@ -0,0 +1,250 @@
+// Package adsb decodes Mode S extended squitter (ADS-B) messages.
+package adsb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+const (
+	// ShortFrameLen is the length in bytes of a 56-bit Mode S frame.
+	ShortFrameLen = 7
+	// LongFrameLen is the length in bytes of a 112-bit Mode S frame.
+	LongFrameLen = 14
+)
+
+// Message kinds decoded from extended squitters.
+const (
+	KindIdentification = "identification"
+	KindSurface        = "surface_position"
+	KindAirborne       = "airborne_position"
+	KindVelocity       = "velocity"
+	KindStatus         = "aircraft_status"
+)
+
+// ErrUnsupported is returned for frames that are valid but carry no
+// information the decoder understands.
+var ErrUnsupported = errors.New("unsupported Mode S message")
+
+// callsignCharset maps 6-bit identification characters to ASCII.
+const callsignCharset = "#ABCDEFGHIJKLMNOPQRSTUVWXYZ##### ###############0123456789######"
+
+// emergencyStates names the emergency states of an aircraft status message,
+// using the same values as the readsb "emergency" field.
+var emergencyStates = []string{"none", "general", "lifeguard", "minfuel", "nordo", "unlawful", "downed", "reserved"}
+
+// Message is a decoded DF17/18 extended squitter.
+type Message struct {
+	DF           int
+	TypeCode     int
+	ICAO         string
+	Kind         string
+	Callsign     string
+	Category     string
+	Altitude     int
+	HasAlt       bool
+	GNSSAlt      bool
+	CPR          CPRFrame
+	HasCPR       bool
+	GS           float64
+	Track        float64
+	HasGS        bool
+	Heading      float64
+	Airspeed     int
+	TrueAirspeed bool
+	HasAirspeed  bool
+	VertRate     int
+	BaroVertRate bool
+	HasVertRate  bool
+	Squawk       string
+	Emergency    string
+}
+
+// Decode decodes a binary Mode S frame. Only DF17 and DF18 extended
+// squitters with a valid CRC are decoded; other downlink formats return
+// ErrUnsupported.
+func Decode(frame []byte) (*Message, error) {
+	if len(frame) != ShortFrameLen && len(frame) != LongFrameLen {
+		return nil, fmt.Errorf("invalid Mode S frame length %d", len(frame))
+	}
+
+	df := int(frame[0] >> 3)
+	if df != 17 && df != 18 {
+		return nil, ErrUnsupported
+	}
+	if len(frame) != LongFrameLen {
+		return nil, fmt.Errorf("DF%d frame must be %d bytes, got %d", df, LongFrameLen, len(frame))
+	}
+	if parity(frame) != 0 {
+		return nil, errors.New("CRC mismatch")
+	}
+	// DF18 control fields other than 0 and 1 carry non-ICAO or TIS-B data.
+	if df == 18 && frame[0]&7 > 1 {
+		return nil, ErrUnsupported
+	}
+
+	me := frame[4:11]
+	msg := &Message{
+		DF:       df,
+		TypeCode: int(me[0] >> 3),
+		ICAO:     fmt.Sprintf("%02x%02x%02x", frame[1], frame[2], frame[3]),
+	}
+
+	switch tc := msg.TypeCode; {
+	case tc >= 1 && tc <= 4:
+		decodeIdentification(msg, me)
+	case tc >= 5 && tc <= 8:
+		msg.Kind = KindSurface
+	case tc >= 9 && tc <= 18, tc >= 20 && tc <= 22:
+		decodeAirbornePosition(msg, me)
+	case tc == 19:
+		if err := decodeVelocity(msg, me); err != nil {
+			return nil, err
+		}
+	case tc == 28:
+		if err := decodeStatus(msg, me); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+
+	return msg, nil
+}
+
+// decodeIdentification decodes a type code 1-4 identification message.
+func decodeIdentification(msg *Message, me []byte) {
+	msg.Kind = KindIdentification
+
+	// Type codes 4..1 map to emitter category sets A..D.
+	category := me[0] & 7
+	if category != 0 {
+		msg.Category = fmt.Sprintf("%c%d", 'A'+4-msg.TypeCode, category)
+	}
+
+	bits := uint64(0)
+	for _, b := range me[1:7] {
+		bits = bits<<8 | uint64(b)
+	}
+	var cs [8]byte
+	for i := range cs {
+		cs[i] = callsignCharset[(bits>>(42-6*uint(i)))&0x3F]
+	}
+	msg.Callsign = strings.TrimRight(strings.ReplaceAll(string(cs[:]), "#", ""), " ")
+}
+
+// decodeAirbornePosition decodes a type code 9-18 or 20-22 position message.
+func decodeAirbornePosition(msg *Message, me []byte) {
+	msg.Kind = KindAirborne
+	msg.GNSSAlt = msg.TypeCode >= 20
+
+	ac := int(me[1])<<4 | int(me[2])>>4
+	if msg.GNSSAlt {
+		// GNSS height is reported in metres.
+		if ac != 0 {
+			msg.Altitude = int(math.Round(float64(ac) * 3.28084))
+			msg.HasAlt = true
+		}
+	} else if alt, ok := decodeAC12(ac); ok {
+		msg.Altitude = alt
+		msg.HasAlt = true
+	}
+
+	msg.CPR = CPRFrame{
+		Odd: me[2]&0x04 != 0,
+		Lat: int(me[2]&0x03)<<15 | int(me[3])<<7 | int(me[4])>>1,
+		Lon: int(me[4]&0x01)<<16 | int(me[5])<<8 | int(me[6]),
+	}
+	msg.HasCPR = true
+}
+
+// decodeAC12 decodes a 12-bit altitude field with 25 ft resolution. Gillham
+// coded (100 ft) altitudes are not supported.
+func decodeAC12(ac int) (int, bool) {
+	if ac == 0 || ac&0x010 == 0 {
+		return 0, false
+	}
+	n := (ac&0xFE0)>>1 | ac&0x00F
+	return n*25 - 1000, true
+}
+
+// decodeVelocity decodes a type code 19 airborne velocity message.
+func decodeVelocity(msg *Message, me []byte) error {
+	msg.Kind = KindVelocity
+
+	subtype := me[0] & 7
+	switch subtype {
+	case 1, 2:
+		ew := int(me[1]&0x03)<<8 | int(me[2])
+		ns := int(me[3]&0x7F)<<3 | int(me[4])>>5
+		if ew != 0 && ns != 0 {
+			vx := float64(ew - 1)
+			vy := float64(ns - 1)
+			if subtype == 2 {
+				vx *= 4
+				vy *= 4
+			}
+			if me[1]&0x04 != 0 {
+				vx = -vx
+			}
+			if me[3]&0x80 != 0 {
+				vy = -vy
+			}
+			msg.GS = math.Hypot(vx, vy)
+			msg.Track = mod(math.Atan2(vx, vy)*180/math.Pi, 360)
+			msg.HasGS = true
+		}
+	case 3, 4:
+		if me[1]&0x04 != 0 {
+			hdg := int(me[1]&0x03)<<8 | int(me[2])
+			msg.Heading = float64(hdg) * 360 / 1024
+		}
+		as := int(me[3]&0x7F)<<3 | int(me[4])>>5
+		if as != 0 {
+			msg.Airspeed = as - 1
+			if subtype == 4 {
+				msg.Airspeed *= 4
+			}
+			msg.TrueAirspeed = me[3]&0x80 != 0
+			msg.HasAirspeed = true
+		}
+	default:
+		return ErrUnsupported
+	}
+
+	vr := int(me[4]&0x07)<<6 | int(me[5])>>2
+	if vr != 0 {
+		msg.VertRate = (vr - 1) * 64
+		if me[4]&0x08 != 0 {
+			msg.VertRate = -msg.VertRate
+		}
+		msg.BaroVertRate = me[4]&0x10 != 0
+		msg.HasVertRate = true
+	}
+
+	return nil
+}
+
+// decodeStatus decodes a type code 28 emergency/priority status message.
+func decodeStatus(msg *Message, me []byte) error {
+	if me[0]&7 != 1 {
+		return ErrUnsupported
+	}
+	msg.Kind = KindStatus
+	msg.Emergency = emergencyStates[me[1]>>5]
+	msg.Squawk = decodeSquawk(int(me[1]&0x1F)<<8 | int(me[2]))
+	return nil
+}
+
+// decodeSquawk decodes a 13-bit Mode A identity code into its four octal
+// digits. The bits are interleaved as C1 A1 C2 A2 C4 A4 X B1 D1 B2 D2 B4 D4.
+func decodeSquawk(id int) string {
+	bit := func(n uint) int { return (id >> (12 - n)) & 1 }
+	a := bit(1)<<0 | bit(3)<<1 | bit(5)<<2
+	b := bit(7)<<0 | bit(9)<<1 | bit(11)<<2
+	c := bit(0)<<0 | bit(2)<<1 | bit(4)<<2
+	d := bit(8)<<0 | bit(10)<<1 | bit(12)<<2
+	return fmt.Sprintf("%d%d%d%d", a, b, c, d)
+}
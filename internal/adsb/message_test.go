@@ -0,0 +1,181 @@
+package adsb
+
+import (
+	"encoding/hex"
+	"math"
+	"testing"
+	"time"
+)
+
+func mustFrame(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("decode hex %q: %v", s, err)
+	}
+	return b
+}
+
+func TestDecodeIdentification(t *testing.T) {
+	msg, err := Decode(mustFrame(t, "8D4840D6202CC371C32CE0576098"))
+	if err != nil {
+		t.Fatalf("Decode() error = %v", err)
+	}
+	if msg.ICAO != "4840d6" {
+		t.Errorf("expected ICAO 4840d6, got %s", msg.ICAO)
+	}
+	if msg.Kind != KindIdentification {
+		t.Errorf("expected identification, got %s", msg.Kind)
+	}
+	if msg.Callsign != "KLM1023" {
+		t.Errorf("expected callsign KLM1023, got %q", msg.Callsign)
+	}
+}
+
+func TestDecodeAirbornePosition(t *testing.T) {
+	even, err := Decode(mustFrame(t, "8D40621D58C382D690C8AC2863A7"))
+	if err != nil {
+		t.Fatalf("Decode(even) error = %v", err)
+	}
+	odd, err := Decode(mustFrame(t, "8D40621D58C386435CC412692AD6"))
+	if err != nil {
+		t.Fatalf("Decode(odd) error = %v", err)
+	}
+
+	if even.Kind != KindAirborne || !even.HasCPR || even.CPR.Odd {
+		t.Fatalf("expected even airborne position, got %+v", even)
+	}
+	if !odd.CPR.Odd {
+		t.Fatal("expected odd frame")
+	}
+	if !even.HasAlt || even.Altitude != 38000 {
+		t.Errorf("expected altitude 38000, got %d (has=%v)", even.Altitude, even.HasAlt)
+	}
+
+	lat, lon, ok := GlobalAirborne(even.CPR, odd.CPR, false)
+	if !ok {
+		t.Fatal("expected global decode to succeed")
+	}
+	if math.Abs(lat-52.25720) > 0.0001 || math.Abs(lon-3.91937) > 0.0001 {
+		t.Errorf("expected 52.25720,3.91937 got %.5f,%.5f", lat, lon)
+	}
+
+	lat, lon = LocalAirborne(52.258, 3.918, even.CPR)
+	if math.Abs(lat-52.25720) > 0.0001 || math.Abs(lon-3.91937) > 0.0001 {
+		t.Errorf("local decode: expected 52.25720,3.91937 got %.5f,%.5f", lat, lon)
+	}
+}
+
+func TestDecodeVelocity(t *testing.T) {
+	tests := []struct {
+		name         string
+		frame        string
+		wantGS       float64
+		wantTrack    float64
+		wantHeading  float64
+		wantAirspeed int
+		wantRate     int
+	}{
+		{
+			name:      "ground speed",
+			frame:     "8D485020994409940838175B284F",
+			wantGS:    159.20,
+			wantTrack: 182.88,
+			wantRate:  -832,
+		},
+		{
+			name:         "airspeed",
+			frame:        "8DA05F219B06B6AF189400CBC33F",
+			wantHeading:  243.98,
+			wantAirspeed: 375,
+			wantRate:     -2304,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := Decode(mustFrame(t, tt.frame))
+			if err != nil {
+				t.Fatalf("Decode() error = %v", err)
+			}
+			if msg.Kind != KindVelocity {
+				t.Fatalf("expected velocity, got %s", msg.Kind)
+			}
+			if math.Abs(msg.GS-tt.wantGS) > 0.01 || math.Abs(msg.Track-tt.wantTrack) > 0.01 {
+				t.Errorf("expected gs %.2f track %.2f, got %.2f %.2f", tt.wantGS, tt.wantTrack, msg.GS, msg.Track)
+			}
+			if math.Abs(msg.Heading-tt.wantHeading) > 0.01 || msg.Airspeed != tt.wantAirspeed {
+				t.Errorf("expected heading %.2f airspeed %d, got %.2f %d", tt.wantHeading, tt.wantAirspeed, msg.Heading, msg.Airspeed)
+			}
+			if !msg.HasVertRate || msg.VertRate != tt.wantRate {
+				t.Errorf("expected vertical rate %d, got %d", tt.wantRate, msg.VertRate)
+			}
+		})
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		frame string
+	}{
+		{name: "bad length", frame: "8D4840D6202CC3"},
+		{name: "bad CRC", frame: "8D4840D6202CC371C32CE0576099"},
+		{name: "short frame", frame: "5D4840D6202CC3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Decode(mustFrame(t, tt.frame)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
+
+func TestDecodeSquawk(t *testing.T) {
+	// Build identity codes from octal digits using the Mode A bit layout.
+	encode := func(a, b, c, d int) int {
+		bits := []int{c & 1, a & 1, c >> 1 & 1, a >> 1 & 1, c >> 2 & 1, a >> 2 & 1, 0, b & 1, d & 1, b >> 1 & 1, d >> 1 & 1, b >> 2 & 1, d >> 2 & 1}
+		id := 0
+		for _, bit := range bits {
+			id = id<<1 | bit
+		}
+		return id
+	}
+
+	for _, code := range []string{"7700", "7500", "1200", "0000", "4521"} {
+		d := func(i int) int { return int(code[i] - '0') }
+		if got := decodeSquawk(encode(d(0), d(1), d(2), d(3))); got != code {
+			t.Errorf("decodeSquawk() = %s, expected %s", got, code)
+		}
+	}
+}
+
+func TestPositionDecoder(t *testing.T) {
+	even, _ := Decode(mustFrame(t, "8D40621D58C382D690C8AC2863A7"))
+	odd, _ := Decode(mustFrame(t, "8D40621D58C386435CC412692AD6"))
+
+	var d PositionDecoder
+	base := time.Date(2016, 3, 14, 23, 0, 0, 0, time.UTC)
+
+	if _, _, ok := d.Decode(odd.CPR, base, 0, 0, false); ok {
+		t.Fatal("expected a single frame without reference to be undecodable")
+	}
+	lat, lon, ok := d.Decode(even.CPR, base.Add(2*time.Second), 0, 0, false)
+	if !ok {
+		t.Fatal("expected global decode from frame pair")
+	}
+	if math.Abs(lat-52.25720) > 0.0001 || math.Abs(lon-3.91937) > 0.0001 {
+		t.Errorf("expected 52.25720,3.91937 got %.5f,%.5f", lat, lon)
+	}
+
+	var fresh PositionDecoder
+	lat, lon, ok = fresh.Decode(even.CPR, base, 52.2, 3.9, true)
+	if !ok {
+		t.Fatal("expected local decode against reference")
+	}
+	if math.Abs(lat-52.25720) > 0.0001 || math.Abs(lon-3.91937) > 0.0001 {
+		t.Errorf("expected 52.25720,3.91937 got %.5f,%.5f", lat, lon)
+	}
+}
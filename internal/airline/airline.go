@@ -0,0 +1,191 @@
+// Package airline decodes airline callsigns, such as "UAL123", into the
+// operator, its radio telephony designator and the flight number, using a
+// bundled table of ICAO airline designators that a local file can extend.
+package airline
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+//go:embed airlines.csv
+var bundled string
+
+// Airline is an operator with an ICAO three letter designator.
+type Airline struct {
+	Designator string
+	Name       string
+	Telephony  string
+}
+
+// Flight is a decoded airline callsign.
+type Flight struct {
+	Airline Airline
+	// Number is the flight number without leading zeros, such as "123" or
+	// "12A".
+	Number string
+}
+
+// Table maps ICAO designators to airlines. It is not modified once built, so
+// it is safe for concurrent use.
+type Table struct {
+	airlines map[string]Airline
+}
+
+// Bundled returns the table of airlines shipped with the program.
+func Bundled() *Table {
+	airlines, err := Parse(strings.NewReader(bundled))
+	if err != nil {
+		panic(fmt.Sprintf("bundled airline table: %v", err))
+	}
+	return &Table{airlines: airlines}
+}
+
+// Load returns the bundled table with the airlines in the file at path added.
+// An airline in the file replaces the bundled one with the same designator.
+// The file is a comma separated designator,name,telephony table with a header
+// row, the same as the bundled one.
+func Load(path string) (*Table, error) {
+	t := Bundled()
+	// #nosec G304 -- path is supplied by the operator
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open airline table: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	airlines, err := Parse(file)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	for designator, a := range airlines {
+		t.airlines[designator] = a
+	}
+	return t, nil
+}
+
+// Parse reads a designator,name,telephony table with a header row, returning
+// the airlines by upper-case designator.
+func Parse(r io.Reader) (map[string]Airline, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+
+	if _, err := reader.Read(); err != nil {
+		return nil, fmt.Errorf("failed to read airline table header: %w", err)
+	}
+
+	airlines := make(map[string]Airline)
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read airline table: %w", err)
+		}
+		if len(row) < 2 {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: expected designator,name,telephony", line)
+		}
+		designator := strings.ToUpper(strings.TrimSpace(row[0]))
+		if !isDesignator(designator) {
+			line, _ := reader.FieldPos(0)
+			return nil, fmt.Errorf("line %d: invalid designator %q", line, row[0])
+		}
+		a := Airline{Designator: designator, Name: strings.TrimSpace(row[1])}
+		if len(row) > 2 {
+			a.Telephony = strings.TrimSpace(row[2])
+		}
+		airlines[designator] = a
+	}
+	if len(airlines) == 0 {
+		return nil, errors.New("no airlines found")
+	}
+	return airlines, nil
+}
+
+// Len returns the number of airlines in the table.
+func (t *Table) Len() int {
+	return len(t.airlines)
+}
+
+// Lookup returns the airline with an ICAO designator, ignoring case.
+func (t *Table) Lookup(designator string) (Airline, bool) {
+	a, ok := t.airlines[strings.ToUpper(designator)]
+	return a, ok
+}
+
+// Decode splits a callsign into a known airline designator and a flight
+// number. Callsigns are three letters followed by one to four characters
+// starting with a digit; padding is ignored. Registrations used as callsigns,
+// such as "N12345", and unknown designators are not decoded.
+func (t *Table) Decode(callsign string) (Flight, bool) {
+	callsign = strings.ToUpper(strings.TrimSpace(callsign))
+	if len(callsign) < 4 || len(callsign) > 7 {
+		return Flight{}, false
+	}
+	designator, number := callsign[:3], callsign[3:]
+	if !isDesignator(designator) || number[0] < '0' || number[0] > '9' || !isAlphanumeric(number) {
+		return Flight{}, false
+	}
+	a, ok := t.airlines[designator]
+	if !ok {
+		return Flight{}, false
+	}
+	if trimmed := strings.TrimLeft(number, "0"); trimmed != "" {
+		number = trimmed
+	} else {
+		number = "0"
+	}
+	return Flight{Airline: a, Number: number}, true
+}
+
+// Enrich sets the callsign of each aircraft reporting a flight, and the
+// airline, telephony and flight number when the callsign decodes.
+func (t *Table) Enrich(aircraft []piaware.Aircraft) {
+	for i := range aircraft {
+		a := &aircraft[i]
+		a.Callsign = strings.TrimSpace(a.Flight)
+		if a.Callsign == "" {
+			continue
+		}
+		flight, ok := t.Decode(a.Callsign)
+		if !ok {
+			continue
+		}
+		a.Airline = flight.Airline.Name
+		a.Telephony = flight.Airline.Telephony
+		a.FlightNumber = flight.Number
+	}
+}
+
+func isDesignator(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, c := range s {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+func isAlphanumeric(s string) bool {
+	for _, c := range s {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+package airline
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func TestDecode(t *testing.T) {
+	table := Bundled()
+	tests := []struct {
+		callsign string
+		want     Flight
+		ok       bool
+	}{
+		{"UAL123  ", Flight{Airline: Airline{Designator: "UAL", Name: "United Airlines", Telephony: "UNITED"}, Number: "123"}, true},
+		{"baw12a", Flight{Airline: Airline{Designator: "BAW", Name: "British Airways", Telephony: "SPEEDBIRD"}, Number: "12A"}, true},
+		{"DLH0400", Flight{Airline: Airline{Designator: "DLH", Name: "Lufthansa", Telephony: "LUFTHANSA"}, Number: "400"}, true},
+		{"N12345", Flight{}, false},
+		{"GABCD", Flight{}, false},
+		{"UAL", Flight{}, false},
+		{"UALX12", Flight{}, false},
+		{"UAL12345", Flight{}, false},
+		{"ZZZ123", Flight{}, false},
+		{"", Flight{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.callsign, func(t *testing.T) {
+			got, ok := table.Decode(tt.callsign)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Decode(%q) = %+v, %v; want %+v, %v", tt.callsign, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLoadOverridesBundled(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "airlines.csv")
+	data := "designator,name,telephony\n# local operators\nUAL,United,UNITED\nzzz,Example Air,EXAMPLE\n"
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatalf("write table: %v", err)
+	}
+	table, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if table.Len() != Bundled().Len()+1 {
+		t.Errorf("expected one airline added to the bundled table, got %d", table.Len())
+	}
+	if a, ok := table.Lookup("ual"); !ok || a.Name != "United" {
+		t.Errorf("Lookup(ual) = %+v, %v", a, ok)
+	}
+	if a, ok := table.Lookup("ZZZ"); !ok || a.Telephony != "EXAMPLE" {
+		t.Errorf("Lookup(ZZZ) = %+v, %v", a, ok)
+	}
+	if _, ok := table.Lookup("BAW"); !ok {
+		t.Error("expected bundled airlines to be kept")
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "header"},
+		{"header only", "designator,name,telephony\n", "no airlines found"},
+		{"bad designator", "designator,name,telephony\nUA,United,UNITED\n", `line 2: invalid designator "UA"`},
+		{"missing name", "designator,name,telephony\nUAL\n", "line 2: expected"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	aircraft := []piaware.Aircraft{
+		{Hex: "a1b2c3", Flight: "UAL123  "},
+		{Hex: "a00001", Flight: "N12345  "},
+		{Hex: "a00002"},
+	}
+	Bundled().Enrich(aircraft)
+	if a := aircraft[0]; a.Callsign != "UAL123" || a.Airline != "United Airlines" || a.Telephony != "UNITED" || a.FlightNumber != "123" {
+		t.Errorf("unexpected airline aircraft %+v", a)
+	}
+	if a := aircraft[1]; a.Callsign != "N12345" || a.Airline != "" || a.FlightNumber != "" {
+		t.Errorf("unexpected private aircraft %+v", a)
+	}
+	if a := aircraft[2]; a.Callsign != "" {
+		t.Errorf("expected no callsign, got %+v", a)
+	}
+}
@@ -0,0 +1,92 @@
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// Reader reads snapshots back from a capture file written by Recorder, or
+// from a tar1090 history_*.json file, which holds a single aircraft.json
+// snapshot timed by its now field. Gzip-compressed files are detected and
+// decompressed.
+type Reader struct {
+	file *os.File
+	gz   *gzip.Reader
+	dec  *json.Decoder
+}
+
+// entry accepts either a capture record or a bare aircraft.json snapshot.
+type entry struct {
+	Time     *time.Time    `json:"time"`
+	Snapshot *piaware.Data `json:"data"`
+	piaware.Data
+}
+
+// Open opens the capture or history file at path.
+func Open(path string) (*Reader, error) {
+	// #nosec G304 -- path is supplied by the operator
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open capture: %w", err)
+	}
+
+	r := &Reader{file: file}
+	buffered := bufio.NewReader(file)
+	var src io.Reader = buffered
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		r.gz, err = gzip.NewReader(buffered)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to decompress capture: %w", err)
+		}
+		src = r.gz
+	}
+	r.dec = json.NewDecoder(src)
+	return r, nil
+}
+
+// Next returns the next snapshot, or io.EOF when there are none left. A
+// capture cut short while it was being written ends at its last complete
+// record.
+func (r *Reader) Next() (Record, error) {
+	var e entry
+	if err := r.dec.Decode(&e); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, io.EOF
+		}
+		return Record{}, err
+	}
+
+	switch {
+	case e.Snapshot != nil && e.Time != nil:
+		return Record{Time: *e.Time, Data: e.Snapshot}, nil
+	case e.Now > 0:
+		data := e.Data
+		return Record{Time: snapshotTime(e.Now), Data: &data}, nil
+	default:
+		return Record{}, errors.New("capture entry has neither a record time nor a snapshot time")
+	}
+}
+
+// Close closes the underlying file.
+func (r *Reader) Close() error {
+	if r.gz != nil {
+		_ = r.gz.Close()
+	}
+	return r.file.Close()
+}
+
+// snapshotTime converts an aircraft.json now (seconds since the epoch) to a time.
+func snapshotTime(now float64) time.Time {
+	sec, frac := math.Modf(now)
+	return time.Unix(int64(sec), int64(math.Round(frac*1e9))).UTC()
+}
@@ -0,0 +1,119 @@
+package capture
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReaderCapture(t *testing.T) {
+	dir := t.TempDir()
+	r, err := NewRecorder(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 2; i++ {
+		if err := r.Record(start.Add(time.Duration(i)*time.Second), snapshot(float64(1000+i))); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	// Leave the capture unfinished, as after a crash.
+	paths, _ := filepath.Glob(filepath.Join(dir, "*"+fileSuffix))
+	if len(paths) != 1 {
+		t.Fatalf("expected one capture file, got %v", paths)
+	}
+
+	reader, err := Open(paths[0])
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	for i := 0; i < 2; i++ {
+		rec, err := reader.Next()
+		if err != nil {
+			t.Fatalf("Next() record %d error = %v", i, err)
+		}
+		if !rec.Time.Equal(start.Add(time.Duration(i) * time.Second)) {
+			t.Errorf("record %d: unexpected time %v", i, rec.Time)
+		}
+		if rec.Data.Now != float64(1000+i) || len(rec.Data.Aircraft) != 2 {
+			t.Errorf("record %d: unexpected snapshot %+v", i, rec.Data)
+		}
+	}
+	if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+		t.Errorf("expected io.EOF at the end of an unfinished capture, got %v", err)
+	}
+	_ = r.Close()
+}
+
+func TestReaderHistory(t *testing.T) {
+	const history = `{"now":1714564800.5,"messages":12,"aircraft":[{"hex":"aaa111","alt_baro":"ground","lat":40.1,"lon":-74.1}]}`
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "history_3.json")
+	if err := os.WriteFile(plain, []byte(history), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	compressed := filepath.Join(dir, "history_4.json.gz")
+	f, err := os.Create(compressed)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	gz := gzip.NewWriter(f)
+	_, _ = io.WriteString(gz, history)
+	_ = gz.Close()
+	_ = f.Close()
+
+	for _, path := range []string{plain, compressed} {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			reader, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer func() {
+				_ = reader.Close()
+			}()
+
+			rec, err := reader.Next()
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			want := time.Date(2024, 5, 1, 12, 0, 0, 500000000, time.UTC)
+			if !rec.Time.Equal(want) {
+				t.Errorf("expected snapshot time %v, got %v", want, rec.Time)
+			}
+			if len(rec.Data.Aircraft) != 1 || !rec.Data.Aircraft[0].OnGround {
+				t.Errorf("unexpected snapshot %+v", rec.Data)
+			}
+			if _, err := reader.Next(); !errors.Is(err, io.EOF) {
+				t.Errorf("expected io.EOF after the snapshot, got %v", err)
+			}
+		})
+	}
+}
+
+func TestReaderRejectsUnknownJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "other.json")
+	if err := os.WriteFile(path, []byte(`{"hello":"world"}`), 0o600); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+	reader, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+	if _, err := reader.Next(); err == nil || errors.Is(err, io.EOF) {
+		t.Errorf("expected error for unrecognised JSON, got %v", err)
+	}
+}
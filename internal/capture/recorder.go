@@ -0,0 +1,168 @@
+// Package capture records the aircraft snapshots the monitor fetches so that
+// alerts can be reproduced later from the exact same data.
+package capture
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+const (
+	// filePrefix and fileSuffix frame the names of capture files.
+	filePrefix = "capture-"
+	fileSuffix = ".jsonl.gz"
+	// fileTimeFormat orders capture file names chronologically.
+	fileTimeFormat = "20060102T150405.000Z"
+)
+
+// Config holds capture settings. Recording is enabled when Dir is set.
+type Config struct {
+	Dir            string
+	MaxSizeMB      int
+	RotateInterval time.Duration
+}
+
+// Record is one line of a capture file: a snapshot and the time it was
+// fetched.
+type Record struct {
+	Time time.Time     `json:"time"`
+	Data *piaware.Data `json:"data"`
+}
+
+// Recorder writes snapshots to gzip-compressed JSONL files in a directory,
+// starting a new file when the current one reaches the size limit or has
+// been open for the rotation interval.
+type Recorder struct {
+	cfg Config
+
+	mutex   sync.Mutex
+	file    *os.File
+	counter *countingWriter
+	gz      *gzip.Writer
+	opened  time.Time
+}
+
+// NewRecorder creates a recorder writing to cfg.Dir, creating it if needed.
+// A zero MaxSizeMB or RotateInterval disables that rotation trigger.
+func NewRecorder(cfg Config) (*Recorder, error) {
+	if cfg.Dir == "" {
+		return nil, errors.New("capture directory is required")
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create capture directory: %w", err)
+	}
+	return &Recorder{cfg: cfg}, nil
+}
+
+// Record appends a snapshot fetched at the given time. Each record is
+// flushed so a capture cut short by a crash is still readable.
+func (r *Recorder) Record(at time.Time, data *piaware.Data) error {
+	line, err := json.Marshal(Record{Time: at.UTC(), Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode capture record: %w", err)
+	}
+	line = append(line, '\n')
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file != nil && r.shouldRotate(at) {
+		if err := r.closeFile(); err != nil {
+			return err
+		}
+	}
+	if r.file == nil {
+		if err := r.openFile(at); err != nil {
+			return err
+		}
+	}
+
+	if _, err := r.gz.Write(line); err != nil {
+		return fmt.Errorf("failed to write capture record: %w", err)
+	}
+	if err := r.gz.Flush(); err != nil {
+		return fmt.Errorf("failed to flush capture file: %w", err)
+	}
+	return nil
+}
+
+// Close finishes the current capture file.
+func (r *Recorder) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	return r.closeFile()
+}
+
+// shouldRotate reports whether the current file has reached a rotation limit.
+func (r *Recorder) shouldRotate(at time.Time) bool {
+	if r.cfg.MaxSizeMB > 0 && r.counter.n >= int64(r.cfg.MaxSizeMB)*1024*1024 {
+		return true
+	}
+	return r.cfg.RotateInterval > 0 && at.Sub(r.opened) >= r.cfg.RotateInterval
+}
+
+// openFile starts a new capture file named after at. A numeric suffix keeps
+// files rotated within the same millisecond apart.
+func (r *Recorder) openFile(at time.Time) error {
+	base := filePrefix + at.UTC().Format(fileTimeFormat)
+	for i := 0; ; i++ {
+		name := base + fileSuffix
+		if i > 0 {
+			name = fmt.Sprintf("%s-%d%s", base, i, fileSuffix)
+		}
+		path := filepath.Join(r.cfg.Dir, name)
+
+		// #nosec G304 -- path is built from the configured capture directory
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to create capture file: %w", err)
+		}
+
+		r.file = file
+		r.counter = &countingWriter{w: file}
+		r.gz = gzip.NewWriter(r.counter)
+		r.opened = at
+		return nil
+	}
+}
+
+// closeFile finishes the gzip stream and closes the current file.
+func (r *Recorder) closeFile() error {
+	gzErr := r.gz.Close()
+	fileErr := r.file.Close()
+	r.file, r.counter, r.gz = nil, nil, nil
+	if gzErr != nil {
+		return fmt.Errorf("failed to finish capture file: %w", gzErr)
+	}
+	if fileErr != nil {
+		return fmt.Errorf("failed to close capture file: %w", fileErr)
+	}
+	return nil
+}
+
+// countingWriter tracks the number of compressed bytes written to a file.
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
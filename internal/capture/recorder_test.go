@@ -0,0 +1,155 @@
+package capture
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// readCaptureFiles returns the records in every capture file in dir, in
+// file name order.
+func readCaptureFiles(t *testing.T, dir string) [][]Record {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, filePrefix+"*"+fileSuffix))
+	if err != nil {
+		t.Fatalf("glob: %v", err)
+	}
+	sort.Strings(paths)
+
+	var files [][]Record
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			t.Fatalf("open %s: %v", path, err)
+		}
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			t.Fatalf("gzip %s: %v", path, err)
+		}
+		var records []Record
+		scanner := bufio.NewScanner(gz)
+		scanner.Buffer(make([]byte, 1024*1024), 1024*1024)
+		for scanner.Scan() {
+			var r Record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				t.Fatalf("decode record in %s: %v", path, err)
+			}
+			records = append(records, r)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		_ = f.Close()
+		files = append(files, records)
+	}
+	return files
+}
+
+func snapshot(now float64) *piaware.Data {
+	return &piaware.Data{Now: now, Aircraft: []piaware.Aircraft{
+		{Hex: "aaa111", Flight: "TEST1", Lat: 40.1, Lon: -74.1, AltBaro: 3000, SeenPos: 1.5},
+		{Hex: "bbb222", Lat: 40.2, Lon: -74.2, OnGround: true},
+	}}
+}
+
+func TestRecorderRoundTrip(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "captures")
+	r, err := NewRecorder(Config{Dir: dir})
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := r.Record(start.Add(time.Duration(i)*time.Second), snapshot(float64(1000+i))); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	files := readCaptureFiles(t, dir)
+	if len(files) != 1 || len(files[0]) != 3 {
+		t.Fatalf("expected one file with 3 records, got %d files", len(files))
+	}
+	rec := files[0][2]
+	if !rec.Time.Equal(start.Add(2 * time.Second)) {
+		t.Errorf("expected record time %v, got %v", start.Add(2*time.Second), rec.Time)
+	}
+	if rec.Data.Now != 1002 || len(rec.Data.Aircraft) != 2 {
+		t.Fatalf("unexpected snapshot %+v", rec.Data)
+	}
+	if rec.Data.Aircraft[0].SeenPos != 1.5 || rec.Data.Aircraft[0].Flight != "TEST1" {
+		t.Errorf("unexpected aircraft %+v", rec.Data.Aircraft[0])
+	}
+	if !rec.Data.Aircraft[1].OnGround {
+		t.Error("expected ground state to survive the capture")
+	}
+}
+
+func TestRecorderRotation(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("by interval", func(t *testing.T) {
+		dir := t.TempDir()
+		r, err := NewRecorder(Config{Dir: dir, RotateInterval: time.Minute})
+		if err != nil {
+			t.Fatalf("NewRecorder() error = %v", err)
+		}
+		for _, offset := range []time.Duration{0, 30 * time.Second, time.Minute, 90 * time.Second, 2 * time.Minute} {
+			if err := r.Record(start.Add(offset), snapshot(1000)); err != nil {
+				t.Fatalf("Record() error = %v", err)
+			}
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		files := readCaptureFiles(t, dir)
+		if len(files) != 3 {
+			t.Fatalf("expected 3 files, got %d", len(files))
+		}
+		if len(files[0]) != 2 || len(files[1]) != 2 || len(files[2]) != 1 {
+			t.Errorf("unexpected records per file: %d, %d, %d", len(files[0]), len(files[1]), len(files[2]))
+		}
+	})
+
+	t.Run("by size", func(t *testing.T) {
+		dir := t.TempDir()
+		r, err := NewRecorder(Config{Dir: dir, MaxSizeMB: 1})
+		if err != nil {
+			t.Fatalf("NewRecorder() error = %v", err)
+		}
+		// Pretend the current file is already full; rotation happens on the
+		// next record, within the same millisecond.
+		if err := r.Record(start, snapshot(1000)); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		r.counter.n = 1024 * 1024
+		if err := r.Record(start, snapshot(1001)); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		files := readCaptureFiles(t, dir)
+		if len(files) != 2 || len(files[0]) != 1 || len(files[1]) != 1 {
+			t.Fatalf("expected 2 files with one record each, got %d", len(files))
+		}
+	})
+}
+
+func TestNewRecorderRequiresDir(t *testing.T) {
+	if _, err := NewRecorder(Config{}); err == nil {
+		t.Error("expected error without a capture directory")
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"math"
 	"net/http"
 	"time"
 
+	"github.com/benvon/whats-flying-over-me/internal/geometry"
 	"github.com/benvon/whats-flying-over-me/internal/piaware"
 )
 
@@ -69,7 +69,7 @@ func NewElasticSearchCataloger(config ElasticSearchConfig) (*ElasticSearchCatalo
 }
 
 // CatalogAircraft catalogs aircraft data to ElasticSearch
-func (e *ElasticSearchCataloger) CatalogAircraft(ctx context.Context, aircraft []piaware.Aircraft, baseLat, baseLon float64) error {
+func (e *ElasticSearchCataloger) CatalogAircraft(ctx context.Context, aircraft []piaware.Aircraft, observer geometry.Observer) error {
 	if !e.config.Enabled {
 		return nil
 	}
@@ -83,23 +83,7 @@ func (e *ElasticSearchCataloger) CatalogAircraft(ctx context.Context, aircraft [
 	timestamp := time.Now()
 
 	for _, a := range aircraft {
-		// Calculate distance if coordinates are available
-		var distanceKm float64
-		if a.Lat != 0 && a.Lon != 0 {
-			distanceKm = calculateDistance(baseLat, baseLon, a.Lat, a.Lon)
-		}
-
-		record := AircraftRecord{
-			Hex:        a.Hex,
-			Flight:     a.Flight,
-			Lat:        a.Lat,
-			Lon:        a.Lon,
-			AltBaro:    a.AltBaro,
-			Timestamp:  timestamp,
-			DistanceKm: distanceKm,
-			BaseLat:    baseLat,
-			BaseLon:    baseLon,
-		}
+		record := newAircraftRecord(a, observer, timestamp)
 
 		// Add index action
 		indexAction := map[string]interface{}{
@@ -225,23 +209,3 @@ func (e *ElasticSearchCataloger) Close() error {
 	// Nothing to close for HTTP client
 	return nil
 }
-
-// calculateDistance calculates the haversine distance in kilometers between two points
-func calculateDistance(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371.0 // Earth radius in kilometers
-
-	// Convert to radians
-	lat1Rad := lat1 * math.Pi / 180
-	lat2Rad := lat2 * math.Pi / 180
-	dLat := (lat2 - lat1) * math.Pi / 180
-	dLon := (lon2 - lon1) * math.Pi / 180
-
-	// Haversine formula
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1Rad)*math.Cos(lat2Rad)*
-			math.Sin(dLon/2)*math.Sin(dLon/2)
-
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-
-	return R * c
-}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/benvon/whats-flying-over-me/internal/geometry"
 	"github.com/benvon/whats-flying-over-me/internal/piaware"
 )
 
@@ -161,7 +162,7 @@ func TestElasticSearchCatalogerCatalogAircraft(t *testing.T) {
 			}
 
 			ctx := context.Background()
-			err = cataloger.CatalogAircraft(ctx, tt.aircraft, tt.baseLat, tt.baseLon)
+			err = cataloger.CatalogAircraft(ctx, tt.aircraft, geometry.Observer{Lat: tt.baseLat, Lon: tt.baseLon})
 			if (err != nil) != tt.wantErr {
 				t.Errorf("CatalogAircraft() error = %v, wantErr %v", err, tt.wantErr)
 			}
@@ -233,50 +234,6 @@ func TestElasticSearchCatalogerClose(t *testing.T) {
 	}
 }
 
-func TestCalculateDistance(t *testing.T) {
-	tests := []struct {
-		name     string
-		lat1     float64
-		lon1     float64
-		lat2     float64
-		lon2     float64
-		expected float64
-	}{
-		{
-			name:     "same point",
-			lat1:     37.6213,
-			lon1:     -122.3790,
-			lat2:     37.6213,
-			lon2:     -122.3790,
-			expected: 0.0,
-		},
-		{
-			name:     "different points",
-			lat1:     37.6213,
-			lon1:     -122.3790,
-			lat2:     37.7213,
-			lon2:     -122.3790,
-			expected: 11.1, // Approximately 11.1 km
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := calculateDistance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
-			if tt.name == "same point" {
-				if result > 0.1 { // Allow small floating point errors
-					t.Errorf("calculateDistance() = %v, expected close to 0", result)
-				}
-			} else {
-				// Allow some tolerance for distance calculations
-				if result < tt.expected-1 || result > tt.expected+1 {
-					t.Errorf("calculateDistance() = %v, expected close to %v", result, tt.expected)
-				}
-			}
-		})
-	}
-}
-
 func TestElasticSearchCatalogerSendBulkRequest(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -4,26 +4,107 @@ import (
 	"context"
 	"time"
 
+	"github.com/benvon/whats-flying-over-me/internal/geometry"
 	"github.com/benvon/whats-flying-over-me/internal/piaware"
 )
 
 // AircraftRecord represents a cataloged aircraft record
 type AircraftRecord struct {
-	Hex        string    `json:"hex"`
-	Flight     string    `json:"flight"`
-	Lat        float64   `json:"lat"`
-	Lon        float64   `json:"lon"`
-	AltBaro    int       `json:"alt_baro"`
-	Timestamp  time.Time `json:"timestamp"`
-	DistanceKm float64   `json:"distance_km"`
-	BaseLat    float64   `json:"base_lat"`
-	BaseLon    float64   `json:"base_lon"`
+	Hex         string    `json:"hex"`
+	Flight      string    `json:"flight"`
+	Lat         float64   `json:"lat"`
+	Lon         float64   `json:"lon"`
+	AltBaro     int       `json:"alt_baro"`
+	OnGround    bool      `json:"on_ground"`
+	AltGeom     int       `json:"alt_geom,omitempty"`
+	GS          float64   `json:"gs,omitempty"`
+	Track       float64   `json:"track,omitempty"`
+	BaroRate    int       `json:"baro_rate,omitempty"`
+	Squawk      string    `json:"squawk,omitempty"`
+	Category    string    `json:"category,omitempty"`
+	Emergency   string    `json:"emergency,omitempty"`
+	RSSI        float64   `json:"rssi,omitempty"`
+	Seen        float64   `json:"seen,omitempty"`
+	SeenPos     float64   `json:"seen_pos,omitempty"`
+	PositionAge float64   `json:"position_age,omitempty"`
+	Receiver    string    `json:"receiver,omitempty"`
+	Timestamp   time.Time `json:"timestamp"`
+	DistanceKm  float64   `json:"distance_km"`
+	BaseLat     float64   `json:"base_lat"`
+	BaseLon     float64   `json:"base_lon"`
+
+	// Aircraft details from readsb, the aircraft database and the ICAO
+	// address.
+	Registration string `json:"registration,omitempty"`
+	TypeCode     string `json:"type_code,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"model,omitempty"`
+	Operator     string `json:"operator,omitempty"`
+	Year         string `json:"year,omitempty"`
+	Country      string `json:"country,omitempty"`
+	Military     bool   `json:"military,omitempty"`
+
+	// Look angles from the observer at the base, for aircraft with a position.
+	AzimuthDeg   float64 `json:"azimuth_deg,omitempty"`
+	ElevationDeg float64 `json:"elevation_deg,omitempty"`
+	SlantRangeKm float64 `json:"slant_range_km,omitempty"`
+	Direction    string  `json:"direction,omitempty"`
+}
+
+// newAircraftRecord builds the catalog record for an aircraft observed at the given time.
+func newAircraftRecord(a piaware.Aircraft, observer geometry.Observer, timestamp time.Time) AircraftRecord {
+	// Calculate distance and look angles if coordinates are available
+	var distanceKm float64
+	var look geometry.Look
+	if a.Lat != 0 && a.Lon != 0 {
+		distanceKm = observer.DistanceKm(a.Lat, a.Lon)
+		look = observer.LookAt(a)
+	}
+
+	return AircraftRecord{
+		Hex:         a.Hex,
+		Flight:      a.Flight,
+		Lat:         a.Lat,
+		Lon:         a.Lon,
+		AltBaro:     a.AltBaro,
+		OnGround:    a.OnGround,
+		AltGeom:     a.AltGeom,
+		GS:          a.GS,
+		Track:       a.Track,
+		BaroRate:    a.BaroRate,
+		Squawk:      a.Squawk,
+		Category:    a.Category,
+		Emergency:   a.Emergency,
+		RSSI:        a.RSSI,
+		Seen:        a.Seen,
+		SeenPos:     a.SeenPos,
+		PositionAge: a.PositionAge,
+		Receiver:    a.Receiver,
+		Timestamp:   timestamp,
+		DistanceKm:  distanceKm,
+		BaseLat:     observer.Lat,
+		BaseLon:     observer.Lon,
+
+		Registration: a.Registration,
+		TypeCode:     a.TypeCode,
+		Manufacturer: a.Manufacturer,
+		Model:        a.Model,
+		Operator:     a.Operator,
+		Year:         a.Year,
+		Country:      a.Country,
+		Military:     a.Military,
+
+		AzimuthDeg:   look.AzimuthDeg,
+		ElevationDeg: look.ElevationDeg,
+		SlantRangeKm: look.SlantRangeKm,
+		Direction:    look.Direction,
+	}
 }
 
 // Cataloger defines the interface for cataloging aircraft data
 type Cataloger interface {
-	// CatalogAircraft catalogs a batch of aircraft data
-	CatalogAircraft(ctx context.Context, aircraft []piaware.Aircraft, baseLat, baseLon float64) error
+	// CatalogAircraft catalogs a batch of aircraft data seen by the observer
+	CatalogAircraft(ctx context.Context, aircraft []piaware.Aircraft, observer geometry.Observer) error
 
 	// HealthCheck performs a health check on the cataloging system
 	HealthCheck(ctx context.Context) error
@@ -35,7 +116,7 @@ type Cataloger interface {
 // NoOpCataloger is a no-operation cataloger that does nothing
 type NoOpCataloger struct{}
 
-func (n *NoOpCataloger) CatalogAircraft(ctx context.Context, aircraft []piaware.Aircraft, baseLat, baseLon float64) error {
+func (n *NoOpCataloger) CatalogAircraft(ctx context.Context, aircraft []piaware.Aircraft, observer geometry.Observer) error {
 	return nil
 }
 
@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/benvon/whats-flying-over-me/internal/geometry"
 	"github.com/benvon/whats-flying-over-me/internal/piaware"
 )
 
@@ -36,7 +37,7 @@ func (m *MockCataloger) SetShouldFail(shouldFail bool, message string) {
 }
 
 // CatalogAircraft catalogs aircraft data (mock implementation)
-func (m *MockCataloger) CatalogAircraft(ctx context.Context, aircraft []piaware.Aircraft, baseLat, baseLon float64) error {
+func (m *MockCataloger) CatalogAircraft(ctx context.Context, aircraft []piaware.Aircraft, observer geometry.Observer) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
@@ -48,24 +49,7 @@ func (m *MockCataloger) CatalogAircraft(ctx context.Context, aircraft []piaware.
 	timestamp := time.Now()
 
 	for _, a := range aircraft {
-		var distanceKm float64
-		if a.Lat != 0 && a.Lon != 0 {
-			distanceKm = calculateDistance(baseLat, baseLon, a.Lat, a.Lon)
-		}
-
-		record := AircraftRecord{
-			Hex:        a.Hex,
-			Flight:     a.Flight,
-			Lat:        a.Lat,
-			Lon:        a.Lon,
-			AltBaro:    a.AltBaro,
-			Timestamp:  timestamp,
-			DistanceKm: distanceKm,
-			BaseLat:    baseLat,
-			BaseLon:    baseLon,
-		}
-
-		m.catalogedAircraft = append(m.catalogedAircraft, record)
+		m.catalogedAircraft = append(m.catalogedAircraft, newAircraftRecord(a, observer, timestamp))
 	}
 
 	return nil
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 
+	"github.com/benvon/whats-flying-over-me/internal/geometry"
 	"github.com/benvon/whats-flying-over-me/internal/piaware"
 )
 
@@ -31,7 +32,7 @@ func TestMockCatalogerCatalogAircraft(t *testing.T) {
 	baseLat, baseLon := 37.6213, -122.3790
 
 	// Test successful cataloging
-	err := mock.CatalogAircraft(ctx, aircraft, baseLat, baseLon)
+	err := mock.CatalogAircraft(ctx, aircraft, geometry.Observer{Lat: baseLat, Lon: baseLon})
 	if err != nil {
 		t.Errorf("CatalogAircraft() failed: %v", err)
 	}
@@ -65,6 +66,61 @@ func TestMockCatalogerCatalogAircraft(t *testing.T) {
 	}
 }
 
+func TestMockCatalogerCarriesExtendedFields(t *testing.T) {
+	mock := NewMockCataloger()
+
+	aircraft := []piaware.Aircraft{
+		{
+			Hex:         "ABC123",
+			Flight:      "TEST123",
+			Lat:         37.6213,
+			Lon:         -122.3790,
+			AltBaro:     5000,
+			AltGeom:     5200,
+			GS:          180.5,
+			Track:       92.1,
+			BaroRate:    -512,
+			Squawk:      "4521",
+			Category:    "A1",
+			Emergency:   "none",
+			RSSI:        -21.3,
+			Seen:        0.5,
+			SeenPos:     1.5,
+			PositionAge: 2.5,
+		},
+		{
+			Hex:      "DEF456",
+			Flight:   "TEST456",
+			Lat:      37.6200,
+			Lon:      -122.3800,
+			OnGround: true,
+		},
+	}
+
+	if err := mock.CatalogAircraft(context.Background(), aircraft, geometry.Observer{Lat: 37.6213, Lon: -122.3790}); err != nil {
+		t.Fatalf("CatalogAircraft() failed: %v", err)
+	}
+
+	cataloged := mock.GetCatalogedAircraft()
+	if len(cataloged) != 2 {
+		t.Fatalf("Expected 2 aircraft records, got %d", len(cataloged))
+	}
+
+	r := cataloged[0]
+	if r.AltGeom != 5200 || r.GS != 180.5 || r.Track != 92.1 || r.BaroRate != -512 {
+		t.Errorf("Unexpected kinematics in record: %+v", r)
+	}
+	if r.Squawk != "4521" || r.Category != "A1" || r.Emergency != "none" {
+		t.Errorf("Unexpected identification in record: %+v", r)
+	}
+	if r.RSSI != -21.3 || r.Seen != 0.5 || r.SeenPos != 1.5 || r.PositionAge != 2.5 {
+		t.Errorf("Unexpected reception data in record: %+v", r)
+	}
+	if !cataloged[1].OnGround {
+		t.Error("Expected second record to be on the ground")
+	}
+}
+
 func TestMockCatalogerHealthCheck(t *testing.T) {
 	mock := NewMockCataloger()
 	ctx := context.Background()
@@ -111,7 +167,7 @@ func TestMockCatalogerSetShouldFail(t *testing.T) {
 	}
 
 	// Test that cataloging fails
-	err := mock.CatalogAircraft(ctx, aircraft, 37.6213, -122.3790)
+	err := mock.CatalogAircraft(ctx, aircraft, geometry.Observer{Lat: 37.6213, Lon: -122.3790})
 	if err == nil {
 		t.Error("Expected CatalogAircraft to fail when shouldFail is true")
 	}
@@ -144,7 +200,7 @@ func TestMockCatalogerReset(t *testing.T) {
 		},
 	}
 
-	if err := mock.CatalogAircraft(ctx, aircraft, 37.6213, -122.3790); err != nil {
+	if err := mock.CatalogAircraft(ctx, aircraft, geometry.Observer{Lat: 37.6213, Lon: -122.3790}); err != nil {
 		t.Errorf("CatalogAircraft() failed: %v", err)
 	}
 	if err := mock.HealthCheck(ctx); err != nil {
@@ -176,3 +232,28 @@ func TestMockCatalogerReset(t *testing.T) {
 		t.Errorf("Expected 0 cataloged aircraft after reset, got %d", len(mock.GetCatalogedAircraft()))
 	}
 }
+
+func TestMockCatalogerRecordsLookAngles(t *testing.T) {
+	mock := NewMockCataloger()
+	observer := geometry.Observer{Lat: 40.0, Lon: -74.0, ElevationFt: 100}
+	aircraft := []piaware.Aircraft{
+		{Hex: "ABC123", Lat: 40.0318, Lon: -73.9586, AltBaro: 35000},
+		{Hex: "DEF456"}, // no position
+	}
+
+	if err := mock.CatalogAircraft(context.Background(), aircraft, observer); err != nil {
+		t.Fatalf("CatalogAircraft() failed: %v", err)
+	}
+
+	cataloged := mock.GetCatalogedAircraft()
+	r := cataloged[0]
+	if r.Direction != "NE" || r.ElevationDeg < 60 || r.AzimuthDeg < 40 || r.AzimuthDeg > 50 || r.SlantRangeKm < 11 {
+		t.Errorf("unexpected look angles in record: %+v", r)
+	}
+	if r.BaseLat != 40.0 || r.BaseLon != -74.0 {
+		t.Errorf("expected observer location as base, got %v,%v", r.BaseLat, r.BaseLon)
+	}
+	if cataloged[1].Direction != "" || cataloged[1].SlantRangeKm != 0 {
+		t.Errorf("expected no look angles without a position, got %+v", cataloged[1])
+	}
+}
@@ -3,11 +3,23 @@ package config
 import (
 	"encoding/json"
 	"flag"
+	"fmt"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/benvon/whats-flying-over-me/internal/capture"
 	"github.com/benvon/whats-flying-over-me/internal/cataloger"
+	"github.com/benvon/whats-flying-over-me/internal/geo"
+	"github.com/benvon/whats-flying-over-me/internal/geometry"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+	"github.com/benvon/whats-flying-over-me/internal/registry"
+	"github.com/benvon/whats-flying-over-me/internal/route"
+	"github.com/benvon/whats-flying-over-me/internal/rules"
+	"github.com/benvon/whats-flying-over-me/internal/tracker"
+	"github.com/benvon/whats-flying-over-me/internal/watchlist"
 )
 
 // Config holds the application configuration.
@@ -15,14 +27,149 @@ type Config struct {
 	ScrapeInterval time.Duration
 	RadiusKm       float64
 	AltitudeMax    int
+	AltitudeMin    int
+	AltitudeSource string
+	QNH            float64
 	BaseLat        float64
 	BaseLon        float64
+	DistanceMode   geo.Mode
 	DataURL        string
+	Receivers      []ReceiverConfig
+	MaxPositionAge time.Duration
+	GeofenceFile   string
+	Zones          []ZoneConfig
+	Rules          []rules.Rule
+	Watchlist      watchlist.Config
+	Fetcher        piaware.HTTPConfig
+	Capture        capture.Config
 	Notifier       NotifierConfig
 	AlertDedupe    AlertDedupeConfig
+	Tracker        tracker.Config
+	Prediction     PredictionConfig
+	Observer       geometry.Config
+	Registry       registry.Config
+	AirlinesFile   string
+	Routes         route.Config
 	Cataloger      cataloger.ElasticSearchConfig
 }
 
+// ReceiverConfig identifies one of several receivers whose feeds are merged.
+// When any receivers are configured they replace DataURL.
+type ReceiverConfig struct {
+	Name string `json:"Name"`
+	URL  string `json:"URL"`
+}
+
+// ZoneConfig is a named circular area monitored with its own thresholds.
+// When any zones are configured they replace the single area around
+// BaseLat/BaseLon. Unset fields fall back to the top-level settings; see
+// Config.MonitorZones.
+type ZoneConfig struct {
+	Name     string
+	BaseLat  float64
+	BaseLon  float64
+	RadiusKm float64
+	// AltitudeMin is the altitude floor in feet; zero means no floor, and
+	// nil uses the top-level AltitudeMin.
+	AltitudeMin *int
+	// AltitudeMax is the altitude ceiling in feet; nil uses the top-level
+	// AltitudeMax.
+	AltitudeMax *int
+	// BlockoutMin is the zone's own alert deduplication blockout.
+	BlockoutMin time.Duration
+}
+
+// Floor returns the zone's altitude floor in feet, or zero when it has none.
+func (z ZoneConfig) Floor() int {
+	if z.AltitudeMin == nil {
+		return 0
+	}
+	return *z.AltitudeMin
+}
+
+// Ceiling returns the zone's altitude ceiling in feet. Zones returned by
+// Config.MonitorZones always have one; a zone without one has a ceiling of
+// zero.
+func (z ZoneConfig) Ceiling() int {
+	if z.AltitudeMax == nil {
+		return 0
+	}
+	return *z.AltitudeMax
+}
+
+// Validate reports settings whose values are not understood, which would
+// otherwise silently fall back to a default.
+func (c Config) Validate() error {
+	if c.AltitudeSource != piaware.AltitudeBaro && c.AltitudeSource != piaware.AltitudeGeom {
+		return fmt.Errorf("unknown altitude source %q, want %q or %q", c.AltitudeSource, piaware.AltitudeBaro, piaware.AltitudeGeom)
+	}
+	if !c.DistanceMode.Valid() {
+		return fmt.Errorf("unknown distance mode %q, want %q or %q", c.DistanceMode, geo.Spherical, geo.Ellipsoidal)
+	}
+	return nil
+}
+
+// AltitudeFilter returns the altitude limits altMin and altMax (ft) applied
+// to the configured altitude source and QNH.
+func (c Config) AltitudeFilter(altMin, altMax int) piaware.AltitudeFilter {
+	return piaware.AltitudeFilter{
+		Source: c.AltitudeSource,
+		QNH:    c.QNH,
+		Min:    altMin,
+		Max:    altMax,
+	}
+}
+
+// BaseObserver returns the observer standing at the base location.
+func (c Config) BaseObserver() geometry.Observer {
+	return geometry.Observer{
+		Lat:         c.BaseLat,
+		Lon:         c.BaseLon,
+		ElevationFt: c.Observer.ElevationFt,
+		Mode:        c.DistanceMode,
+	}
+}
+
+// MonitorZones returns the configured zones with unset fields filled from
+// the top-level settings, or a single unnamed zone around BaseLat/BaseLon
+// when none are configured.
+func (c Config) MonitorZones() []ZoneConfig {
+	if len(c.Zones) == 0 {
+		return []ZoneConfig{{
+			BaseLat:     c.BaseLat,
+			BaseLon:     c.BaseLon,
+			RadiusKm:    c.RadiusKm,
+			AltitudeMin: &c.AltitudeMin,
+			AltitudeMax: &c.AltitudeMax,
+			BlockoutMin: c.AlertDedupe.BlockoutMin,
+		}}
+	}
+
+	zones := make([]ZoneConfig, len(c.Zones))
+	for i, z := range c.Zones {
+		if z.Name == "" {
+			z.Name = fmt.Sprintf("zone-%d", i+1)
+		}
+		if z.BaseLat == 0 && z.BaseLon == 0 {
+			z.BaseLat, z.BaseLon = c.BaseLat, c.BaseLon
+		}
+		if z.RadiusKm == 0 {
+			z.RadiusKm = c.RadiusKm
+		}
+		if z.AltitudeMin == nil {
+			z.AltitudeMin = &c.AltitudeMin
+		}
+		if z.AltitudeMax == nil {
+			z.AltitudeMax = &c.AltitudeMax
+		}
+		if z.BlockoutMin == 0 {
+			z.BlockoutMin = c.AlertDedupe.BlockoutMin
+		}
+		zones[i] = z
+	}
+	return zones
+}
+
 // Duration is a custom type that can unmarshal from string
 type Duration time.Duration
 
@@ -45,13 +192,57 @@ func (d *Duration) UnmarshalJSON(data []byte) error {
 
 // ConfigJSON is used for JSON unmarshaling
 type ConfigJSON struct {
-	ScrapeInterval Duration `json:"ScrapeInterval"`
-	RadiusKm       float64  `json:"RadiusKm"`
-	AltitudeMax    int      `json:"AltitudeMax"`
-	BaseLat        float64  `json:"BaseLat"`
-	BaseLon        float64  `json:"BaseLon"`
-	DataURL        string   `json:"DataURL"`
-	Notifier       struct {
+	ScrapeInterval Duration         `json:"ScrapeInterval"`
+	RadiusKm       float64          `json:"RadiusKm"`
+	AltitudeMax    int              `json:"AltitudeMax"`
+	AltitudeMin    int              `json:"AltitudeMin"`
+	AltitudeSource string           `json:"AltitudeSource"`
+	QNH            float64          `json:"QNH"`
+	BaseLat        float64          `json:"BaseLat"`
+	BaseLon        float64          `json:"BaseLon"`
+	DistanceMode   geo.Mode         `json:"DistanceMode"`
+	DataURL        string           `json:"DataURL"`
+	Receivers      []ReceiverConfig `json:"Receivers"`
+	MaxPositionAge *Duration        `json:"MaxPositionAge"`
+	GeofenceFile   string           `json:"GeofenceFile"`
+	Zones          []struct {
+		Name        string   `json:"Name"`
+		BaseLat     float64  `json:"BaseLat"`
+		BaseLon     float64  `json:"BaseLon"`
+		RadiusKm    float64  `json:"RadiusKm"`
+		AltitudeMin *int     `json:"AltitudeMin"`
+		AltitudeMax *int     `json:"AltitudeMax"`
+		BlockoutMin Duration `json:"BlockoutMin"`
+	} `json:"Zones"`
+	Rules []struct {
+		Name        string   `json:"Name"`
+		When        string   `json:"When"`
+		Severity    string   `json:"Severity"`
+		Notifiers   []string `json:"Notifiers"`
+		BlockoutMin Duration `json:"BlockoutMin"`
+	} `json:"Rules"`
+	Watchlist struct {
+		Hexes         []string `json:"Hexes"`
+		Registrations []string `json:"Registrations"`
+		Callsigns     []string `json:"Callsigns"`
+		BlockoutMin   Duration `json:"BlockoutMin"`
+	} `json:"Watchlist"`
+	Fetcher struct {
+		Timeout     Duration          `json:"Timeout"`
+		Username    string            `json:"Username"`
+		Password    string            `json:"Password"`
+		BearerToken string            `json:"BearerToken"`
+		Headers     map[string]string `json:"Headers"`
+		CAFile      string            `json:"CAFile"`
+		CertFile    string            `json:"CertFile"`
+		KeyFile     string            `json:"KeyFile"`
+	} `json:"Fetcher"`
+	Capture struct {
+		Dir            string    `json:"Dir"`
+		MaxSizeMB      *int      `json:"MaxSizeMB"`
+		RotateInterval *Duration `json:"RotateInterval"`
+	} `json:"Capture"`
+	Notifier struct {
 		Webhook struct {
 			Enabled bool     `json:"Enabled"`
 			URL     string   `json:"URL"`
@@ -70,6 +261,27 @@ type ConfigJSON struct {
 		Enabled     bool     `json:"Enabled"`
 		BlockoutMin Duration `json:"BlockoutMin"`
 	} `json:"AlertDedupe"`
+	Tracker struct {
+		Enabled   bool     `json:"Enabled"`
+		ExitAfter Duration `json:"ExitAfter"`
+	} `json:"Tracker"`
+	Prediction struct {
+		Enabled   bool     `json:"Enabled"`
+		Lookahead Duration `json:"Lookahead"`
+	} `json:"Prediction"`
+	Observer struct {
+		ElevationFt     float64 `json:"ElevationFt"`
+		MinElevationDeg float64 `json:"MinElevationDeg"`
+	} `json:"Observer"`
+	Registry struct {
+		File           string   `json:"File"`
+		ReloadInterval Duration `json:"ReloadInterval"`
+	} `json:"Registry"`
+	AirlinesFile string `json:"AirlinesFile"`
+	Routes       struct {
+		File         string `json:"File"`
+		AirportsFile string `json:"AirportsFile"`
+	} `json:"Routes"`
 	Cataloger struct {
 		Enabled    bool     `json:"Enabled"`
 		URL        string   `json:"URL"`
@@ -92,9 +304,71 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 	c.ScrapeInterval = time.Duration(configJSON.ScrapeInterval)
 	c.RadiusKm = configJSON.RadiusKm
 	c.AltitudeMax = configJSON.AltitudeMax
+	c.AltitudeMin = configJSON.AltitudeMin
+	if configJSON.AltitudeSource != "" {
+		c.AltitudeSource = configJSON.AltitudeSource
+	}
+	c.QNH = configJSON.QNH
 	c.BaseLat = configJSON.BaseLat
 	c.BaseLon = configJSON.BaseLon
+	if configJSON.DistanceMode != "" {
+		c.DistanceMode = configJSON.DistanceMode
+	}
 	c.DataURL = configJSON.DataURL
+	c.Receivers = configJSON.Receivers
+	if configJSON.MaxPositionAge != nil {
+		c.MaxPositionAge = time.Duration(*configJSON.MaxPositionAge)
+	}
+	c.GeofenceFile = configJSON.GeofenceFile
+	c.Zones = nil
+	for _, z := range configJSON.Zones {
+		c.Zones = append(c.Zones, ZoneConfig{
+			Name:        z.Name,
+			BaseLat:     z.BaseLat,
+			BaseLon:     z.BaseLon,
+			RadiusKm:    z.RadiusKm,
+			AltitudeMin: z.AltitudeMin,
+			AltitudeMax: z.AltitudeMax,
+			BlockoutMin: time.Duration(z.BlockoutMin),
+		})
+	}
+	c.Rules = nil
+	for _, r := range configJSON.Rules {
+		c.Rules = append(c.Rules, rules.Rule{
+			Name:        r.Name,
+			When:        r.When,
+			Severity:    r.Severity,
+			Notifiers:   r.Notifiers,
+			BlockoutMin: time.Duration(r.BlockoutMin),
+		})
+	}
+
+	// Copy Watchlist fields
+	c.Watchlist.Hexes = configJSON.Watchlist.Hexes
+	c.Watchlist.Registrations = configJSON.Watchlist.Registrations
+	c.Watchlist.Callsigns = configJSON.Watchlist.Callsigns
+	c.Watchlist.BlockoutMin = time.Duration(configJSON.Watchlist.BlockoutMin)
+
+	// Copy Fetcher fields
+	if configJSON.Fetcher.Timeout != 0 {
+		c.Fetcher.Timeout = time.Duration(configJSON.Fetcher.Timeout)
+	}
+	c.Fetcher.Username = configJSON.Fetcher.Username
+	c.Fetcher.Password = configJSON.Fetcher.Password
+	c.Fetcher.BearerToken = configJSON.Fetcher.BearerToken
+	c.Fetcher.Headers = configJSON.Fetcher.Headers
+	c.Fetcher.CAFile = configJSON.Fetcher.CAFile
+	c.Fetcher.CertFile = configJSON.Fetcher.CertFile
+	c.Fetcher.KeyFile = configJSON.Fetcher.KeyFile
+
+	// Copy Capture fields
+	c.Capture.Dir = configJSON.Capture.Dir
+	if configJSON.Capture.MaxSizeMB != nil {
+		c.Capture.MaxSizeMB = *configJSON.Capture.MaxSizeMB
+	}
+	if configJSON.Capture.RotateInterval != nil {
+		c.Capture.RotateInterval = time.Duration(*configJSON.Capture.RotateInterval)
+	}
 
 	// Copy Notifier fields
 	c.Notifier.Webhook.Enabled = configJSON.Notifier.Webhook.Enabled
@@ -111,6 +385,33 @@ func (c *Config) UnmarshalJSON(data []byte) error {
 	c.AlertDedupe.Enabled = configJSON.AlertDedupe.Enabled
 	c.AlertDedupe.BlockoutMin = time.Duration(configJSON.AlertDedupe.BlockoutMin)
 
+	// Copy Tracker fields
+	c.Tracker.Enabled = configJSON.Tracker.Enabled
+	if configJSON.Tracker.ExitAfter != 0 {
+		c.Tracker.ExitAfter = time.Duration(configJSON.Tracker.ExitAfter)
+	}
+
+	// Copy Prediction fields
+	c.Prediction.Enabled = configJSON.Prediction.Enabled
+	if configJSON.Prediction.Lookahead != 0 {
+		c.Prediction.Lookahead = time.Duration(configJSON.Prediction.Lookahead)
+	}
+
+	// Copy Observer fields
+	c.Observer.ElevationFt = configJSON.Observer.ElevationFt
+	c.Observer.MinElevationDeg = configJSON.Observer.MinElevationDeg
+
+	// Copy Registry fields
+	c.Registry.File = configJSON.Registry.File
+	if configJSON.Registry.ReloadInterval != 0 {
+		c.Registry.ReloadInterval = time.Duration(configJSON.Registry.ReloadInterval)
+	}
+	c.AirlinesFile = configJSON.AirlinesFile
+
+	// Copy Routes fields
+	c.Routes.File = configJSON.Routes.File
+	c.Routes.AirportsFile = configJSON.Routes.AirportsFile
+
 	// Copy Cataloger fields
 	c.Cataloger.Enabled = configJSON.Cataloger.Enabled
 	c.Cataloger.URL = configJSON.Cataloger.URL
@@ -146,6 +447,13 @@ type RabbitMQConfig struct {
 	Timeout    time.Duration
 }
 
+// PredictionConfig holds closest-point-of-approach prediction settings.
+type PredictionConfig struct {
+	Enabled bool
+	// Lookahead is how far ahead an aircraft's path is projected.
+	Lookahead time.Duration
+}
+
 // AlertDedupeConfig holds alert deduplication settings.
 type AlertDedupeConfig struct {
 	Enabled     bool
@@ -160,6 +468,30 @@ const (
 	envBaseLat    = "WFO_BASE_LAT"
 	envBaseLon    = "WFO_BASE_LON"
 	envDataURL    = "WFO_DATA_URL"
+	envReceivers  = "WFO_RECEIVERS"
+
+	envAltitudeMin    = "WFO_ALTITUDE_MIN"
+	envAltitudeSource = "WFO_ALTITUDE_SOURCE"
+	envQNH            = "WFO_QNH"
+	envDistanceMode   = "WFO_DISTANCE_MODE"
+	envMaxPositionAge = "WFO_MAX_POSITION_AGE"
+	envGeofenceFile   = "WFO_GEOFENCE_FILE"
+	envZones          = "WFO_ZONES"
+
+	// Data fetcher settings
+	envFetchTimeout     = "WFO_FETCH_TIMEOUT"
+	envFetchUsername    = "WFO_FETCH_USERNAME"
+	envFetchPassword    = "WFO_FETCH_PASSWORD" // #nosec G101 -- this is an environment variable name
+	envFetchBearerToken = "WFO_FETCH_BEARER_TOKEN"
+	envFetchHeaders     = "WFO_FETCH_HEADERS"
+	envFetchCAFile      = "WFO_FETCH_CA_FILE"
+	envFetchCertFile    = "WFO_FETCH_CERT_FILE"
+	envFetchKeyFile     = "WFO_FETCH_KEY_FILE"
+
+	// Capture settings
+	envCaptureDir            = "WFO_CAPTURE_DIR"
+	envCaptureMaxSizeMB      = "WFO_CAPTURE_MAX_SIZE_MB"
+	envCaptureRotateInterval = "WFO_CAPTURE_ROTATE_INTERVAL"
 
 	// Webhook settings
 	envWebhookEnabled = "WFO_WEBHOOK_ENABLED"
@@ -177,6 +509,33 @@ const (
 	envAlertDedupeEnabled = "WFO_ALERT_DEDUPE_ENABLED"
 	envAlertBlockoutMin   = "WFO_ALERT_BLOCKOUT_MIN"
 
+	// Tracker settings
+	envTrackerEnabled   = "WFO_TRACKER_ENABLED"
+	envTrackerExitAfter = "WFO_TRACKER_EXIT_AFTER"
+
+	// Prediction settings
+	envPredictionEnabled   = "WFO_PREDICTION_ENABLED"
+	envPredictionLookahead = "WFO_PREDICTION_LOOKAHEAD"
+
+	// Observer settings
+	envObserverElevationFt = "WFO_OBSERVER_ELEVATION_FT"
+	envMinElevationDeg     = "WFO_MIN_ELEVATION_DEG"
+
+	// Aircraft registry settings
+	envRegistryFile           = "WFO_REGISTRY_FILE"
+	envRegistryReloadInterval = "WFO_REGISTRY_RELOAD_INTERVAL"
+	envAirlinesFile           = "WFO_AIRLINES_FILE"
+
+	// Route lookup settings
+	envRoutesFile   = "WFO_ROUTES_FILE"
+	envAirportsFile = "WFO_AIRPORTS_FILE"
+
+	// Watchlist settings
+	envWatchlistHexes         = "WFO_WATCHLIST_HEXES"
+	envWatchlistRegistrations = "WFO_WATCHLIST_REGISTRATIONS"
+	envWatchlistCallsigns     = "WFO_WATCHLIST_CALLSIGNS"
+	envWatchlistBlockout      = "WFO_WATCHLIST_BLOCKOUT"
+
 	// Cataloging settings
 	envCatalogerEnabled    = "WFO_CATALOGER_ENABLED"
 	envCatalogerURL        = "WFO_CATALOGER_URL"
@@ -200,7 +559,17 @@ func LoadWithFlagSet(flagSet *flag.FlagSet) Config {
 		ScrapeInterval: time.Minute,
 		RadiusKm:       25.0,
 		AltitudeMax:    10000,
+		AltitudeSource: piaware.AltitudeBaro,
+		DistanceMode:   geo.Spherical,
 		DataURL:        "http://localhost:8080/data/aircraft.json",
+		MaxPositionAge: 30 * time.Second,
+		Fetcher: piaware.HTTPConfig{
+			Timeout: 10 * time.Second,
+		},
+		Capture: capture.Config{
+			MaxSizeMB:      100,
+			RotateInterval: time.Hour,
+		},
 		Notifier: NotifierConfig{
 			Console: true, // Default to console logging only
 		},
@@ -208,6 +577,15 @@ func LoadWithFlagSet(flagSet *flag.FlagSet) Config {
 			Enabled:     true,
 			BlockoutMin: 15 * time.Minute,
 		},
+		Tracker: tracker.Config{
+			ExitAfter: time.Minute,
+		},
+		Prediction: PredictionConfig{
+			Lookahead: 5 * time.Minute,
+		},
+		Registry: registry.Config{
+			ReloadInterval: time.Minute,
+		},
 		Cataloger: cataloger.ElasticSearchConfig{
 			Enabled:    false, // Default to disabled
 			Index:      "aircraft",
@@ -246,7 +624,17 @@ func LoadWithFlagSetAndArgs(flagSet *flag.FlagSet, args []string) Config {
 		ScrapeInterval: time.Minute,
 		RadiusKm:       25.0,
 		AltitudeMax:    10000,
+		AltitudeSource: piaware.AltitudeBaro,
+		DistanceMode:   geo.Spherical,
 		DataURL:        "http://localhost:8080/data/aircraft.json",
+		MaxPositionAge: 30 * time.Second,
+		Fetcher: piaware.HTTPConfig{
+			Timeout: 10 * time.Second,
+		},
+		Capture: capture.Config{
+			MaxSizeMB:      100,
+			RotateInterval: time.Hour,
+		},
 		Notifier: NotifierConfig{
 			Console: true, // Default to console logging only
 		},
@@ -254,6 +642,15 @@ func LoadWithFlagSetAndArgs(flagSet *flag.FlagSet, args []string) Config {
 			Enabled:     true,
 			BlockoutMin: 15 * time.Minute,
 		},
+		Tracker: tracker.Config{
+			ExitAfter: time.Minute,
+		},
+		Prediction: PredictionConfig{
+			Lookahead: 5 * time.Minute,
+		},
+		Registry: registry.Config{
+			ReloadInterval: time.Minute,
+		},
 		Cataloger: cataloger.ElasticSearchConfig{
 			Enabled:    false, // Default to disabled
 			Index:      "aircraft",
@@ -291,6 +688,29 @@ type commandLineFlags struct {
 	lat        *float64
 	lon        *float64
 	dataURL    *string
+	receivers  *string
+
+	altitudeMin    *int
+	altitudeSource *string
+	qnh            *float64
+	distanceMode   *string
+	maxPositionAge *time.Duration
+	geofenceFile   *string
+	zones          *string
+
+	// Data fetcher flags
+	fetchTimeout     *time.Duration
+	fetchUsername    *string
+	fetchPassword    *string
+	fetchBearerToken *string
+	fetchCAFile      *string
+	fetchCertFile    *string
+	fetchKeyFile     *string
+
+	// Capture flags
+	captureDir            *string
+	captureMaxSizeMB      *int
+	captureRotateInterval *time.Duration
 
 	// Webhook flags
 	webhookEnabled *bool
@@ -308,6 +728,33 @@ type commandLineFlags struct {
 	alertDedupeEnabled *bool
 	alertBlockoutMin   *time.Duration
 
+	// Tracker flags
+	trackerEnabled   *bool
+	trackerExitAfter *time.Duration
+
+	// Prediction flags
+	predictionEnabled   *bool
+	predictionLookahead *time.Duration
+
+	// Observer flags
+	observerElevationFt *float64
+	minElevationDeg     *float64
+
+	// Aircraft registry flags
+	registryFile           *string
+	registryReloadInterval *time.Duration
+	airlinesFile           *string
+
+	// Route lookup flags
+	routesFile   *string
+	airportsFile *string
+
+	// Watchlist flags
+	watchlistHexes         *string
+	watchlistRegistrations *string
+	watchlistCallsigns     *string
+	watchlistBlockout      *time.Duration
+
 	// Cataloging flags
 	catalogerEnabled    *bool
 	catalogerURL        *string
@@ -327,6 +774,29 @@ func defineFlagsWithFlagSet(flagSet *flag.FlagSet) commandLineFlags {
 		lat:        flagSet.Float64("lat", 0, "base latitude"),
 		lon:        flagSet.Float64("lon", 0, "base longitude"),
 		dataURL:    flagSet.String("url", "", "piaware data URL"),
+		receivers:  flagSet.String("receivers", "", "comma-separated receivers to merge, as name=url or url"),
+
+		altitudeMin:    flagSet.Int("altitude-min", 0, "altitude floor in feet (0 disables)"),
+		altitudeSource: flagSet.String("altitude-source", "", "altitude compared against the limits: baro or geom"),
+		qnh:            flagSet.Float64("qnh", 0, "local QNH in hPa used to correct barometric altitudes (0 disables)"),
+		distanceMode:   flagSet.String("distance-mode", "", "distance calculation: spherical or ellipsoidal"),
+		maxPositionAge: flagSet.Duration("max-position-age", 0, "maximum position age before an aircraft is ignored (0 disables)"),
+		geofenceFile:   flagSet.String("geofence-file", "", "GeoJSON file of zones to monitor instead of the radius"),
+		zones:          flagSet.String("zones", "", "semicolon-separated zones, as name=lat,lon,radiusKm[,altMin,altMax[,blockout]]"),
+
+		// Data fetcher flags
+		fetchTimeout:     flagSet.Duration("fetch-timeout", 0, "aircraft data fetch timeout"),
+		fetchUsername:    flagSet.String("fetch-username", "", "aircraft data basic auth username"),
+		fetchPassword:    flagSet.String("fetch-password", "", "aircraft data basic auth password"),
+		fetchBearerToken: flagSet.String("fetch-bearer-token", "", "aircraft data bearer token"),
+		fetchCAFile:      flagSet.String("fetch-ca-file", "", "CA certificate file for the aircraft data URL"),
+		fetchCertFile:    flagSet.String("fetch-cert-file", "", "client certificate file for the aircraft data URL"),
+		fetchKeyFile:     flagSet.String("fetch-key-file", "", "client key file for the aircraft data URL"),
+
+		// Capture flags
+		captureDir:            flagSet.String("capture-dir", "", "directory to record fetched snapshots to"),
+		captureMaxSizeMB:      flagSet.Int("capture-max-size-mb", 0, "capture file size in MB before rotating"),
+		captureRotateInterval: flagSet.Duration("capture-rotate-interval", 0, "capture file age before rotating"),
 
 		// Webhook flags
 		webhookEnabled: flagSet.Bool("webhook-enabled", false, "enable webhook notifications"),
@@ -344,6 +814,33 @@ func defineFlagsWithFlagSet(flagSet *flag.FlagSet) commandLineFlags {
 		alertDedupeEnabled: flagSet.Bool("alert-dedupe-enabled", true, "enable alert deduplication"),
 		alertBlockoutMin:   flagSet.Duration("alert-blockout-min", 0, "alert blockout period"),
 
+		// Tracker flags
+		trackerEnabled:   flagSet.Bool("tracker-enabled", false, "alert on aircraft entering, passing closest and leaving the area"),
+		trackerExitAfter: flagSet.Duration("tracker-exit-after", 0, "how long an aircraft must be out of the area before its track closes"),
+
+		// Prediction flags
+		predictionEnabled:   flagSet.Bool("prediction-enabled", false, "warn about aircraft predicted to pass within the radius"),
+		predictionLookahead: flagSet.Duration("prediction-lookahead", 0, "how far ahead to project aircraft paths"),
+
+		// Observer flags
+		observerElevationFt: flagSet.Float64("observer-elevation-ft", 0, "observer ground elevation in feet above mean sea level"),
+		minElevationDeg:     flagSet.Float64("min-elevation-deg", 0, "alert on aircraft at least this many degrees above the horizon (0 disables)"),
+
+		// Aircraft registry flags
+		registryFile:           flagSet.String("registry-file", "", "aircraft database (tar1090-db or BaseStation CSV) used to enrich alerts"),
+		registryReloadInterval: flagSet.Duration("registry-reload-interval", 0, "how often the aircraft database is checked for changes"),
+		airlinesFile:           flagSet.String("airlines-file", "", "CSV of airline designators added to the bundled table used to decode callsigns"),
+
+		// Route lookup flags
+		routesFile:   flagSet.String("routes-file", "", "routes CSV mapping callsigns to origin and destination airports"),
+		airportsFile: flagSet.String("airports-file", "", "airports CSV naming the airports in the routes file"),
+
+		// Watchlist flags
+		watchlistHexes:         flagSet.String("watchlist-hexes", "", "comma-separated ICAO hexes to alert on anywhere in the feed"),
+		watchlistRegistrations: flagSet.String("watchlist-registrations", "", "comma-separated registrations to alert on anywhere in the feed"),
+		watchlistCallsigns:     flagSet.String("watchlist-callsigns", "", "comma-separated callsign regular expressions to alert on anywhere in the feed"),
+		watchlistBlockout:      flagSet.Duration("watchlist-blockout", 0, "how long a watched aircraft is not alerted again (0 uses -blockout)"),
+
 		// Cataloging flags
 		catalogerEnabled:    flagSet.Bool("cataloger-enabled", false, "enable aircraft cataloging"),
 		catalogerURL:        flagSet.String("cataloger-url", "", "ElasticSearch URL"),
@@ -381,9 +878,17 @@ func loadFromConfigFile(cfg *Config, configPath *string) {
 
 func loadFromEnvironment(cfg *Config) {
 	loadBasicConfigFromEnv(cfg)
+	loadFetcherConfigFromEnv(cfg)
+	loadCaptureConfigFromEnv(cfg)
 	loadWebhookConfigFromEnv(cfg)
 	loadRabbitMQConfigFromEnv(cfg)
 	loadAlertDedupeConfigFromEnv(cfg)
+	loadTrackerConfigFromEnv(cfg)
+	loadPredictionConfigFromEnv(cfg)
+	loadObserverConfigFromEnv(cfg)
+	loadRegistryConfigFromEnv(cfg)
+	loadRoutesConfigFromEnv(cfg)
+	loadWatchlistConfigFromEnv(cfg)
 	loadCatalogerConfigFromEnv(cfg)
 }
 
@@ -391,9 +896,48 @@ func loadBasicConfigFromEnv(cfg *Config) {
 	setDurationFromEnv(envInterval, func(d time.Duration) { cfg.ScrapeInterval = d })
 	setFloatFromEnv(envRadius, func(f float64) { cfg.RadiusKm = f })
 	setIntFromEnv(envAltitude, func(i int) { cfg.AltitudeMax = i })
+	setIntFromEnv(envAltitudeMin, func(i int) { cfg.AltitudeMin = i })
+	setStringFromEnv(envAltitudeSource, func(s string) { cfg.AltitudeSource = s })
+	setFloatFromEnv(envQNH, func(f float64) { cfg.QNH = f })
+	setStringFromEnv(envDistanceMode, func(s string) { cfg.DistanceMode = geo.Mode(s) })
 	setFloatFromEnv(envBaseLat, func(f float64) { cfg.BaseLat = f })
 	setFloatFromEnv(envBaseLon, func(f float64) { cfg.BaseLon = f })
 	setStringFromEnv(envDataURL, func(s string) { cfg.DataURL = s })
+	setStringFromEnv(envReceivers, func(s string) { cfg.Receivers = ParseReceivers(s) })
+	setDurationFromEnv(envMaxPositionAge, func(d time.Duration) { cfg.MaxPositionAge = d })
+	setStringFromEnv(envGeofenceFile, func(s string) { cfg.GeofenceFile = s })
+	setStringFromEnv(envZones, func(s string) { cfg.Zones = ParseZones(s) })
+}
+
+func loadFetcherConfigFromEnv(cfg *Config) {
+	setDurationFromEnv(envFetchTimeout, func(d time.Duration) { cfg.Fetcher.Timeout = d })
+	setStringFromEnv(envFetchUsername, func(s string) { cfg.Fetcher.Username = s })
+	setStringFromEnv(envFetchPassword, func(s string) { cfg.Fetcher.Password = s })
+	setStringFromEnv(envFetchBearerToken, func(s string) { cfg.Fetcher.BearerToken = s })
+	setStringFromEnv(envFetchHeaders, func(s string) { cfg.Fetcher.Headers = ParseHeaders(s) })
+	setStringFromEnv(envFetchCAFile, func(s string) { cfg.Fetcher.CAFile = s })
+	setStringFromEnv(envFetchCertFile, func(s string) { cfg.Fetcher.CertFile = s })
+	setStringFromEnv(envFetchKeyFile, func(s string) { cfg.Fetcher.KeyFile = s })
+}
+
+func loadCaptureConfigFromEnv(cfg *Config) {
+	setStringFromEnv(envCaptureDir, func(s string) { cfg.Capture.Dir = s })
+	setIntFromEnv(envCaptureMaxSizeMB, func(i int) { cfg.Capture.MaxSizeMB = i })
+	setDurationFromEnv(envCaptureRotateInterval, func(d time.Duration) { cfg.Capture.RotateInterval = d })
+}
+
+// ParseHeaders parses a comma-separated list of "Name=Value" headers.
+func ParseHeaders(s string) map[string]string {
+	headers := make(map[string]string)
+	for _, entry := range strings.Split(s, ",") {
+		name, value, found := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			continue
+		}
+		headers[name] = strings.TrimSpace(value)
+	}
+	return headers
 }
 
 func setDurationFromEnv(key string, setter func(time.Duration)) {
@@ -420,6 +964,14 @@ func setIntFromEnv(key string, setter func(int)) {
 	}
 }
 
+func setBoolFromEnv(key string, setter func(bool)) {
+	if v, ok := os.LookupEnv(key); ok {
+		if b, err := strconv.ParseBool(v); err == nil {
+			setter(b)
+		}
+	}
+}
+
 func setStringFromEnv(key string, setter func(string)) {
 	if v, ok := os.LookupEnv(key); ok {
 		setter(v)
@@ -477,6 +1029,50 @@ func loadAlertDedupeConfigFromEnv(cfg *Config) {
 	}
 }
 
+func loadTrackerConfigFromEnv(cfg *Config) {
+	setBoolFromEnv(envTrackerEnabled, func(b bool) { cfg.Tracker.Enabled = b })
+	setDurationFromEnv(envTrackerExitAfter, func(d time.Duration) { cfg.Tracker.ExitAfter = d })
+}
+
+func loadPredictionConfigFromEnv(cfg *Config) {
+	setBoolFromEnv(envPredictionEnabled, func(b bool) { cfg.Prediction.Enabled = b })
+	setDurationFromEnv(envPredictionLookahead, func(d time.Duration) { cfg.Prediction.Lookahead = d })
+}
+
+func loadObserverConfigFromEnv(cfg *Config) {
+	setFloatFromEnv(envObserverElevationFt, func(f float64) { cfg.Observer.ElevationFt = f })
+	setFloatFromEnv(envMinElevationDeg, func(f float64) { cfg.Observer.MinElevationDeg = f })
+}
+
+func loadRegistryConfigFromEnv(cfg *Config) {
+	setStringFromEnv(envRegistryFile, func(s string) { cfg.Registry.File = s })
+	setDurationFromEnv(envRegistryReloadInterval, func(d time.Duration) { cfg.Registry.ReloadInterval = d })
+	setStringFromEnv(envAirlinesFile, func(s string) { cfg.AirlinesFile = s })
+}
+
+func loadRoutesConfigFromEnv(cfg *Config) {
+	setStringFromEnv(envRoutesFile, func(s string) { cfg.Routes.File = s })
+	setStringFromEnv(envAirportsFile, func(s string) { cfg.Routes.AirportsFile = s })
+}
+
+func loadWatchlistConfigFromEnv(cfg *Config) {
+	setStringFromEnv(envWatchlistHexes, func(s string) { cfg.Watchlist.Hexes = ParseList(s) })
+	setStringFromEnv(envWatchlistRegistrations, func(s string) { cfg.Watchlist.Registrations = ParseList(s) })
+	setStringFromEnv(envWatchlistCallsigns, func(s string) { cfg.Watchlist.Callsigns = ParseList(s) })
+	setDurationFromEnv(envWatchlistBlockout, func(d time.Duration) { cfg.Watchlist.BlockoutMin = d })
+}
+
+// ParseList parses a comma-separated list, dropping empty entries.
+func ParseList(s string) []string {
+	var list []string
+	for _, entry := range strings.Split(s, ",") {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
 func loadCatalogerConfigFromEnv(cfg *Config) {
 	if v, ok := os.LookupEnv(envCatalogerEnabled); ok {
 		if b, err := strconv.ParseBool(v); err == nil {
@@ -509,9 +1105,17 @@ func loadCatalogerConfigFromEnv(cfg *Config) {
 
 func applyCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
 	applyBasicCommandLineOverrides(cfg, flags, setFlags)
+	applyFetcherCommandLineOverrides(cfg, flags, setFlags)
+	applyCaptureCommandLineOverrides(cfg, flags, setFlags)
 	applyWebhookCommandLineOverrides(cfg, flags, setFlags)
 	applyRabbitMQCommandLineOverrides(cfg, flags, setFlags)
 	applyAlertDedupeCommandLineOverrides(cfg, flags, setFlags)
+	applyTrackerCommandLineOverrides(cfg, flags, setFlags)
+	applyPredictionCommandLineOverrides(cfg, flags, setFlags)
+	applyObserverCommandLineOverrides(cfg, flags, setFlags)
+	applyRegistryCommandLineOverrides(cfg, flags, setFlags)
+	applyRoutesCommandLineOverrides(cfg, flags, setFlags)
+	applyWatchlistCommandLineOverrides(cfg, flags, setFlags)
 	applyCatalogerCommandLineOverrides(cfg, flags, setFlags)
 }
 
@@ -525,6 +1129,18 @@ func applyBasicCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlag
 	if setFlags["altitude"] {
 		cfg.AltitudeMax = *flags.altitude
 	}
+	if setFlags["altitude-min"] {
+		cfg.AltitudeMin = *flags.altitudeMin
+	}
+	if setFlags["altitude-source"] {
+		cfg.AltitudeSource = *flags.altitudeSource
+	}
+	if setFlags["qnh"] {
+		cfg.QNH = *flags.qnh
+	}
+	if setFlags["distance-mode"] {
+		cfg.DistanceMode = geo.Mode(*flags.distanceMode)
+	}
 	if setFlags["lat"] {
 		cfg.BaseLat = *flags.lat
 	}
@@ -534,6 +1150,140 @@ func applyBasicCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlag
 	if setFlags["url"] {
 		cfg.DataURL = *flags.dataURL
 	}
+	if setFlags["receivers"] {
+		cfg.Receivers = ParseReceivers(*flags.receivers)
+	}
+	if setFlags["max-position-age"] {
+		cfg.MaxPositionAge = *flags.maxPositionAge
+	}
+	if setFlags["geofence-file"] {
+		cfg.GeofenceFile = *flags.geofenceFile
+	}
+	if setFlags["zones"] {
+		cfg.Zones = ParseZones(*flags.zones)
+	}
+}
+
+// ParseReceivers parses a comma-separated receiver list. Each entry is either
+// "name=url" or a bare URL, in which case the URL host is used as the name.
+// Names need not be unique.
+func ParseReceivers(s string) []ReceiverConfig {
+	var receivers []ReceiverConfig
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, rawURL, found := strings.Cut(entry, "=")
+		if !found || strings.Contains(name, "://") {
+			rawURL = entry
+			name = ""
+		}
+		if name == "" {
+			name = rawURL
+			if u, err := url.Parse(rawURL); err == nil && u.Host != "" {
+				name = u.Host
+			}
+		}
+
+		receivers = append(receivers, ReceiverConfig{Name: strings.TrimSpace(name), URL: strings.TrimSpace(rawURL)})
+	}
+	return receivers
+}
+
+// ParseZones parses a semicolon-separated zone list. Each entry is
+// "name=lat,lon,radiusKm", optionally followed by ",altMin,altMax" and then
+// ",blockout". Entries that do not parse are skipped.
+func ParseZones(s string) []ZoneConfig {
+	var zones []ZoneConfig
+	for _, entry := range strings.Split(s, ";") {
+		name, spec, found := strings.Cut(entry, "=")
+		if !found {
+			continue
+		}
+		zone, ok := parseZoneSpec(strings.Split(spec, ","))
+		if !ok {
+			continue
+		}
+		zone.Name = strings.TrimSpace(name)
+		zones = append(zones, zone)
+	}
+	return zones
+}
+
+func parseZoneSpec(fields []string) (ZoneConfig, bool) {
+	if len(fields) != 3 && len(fields) != 5 && len(fields) != 6 {
+		return ZoneConfig{}, false
+	}
+	for i := range fields {
+		fields[i] = strings.TrimSpace(fields[i])
+	}
+
+	var zone ZoneConfig
+	var err error
+	if zone.BaseLat, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return ZoneConfig{}, false
+	}
+	if zone.BaseLon, err = strconv.ParseFloat(fields[1], 64); err != nil {
+		return ZoneConfig{}, false
+	}
+	if zone.RadiusKm, err = strconv.ParseFloat(fields[2], 64); err != nil {
+		return ZoneConfig{}, false
+	}
+	if len(fields) >= 5 {
+		altMin, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return ZoneConfig{}, false
+		}
+		altMax, err := strconv.Atoi(fields[4])
+		if err != nil {
+			return ZoneConfig{}, false
+		}
+		zone.AltitudeMin, zone.AltitudeMax = &altMin, &altMax
+	}
+	if len(fields) == 6 {
+		if zone.BlockoutMin, err = time.ParseDuration(fields[5]); err != nil {
+			return ZoneConfig{}, false
+		}
+	}
+	return zone, true
+}
+
+func applyFetcherCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
+	if setFlags["fetch-timeout"] {
+		cfg.Fetcher.Timeout = *flags.fetchTimeout
+	}
+	if setFlags["fetch-username"] {
+		cfg.Fetcher.Username = *flags.fetchUsername
+	}
+	if setFlags["fetch-password"] {
+		cfg.Fetcher.Password = *flags.fetchPassword
+	}
+	if setFlags["fetch-bearer-token"] {
+		cfg.Fetcher.BearerToken = *flags.fetchBearerToken
+	}
+	if setFlags["fetch-ca-file"] {
+		cfg.Fetcher.CAFile = *flags.fetchCAFile
+	}
+	if setFlags["fetch-cert-file"] {
+		cfg.Fetcher.CertFile = *flags.fetchCertFile
+	}
+	if setFlags["fetch-key-file"] {
+		cfg.Fetcher.KeyFile = *flags.fetchKeyFile
+	}
+}
+
+func applyCaptureCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
+	if setFlags["capture-dir"] {
+		cfg.Capture.Dir = *flags.captureDir
+	}
+	if setFlags["capture-max-size-mb"] {
+		cfg.Capture.MaxSizeMB = *flags.captureMaxSizeMB
+	}
+	if setFlags["capture-rotate-interval"] {
+		cfg.Capture.RotateInterval = *flags.captureRotateInterval
+	}
 }
 
 func applyWebhookCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
@@ -575,6 +1325,69 @@ func applyAlertDedupeCommandLineOverrides(cfg *Config, flags commandLineFlags, s
 	}
 }
 
+func applyTrackerCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
+	if setFlags["tracker-enabled"] {
+		cfg.Tracker.Enabled = *flags.trackerEnabled
+	}
+	if setFlags["tracker-exit-after"] {
+		cfg.Tracker.ExitAfter = *flags.trackerExitAfter
+	}
+}
+
+func applyPredictionCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
+	if setFlags["prediction-enabled"] {
+		cfg.Prediction.Enabled = *flags.predictionEnabled
+	}
+	if setFlags["prediction-lookahead"] {
+		cfg.Prediction.Lookahead = *flags.predictionLookahead
+	}
+}
+
+func applyObserverCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
+	if setFlags["observer-elevation-ft"] {
+		cfg.Observer.ElevationFt = *flags.observerElevationFt
+	}
+	if setFlags["min-elevation-deg"] {
+		cfg.Observer.MinElevationDeg = *flags.minElevationDeg
+	}
+}
+
+func applyRegistryCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
+	if setFlags["registry-file"] {
+		cfg.Registry.File = *flags.registryFile
+	}
+	if setFlags["registry-reload-interval"] {
+		cfg.Registry.ReloadInterval = *flags.registryReloadInterval
+	}
+	if setFlags["airlines-file"] {
+		cfg.AirlinesFile = *flags.airlinesFile
+	}
+}
+
+func applyRoutesCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
+	if setFlags["routes-file"] {
+		cfg.Routes.File = *flags.routesFile
+	}
+	if setFlags["airports-file"] {
+		cfg.Routes.AirportsFile = *flags.airportsFile
+	}
+}
+
+func applyWatchlistCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
+	if setFlags["watchlist-hexes"] {
+		cfg.Watchlist.Hexes = ParseList(*flags.watchlistHexes)
+	}
+	if setFlags["watchlist-registrations"] {
+		cfg.Watchlist.Registrations = ParseList(*flags.watchlistRegistrations)
+	}
+	if setFlags["watchlist-callsigns"] {
+		cfg.Watchlist.Callsigns = ParseList(*flags.watchlistCallsigns)
+	}
+	if setFlags["watchlist-blockout"] {
+		cfg.Watchlist.BlockoutMin = *flags.watchlistBlockout
+	}
+}
+
 func applyCatalogerCommandLineOverrides(cfg *Config, flags commandLineFlags, setFlags map[string]bool) {
 	if setFlags["cataloger-enabled"] {
 		cfg.Cataloger.Enabled = *flags.catalogerEnabled
@@ -3,8 +3,14 @@ package config
 import (
 	"flag"
 	"os"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/geo"
+	"github.com/benvon/whats-flying-over-me/internal/rules"
+	"github.com/benvon/whats-flying-over-me/internal/watchlist"
 )
 
 // helper to reset environment and flags
@@ -681,3 +687,548 @@ func TestFlagParsing(t *testing.T) {
 		}
 	})
 }
+
+func TestParseReceivers(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []ReceiverConfig
+	}{
+		{
+			name:     "empty",
+			input:    "",
+			expected: nil,
+		},
+		{
+			name:  "named receivers",
+			input: "north=http://north.local/data/aircraft.json, south=sbs://south.local:30003",
+			expected: []ReceiverConfig{
+				{Name: "north", URL: "http://north.local/data/aircraft.json"},
+				{Name: "south", URL: "sbs://south.local:30003"},
+			},
+		},
+		{
+			name:  "bare URLs use host as name",
+			input: "http://east.local:8080/data/aircraft.json,http://west.local/data.json?a=b",
+			expected: []ReceiverConfig{
+				{Name: "east.local:8080", URL: "http://east.local:8080/data/aircraft.json"},
+				{Name: "west.local", URL: "http://west.local/data.json?a=b"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseReceivers(tt.input)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %d receivers, got %d: %+v", len(tt.expected), len(result), result)
+			}
+			for i := range tt.expected {
+				if result[i] != tt.expected[i] {
+					t.Errorf("receiver %d: expected %+v, got %+v", i, tt.expected[i], result[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadReceivers(t *testing.T) {
+	reset()
+	cfgFile, err := os.CreateTemp(t.TempDir(), "cfg*.json")
+	if err != nil {
+		t.Fatalf("temp file: %v", err)
+	}
+	if _, err := cfgFile.WriteString(`{"Receivers":[{"Name":"roof","URL":"http://roof.local/data/aircraft.json"}]}`); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := cfgFile.Close(); err != nil {
+		t.Fatalf("close config: %v", err)
+	}
+	if err := os.Setenv("WFO_CONFIG", cfgFile.Name()); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if len(cfg.Receivers) != 1 || cfg.Receivers[0].Name != "roof" {
+		t.Fatalf("expected receiver from config file, got %+v", cfg.Receivers)
+	}
+
+	if err := os.Setenv("WFO_RECEIVERS", "a=http://a.local/x.json,b=http://b.local/x.json"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if len(cfg.Receivers) != 2 {
+		t.Fatalf("expected receivers from environment, got %+v", cfg.Receivers)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-receivers", "c=http://c.local/x.json"})
+	if len(cfg.Receivers) != 1 || cfg.Receivers[0].Name != "c" {
+		t.Fatalf("expected receivers from command line, got %+v", cfg.Receivers)
+	}
+}
+
+func TestParseHeaders(t *testing.T) {
+	headers := ParseHeaders("X-Api-Key=abc, X-Station = roof ,invalid,=empty")
+	if len(headers) != 2 {
+		t.Fatalf("expected 2 headers, got %+v", headers)
+	}
+	if headers["X-Api-Key"] != "abc" || headers["X-Station"] != "roof" {
+		t.Errorf("unexpected headers %+v", headers)
+	}
+	if len(ParseHeaders("")) != 0 {
+		t.Error("expected no headers for empty input")
+	}
+}
+
+func TestLoadFetcher(t *testing.T) {
+	reset()
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.Fetcher.Timeout != 10*time.Second {
+		t.Errorf("expected default fetch timeout 10s, got %v", cfg.Fetcher.Timeout)
+	}
+	if cfg.MaxPositionAge != 30*time.Second {
+		t.Errorf("expected default max position age 30s, got %v", cfg.MaxPositionAge)
+	}
+
+	cfgFile, err := os.CreateTemp(t.TempDir(), "cfg*.json")
+	if err != nil {
+		t.Fatalf("temp file: %v", err)
+	}
+	if _, err := cfgFile.WriteString(`{"Fetcher":{"Timeout":"5s","Username":"file","Headers":{"X-Station":"roof"},"CAFile":"/etc/ca.pem"}}`); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := cfgFile.Close(); err != nil {
+		t.Fatalf("close config: %v", err)
+	}
+	if err := os.Setenv("WFO_CONFIG", cfgFile.Name()); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.Fetcher.Timeout != 5*time.Second || cfg.Fetcher.Username != "file" || cfg.Fetcher.CAFile != "/etc/ca.pem" {
+		t.Errorf("unexpected fetcher config from file: %+v", cfg.Fetcher)
+	}
+	if cfg.Fetcher.Headers["X-Station"] != "roof" {
+		t.Errorf("expected header from file, got %+v", cfg.Fetcher.Headers)
+	}
+
+	if err := os.Setenv("WFO_FETCH_USERNAME", "env"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	if err := os.Setenv("WFO_FETCH_BEARER_TOKEN", "token"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-fetch-timeout", "2s", "-fetch-ca-file", "/flag/ca.pem"})
+	if cfg.Fetcher.Username != "env" || cfg.Fetcher.BearerToken != "token" {
+		t.Errorf("expected credentials from environment, got %+v", cfg.Fetcher)
+	}
+	if cfg.Fetcher.Timeout != 2*time.Second || cfg.Fetcher.CAFile != "/flag/ca.pem" {
+		t.Errorf("expected command line overrides, got %+v", cfg.Fetcher)
+	}
+}
+
+func TestLoadMaxPositionAge(t *testing.T) {
+	reset()
+	if err := os.Setenv("WFO_MAX_POSITION_AGE", "20s"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.MaxPositionAge != 20*time.Second {
+		t.Errorf("expected max position age from environment, got %v", cfg.MaxPositionAge)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-max-position-age", "0"})
+	if cfg.MaxPositionAge != 0 {
+		t.Errorf("expected command line to disable the check, got %v", cfg.MaxPositionAge)
+	}
+}
+
+func TestLoadZeroDisablesFromConfigFile(t *testing.T) {
+	reset()
+	cfgFile, err := os.CreateTemp(t.TempDir(), "cfg*.json")
+	if err != nil {
+		t.Fatalf("temp file: %v", err)
+	}
+	if _, err := cfgFile.WriteString(`{"MaxPositionAge":"0s","Capture":{"MaxSizeMB":0,"RotateInterval":"0s"}}`); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := cfgFile.Close(); err != nil {
+		t.Fatalf("close config: %v", err)
+	}
+	if err := os.Setenv("WFO_CONFIG", cfgFile.Name()); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.MaxPositionAge != 0 {
+		t.Errorf("expected config file to disable the position age check, got %v", cfg.MaxPositionAge)
+	}
+	if cfg.Capture.MaxSizeMB != 0 || cfg.Capture.RotateInterval != 0 {
+		t.Errorf("expected config file to disable capture rotation, got %+v", cfg.Capture)
+	}
+}
+
+func TestLoadCapture(t *testing.T) {
+	reset()
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.Capture.Dir != "" || cfg.Capture.MaxSizeMB != 100 || cfg.Capture.RotateInterval != time.Hour {
+		t.Errorf("unexpected capture defaults %+v", cfg.Capture)
+	}
+
+	if err := os.Setenv("WFO_CAPTURE_DIR", "/var/lib/wfo/captures"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	if err := os.Setenv("WFO_CAPTURE_MAX_SIZE_MB", "10"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-capture-rotate-interval", "15m"})
+	if cfg.Capture.Dir != "/var/lib/wfo/captures" || cfg.Capture.MaxSizeMB != 10 || cfg.Capture.RotateInterval != 15*time.Minute {
+		t.Errorf("unexpected capture settings %+v", cfg.Capture)
+	}
+}
+
+func TestLoadTracker(t *testing.T) {
+	reset()
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.Tracker.Enabled || cfg.Tracker.ExitAfter != time.Minute {
+		t.Errorf("unexpected tracker defaults %+v", cfg.Tracker)
+	}
+
+	if err := os.Setenv("WFO_TRACKER_ENABLED", "true"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-tracker-exit-after", "2m"})
+	if !cfg.Tracker.Enabled || cfg.Tracker.ExitAfter != 2*time.Minute {
+		t.Errorf("unexpected tracker settings %+v", cfg.Tracker)
+	}
+}
+
+func TestLoadPrediction(t *testing.T) {
+	reset()
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.Prediction.Enabled || cfg.Prediction.Lookahead != 5*time.Minute {
+		t.Errorf("unexpected prediction defaults %+v", cfg.Prediction)
+	}
+
+	if err := os.Setenv("WFO_PREDICTION_ENABLED", "true"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-prediction-lookahead", "10m"})
+	if !cfg.Prediction.Enabled || cfg.Prediction.Lookahead != 10*time.Minute {
+		t.Errorf("unexpected prediction settings %+v", cfg.Prediction)
+	}
+}
+
+func TestLoadGeofenceFile(t *testing.T) {
+	reset()
+	if err := os.Setenv("WFO_GEOFENCE_FILE", "/etc/wfo/zones.geojson"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.GeofenceFile != "/etc/wfo/zones.geojson" {
+		t.Errorf("expected geofence file from environment, got %q", cfg.GeofenceFile)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-geofence-file", "zones.geojson"})
+	if cfg.GeofenceFile != "zones.geojson" {
+		t.Errorf("expected geofence file from command line, got %q", cfg.GeofenceFile)
+	}
+}
+
+func TestParseZones(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []ZoneConfig
+	}{
+		{
+			name:     "empty",
+			input:    "",
+			expected: nil,
+		},
+		{
+			name:  "radius only and full zones",
+			input: "home=40.7,-74.0,5; airport approach=40.64,-73.78,15,500,8000,30m",
+			expected: []ZoneConfig{
+				{Name: "home", BaseLat: 40.7, BaseLon: -74.0, RadiusKm: 5},
+				{Name: "airport approach", BaseLat: 40.64, BaseLon: -73.78, RadiusKm: 15, AltitudeMin: new(500), AltitudeMax: new(8000), BlockoutMin: 30 * time.Minute},
+			},
+		},
+		{
+			name:  "invalid entries skipped",
+			input: "bad=40.7,-74.0;nolat=x,-74.0,5;good=1,2,3,0,3000;noname",
+			expected: []ZoneConfig{
+				{Name: "good", BaseLat: 1, BaseLon: 2, RadiusKm: 3, AltitudeMin: new(0), AltitudeMax: new(3000)},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ParseZones(tt.input)
+			if len(result) != len(tt.expected) {
+				t.Fatalf("expected %d zones, got %d: %+v", len(tt.expected), len(result), result)
+			}
+			for i := range tt.expected {
+				if !reflect.DeepEqual(result[i], tt.expected[i]) {
+					t.Errorf("zone %d: expected %+v, got %+v", i, tt.expected[i], result[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLoadZones(t *testing.T) {
+	reset()
+	cfgFile, err := os.CreateTemp(t.TempDir(), "cfg*.json")
+	if err != nil {
+		t.Fatalf("temp file: %v", err)
+	}
+	if _, err := cfgFile.WriteString(`{"BaseLat":40.7,"BaseLon":-74.0,"RadiusKm":25,"AltitudeMin":200,"AltitudeMax":10000,"AlertDedupe":{"Enabled":true,"BlockoutMin":"15m"},"Zones":[{"Name":"home","RadiusKm":5,"AltitudeMax":3000},{"BaseLat":40.64,"BaseLon":-73.78,"AltitudeMin":500,"BlockoutMin":"30m"},{"Name":"field","AltitudeMin":0},{"Name":"ramp","AltitudeMax":0}]}`); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := cfgFile.Close(); err != nil {
+		t.Fatalf("close config: %v", err)
+	}
+	if err := os.Setenv("WFO_CONFIG", cfgFile.Name()); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	zones := cfg.MonitorZones()
+	expected := []ZoneConfig{
+		{Name: "home", BaseLat: 40.7, BaseLon: -74.0, RadiusKm: 5, AltitudeMin: new(200), AltitudeMax: new(3000), BlockoutMin: 15 * time.Minute},
+		{Name: "zone-2", BaseLat: 40.64, BaseLon: -73.78, RadiusKm: 25, AltitudeMin: new(500), AltitudeMax: new(10000), BlockoutMin: 30 * time.Minute},
+		// An explicit zero floor is kept rather than taking the top-level one.
+		{Name: "field", BaseLat: 40.7, BaseLon: -74.0, RadiusKm: 25, AltitudeMin: new(0), AltitudeMax: new(10000), BlockoutMin: 15 * time.Minute},
+		// So is an explicit zero ceiling.
+		{Name: "ramp", BaseLat: 40.7, BaseLon: -74.0, RadiusKm: 25, AltitudeMin: new(200), AltitudeMax: new(0), BlockoutMin: 15 * time.Minute},
+	}
+	if len(zones) != len(expected) {
+		t.Fatalf("expected zones from config file, got %+v", zones)
+	}
+	for i := range expected {
+		if !reflect.DeepEqual(zones[i], expected[i]) {
+			t.Errorf("zone %d: expected %+v, got %+v", i, expected[i], zones[i])
+		}
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-zones", "roof=1,2,3"})
+	if len(cfg.Zones) != 1 || cfg.Zones[0].Name != "roof" {
+		t.Errorf("expected zones from command line, got %+v", cfg.Zones)
+	}
+
+	cfg.Zones = nil
+	if zones := cfg.MonitorZones(); len(zones) != 1 || zones[0].Name != "" || zones[0].RadiusKm != cfg.RadiusKm {
+		t.Errorf("expected a single default zone, got %+v", zones)
+	}
+}
+
+func TestLoadAltitudeSettings(t *testing.T) {
+	reset()
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.AltitudeMin != 0 || cfg.AltitudeSource != "baro" || cfg.QNH != 0 {
+		t.Errorf("unexpected altitude defaults: min %d, source %q, QNH %v", cfg.AltitudeMin, cfg.AltitudeSource, cfg.QNH)
+	}
+
+	if err := os.Setenv("WFO_ALTITUDE_MIN", "500"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	if err := os.Setenv("WFO_QNH", "1021.5"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-altitude-source", "geom"})
+	if cfg.AltitudeMin != 500 || cfg.AltitudeSource != "geom" || cfg.QNH != 1021.5 {
+		t.Errorf("unexpected altitude settings: min %d, source %q, QNH %v", cfg.AltitudeMin, cfg.AltitudeSource, cfg.QNH)
+	}
+
+	filter := cfg.AltitudeFilter(1000, 3000)
+	if filter.Source != "geom" || filter.QNH != 1021.5 || filter.Min != 1000 || filter.Max != 3000 {
+		t.Errorf("unexpected altitude filter %+v", filter)
+	}
+	if zones := cfg.MonitorZones(); zones[0].Floor() != 500 {
+		t.Errorf("expected the default zone to use the altitude floor, got %+v", zones[0])
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-altitude-source", "gnss"})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `unknown altitude source "gnss"`) {
+		t.Errorf("expected an error for an unknown altitude source, got %v", err)
+	}
+}
+
+func TestLoadObserver(t *testing.T) {
+	reset()
+	if err := os.Setenv("WFO_OBSERVER_ELEVATION_FT", "420"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-min-elevation-deg", "30", "-lat", "40.5", "-lon", "-74.5"})
+	if cfg.Observer.ElevationFt != 420 || cfg.Observer.MinElevationDeg != 30 {
+		t.Errorf("unexpected observer settings %+v", cfg.Observer)
+	}
+
+	observer := cfg.BaseObserver()
+	if observer.Lat != 40.5 || observer.Lon != -74.5 || observer.ElevationFt != 420 {
+		t.Errorf("unexpected observer %+v", observer)
+	}
+}
+
+func TestLoadDistanceMode(t *testing.T) {
+	reset()
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.DistanceMode != geo.Spherical {
+		t.Errorf("expected spherical distances by default, got %q", cfg.DistanceMode)
+	}
+
+	if err := os.Setenv("WFO_DISTANCE_MODE", "ellipsoidal"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.DistanceMode != geo.Ellipsoidal || cfg.BaseObserver().Mode != geo.Ellipsoidal {
+		t.Errorf("expected ellipsoidal distances from the environment, got %q", cfg.DistanceMode)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-distance-mode", "spherical"})
+	if cfg.DistanceMode != geo.Spherical {
+		t.Errorf("expected the flag to override the environment, got %q", cfg.DistanceMode)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-distance-mode", "elipsoidal"})
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `unknown distance mode "elipsoidal"`) {
+		t.Errorf("expected an error for a misspelt distance mode, got %v", err)
+	}
+}
+
+func TestLoadRegistry(t *testing.T) {
+	reset()
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.Registry.File != "" || cfg.Registry.ReloadInterval != time.Minute {
+		t.Errorf("unexpected registry defaults %+v", cfg.Registry)
+	}
+
+	if err := os.Setenv("WFO_REGISTRY_FILE", "/var/lib/wfo/aircraft.csv.gz"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	if err := os.Setenv("WFO_REGISTRY_RELOAD_INTERVAL", "10m"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.Registry.File != "/var/lib/wfo/aircraft.csv.gz" || cfg.Registry.ReloadInterval != 10*time.Minute {
+		t.Errorf("unexpected registry settings from environment %+v", cfg.Registry)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-registry-file", "basestation.csv", "-registry-reload-interval", "0s"})
+	if cfg.Registry.File != "basestation.csv" || cfg.Registry.ReloadInterval != 0 {
+		t.Errorf("unexpected registry settings from command line %+v", cfg.Registry)
+	}
+}
+
+func TestLoadAirlinesFile(t *testing.T) {
+	reset()
+	if err := os.Setenv("WFO_AIRLINES_FILE", "/etc/wfo/airlines.csv"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.AirlinesFile != "/etc/wfo/airlines.csv" {
+		t.Errorf("unexpected airlines file from environment %q", cfg.AirlinesFile)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-airlines-file", "local.csv"})
+	if cfg.AirlinesFile != "local.csv" {
+		t.Errorf("unexpected airlines file from command line %q", cfg.AirlinesFile)
+	}
+}
+
+func TestLoadRoutes(t *testing.T) {
+	reset()
+	if err := os.Setenv("WFO_ROUTES_FILE", "/var/lib/wfo/routes.csv"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	if err := os.Setenv("WFO_AIRPORTS_FILE", "/var/lib/wfo/airports.csv"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	if cfg.Routes.File != "/var/lib/wfo/routes.csv" || cfg.Routes.AirportsFile != "/var/lib/wfo/airports.csv" {
+		t.Errorf("unexpected route settings from environment %+v", cfg.Routes)
+	}
+
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-routes-file", "routes.csv", "-airports-file", ""})
+	if cfg.Routes.File != "routes.csv" || cfg.Routes.AirportsFile != "" {
+		t.Errorf("unexpected route settings from command line %+v", cfg.Routes)
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	reset()
+	cfgFile, err := os.CreateTemp(t.TempDir(), "cfg*.json")
+	if err != nil {
+		t.Fatalf("temp file: %v", err)
+	}
+	if _, err := cfgFile.WriteString(`{"Rules":[{"Name":"heavies","When":"type in [\"B744\",\"A388\"] && alt < 10000 && distance < 20","Severity":"warning","Notifiers":["webhook"],"BlockoutMin":"1h"},{"Name":"low","When":"alt < 1000"}]}`); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := cfgFile.Close(); err != nil {
+		t.Fatalf("close config: %v", err)
+	}
+	if err := os.Setenv("WFO_CONFIG", cfgFile.Name()); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	expected := []rules.Rule{
+		{Name: "heavies", When: `type in ["B744","A388"] && alt < 10000 && distance < 20`, Severity: "warning", Notifiers: []string{"webhook"}, BlockoutMin: time.Hour},
+		{Name: "low", When: "alt < 1000"},
+	}
+	if !reflect.DeepEqual(cfg.Rules, expected) {
+		t.Errorf("expected rules %+v, got %+v", expected, cfg.Rules)
+	}
+}
+
+func TestLoadWatchlist(t *testing.T) {
+	reset()
+	cfgFile, err := os.CreateTemp(t.TempDir(), "cfg*.json")
+	if err != nil {
+		t.Fatalf("temp file: %v", err)
+	}
+	if _, err := cfgFile.WriteString(`{"Watchlist":{"Hexes":["a1b2c3"],"Registrations":["N12345"],"Callsigns":["^AF1$"],"BlockoutMin":"2h"}}`); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if err := cfgFile.Close(); err != nil {
+		t.Fatalf("close config: %v", err)
+	}
+	if err := os.Setenv("WFO_CONFIG", cfgFile.Name()); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+
+	cfg := LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), nil)
+	expected := watchlist.Config{
+		Hexes:         []string{"a1b2c3"},
+		Registrations: []string{"N12345"},
+		Callsigns:     []string{"^AF1$"},
+		BlockoutMin:   2 * time.Hour,
+	}
+	if !reflect.DeepEqual(cfg.Watchlist, expected) {
+		t.Errorf("expected watchlist %+v from file, got %+v", expected, cfg.Watchlist)
+	}
+
+	if err := os.Setenv("WFO_WATCHLIST_CALLSIGNS", "^SAM[0-9]+$, ^VENUS"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	if err := os.Setenv("WFO_WATCHLIST_BLOCKOUT", "30m"); err != nil {
+		t.Fatalf("set env: %v", err)
+	}
+	cfg = LoadWithFlagSetAndArgs(flag.NewFlagSet("test", flag.ContinueOnError), []string{"-watchlist-hexes", "adfdf8,adfdf9", "-watchlist-registrations", ""})
+	expected = watchlist.Config{
+		Hexes:       []string{"adfdf8", "adfdf9"},
+		Callsigns:   []string{"^SAM[0-9]+$", "^VENUS"},
+		BlockoutMin: 30 * time.Minute,
+	}
+	if !reflect.DeepEqual(cfg.Watchlist, expected) {
+		t.Errorf("expected watchlist %+v, got %+v", expected, cfg.Watchlist)
+	}
+}
@@ -0,0 +1,70 @@
+// Package csvfile reads comma separated data files whose columns are found
+// by name in a header row, as in the aircraft, route and airport databases.
+package csvfile
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Read reads a comma separated file with a header row and calls row for
+// each line. columns lists the accepted header names of each field, lower
+// case; the first one present is used. row looks fields up by name, getting
+// the trimmed value or "" when the field has no column. The required fields
+// must have a column.
+func Read(r io.Reader, columns map[string][]string, required []string, row func(field func(string) string)) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return fmt.Errorf("failed to read header: %w", err)
+	}
+	index := make(map[string]int)
+	for field, names := range columns {
+		index[field] = -1
+		for _, name := range names {
+			if i := indexOf(header, name); i >= 0 {
+				index[field] = i
+				break
+			}
+		}
+	}
+	for _, field := range required {
+		if index[field] < 0 {
+			return fmt.Errorf("no %s column in header", strings.Join(columns[field], " or "))
+		}
+	}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read: %w", err)
+		}
+		row(func(name string) string {
+			if i, ok := index[name]; ok && i >= 0 && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		})
+	}
+}
+
+// indexOf returns the position of the header column named name, ignoring
+// case, quotes and surrounding space, or -1.
+func indexOf(header []string, name string) int {
+	for i, h := range header {
+		if strings.EqualFold(strings.Trim(strings.TrimSpace(h), `"'`), name) {
+			return i
+		}
+	}
+	return -1
+}
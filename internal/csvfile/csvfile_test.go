@@ -0,0 +1,56 @@
+package csvfile
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRead(t *testing.T) {
+	columns := map[string][]string{
+		"hex":  {"modes", "icao24"},
+		"reg":  {"registration", "reg"},
+		"type": {"typecode"},
+	}
+	data := `" ICAO24 ",Reg,Other
+a835af, N628TS ,x
+abc123
+`
+
+	var rows [][]string
+	err := Read(strings.NewReader(data), columns, []string{"hex"}, func(field func(string) string) {
+		rows = append(rows, []string{field("hex"), field("reg"), field("type"), field("unknown")})
+	})
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := [][]string{{"a835af", "N628TS", "", ""}, {"abc123", "", "", ""}}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d rows, got %q", len(want), rows)
+	}
+	for i := range want {
+		if strings.Join(rows[i], "|") != strings.Join(want[i], "|") {
+			t.Errorf("row %d = %q, want %q", i, rows[i], want[i])
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	columns := map[string][]string{"hex": {"modes", "icao24"}}
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "failed to read header"},
+		{"missing required column", "Registration\nN1\n", "no modes or icao24 column in header"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Read(strings.NewReader(tt.data), columns, []string{"hex"}, func(func(string) string) {})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Read() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
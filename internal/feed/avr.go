@@ -0,0 +1,216 @@
+package feed
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// avrTimestampLen is the number of hex digits of the MLAT timestamp that
+// prefixes frames in the "@" AVR variant.
+const avrTimestampLen = 12
+
+// avrTicksPerMicrosecond is the rate of the MLAT timestamp counter, the
+// 12 MHz clock of dump1090 and readsb.
+const avrTicksPerMicrosecond = 12
+
+// avrFrame is a frame read from an AVR line, with its MLAT timestamp when the
+// line has the "@" form.
+type avrFrame struct {
+	data      []byte
+	timestamp uint64
+	timed     bool
+}
+
+// NewAVR creates a stream that reads the raw AVR hex feed served by
+// dump1090/readsb on port 30002. refLat and refLon give the receiver location
+// used for local CPR position decoding.
+func NewAVR(addr string, refLat, refLon float64) *Stream {
+	s := newStream("avr", addr, decodeAVR)
+	s.modes = newModeSDecoder(s.table, refLat, refLon)
+	return s
+}
+
+// decodeAVR reads AVR lines from r until it fails.
+func decodeAVR(r io.Reader, s *Stream) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		frame, err := parseAVRLine(scanner.Text())
+		if err != nil || frame == nil {
+			continue
+		}
+		position, err := s.modes.apply(frame.data, math.NaN())
+		if err != nil {
+			continue
+		}
+		if position {
+			s.notifyUpdate()
+		}
+	}
+	return scanner.Err()
+}
+
+// parseAVRLine parses a single AVR line such as
+// "*8D4840D6202CC371C32CE0576098;" or its timestamped "@" form. Blank lines
+// and comments starting with "#" return a nil frame.
+func parseAVRLine(line string) (*avrFrame, error) {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil, nil
+	}
+
+	frame := &avrFrame{}
+	var body string
+	switch line[0] {
+	case '*':
+		body = line[1:]
+	case '@':
+		if len(line) < 1+avrTimestampLen {
+			return nil, fmt.Errorf("AVR line too short: %q", line)
+		}
+		timestamp, err := strconv.ParseUint(line[1:1+avrTimestampLen], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid AVR timestamp %q: %w", line, err)
+		}
+		frame.timestamp, frame.timed = timestamp, true
+		body = line[1+avrTimestampLen:]
+	default:
+		return nil, fmt.Errorf("not an AVR frame: %q", line)
+	}
+
+	body = strings.TrimSuffix(body, ";")
+	data, err := hex.DecodeString(body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid AVR frame %q: %w", line, err)
+	}
+	if len(data) != 7 && len(data) != 14 {
+		return nil, fmt.Errorf("invalid AVR frame length %d: %q", len(data), line)
+	}
+	frame.data = data
+	return frame, nil
+}
+
+// replayClock stamps the frames of a capture with the time recorded in their
+// MLAT timestamps, counted from the start of the capture. Frames without a
+// timestamp are taken to follow the previous frame immediately, and a
+// timestamp that goes backwards, as when the receiver restarts, starts the
+// count again from there.
+type replayClock struct {
+	now     time.Time
+	last    uint64
+	started bool
+}
+
+// advance moves the clock to the time of frame.
+func (c *replayClock) advance(frame *avrFrame) {
+	if !frame.timed {
+		return
+	}
+	if c.started && frame.timestamp > c.last {
+		c.now = c.now.Add(time.Duration(frame.timestamp-c.last) * time.Microsecond / avrTicksPerMicrosecond)
+	}
+	c.last, c.started = frame.timestamp, true
+}
+
+// ReadAVR decodes every frame in an AVR capture and returns the resulting
+// aircraft state at the end of the capture. Lines that are not valid frames
+// are skipped.
+//
+// Frames are timed by their "@" MLAT timestamps, so CPR frames are only
+// paired, and aircraft only aged, by the time between them in the recording.
+// Plain "*" frames carry no time and are taken in file order as arriving
+// together with the frame before them. The snapshot's seen and seen_pos are
+// measured from the last frame, and its now is left unset, as the capture
+// does not say when it was recorded.
+func ReadAVR(r io.Reader, refLat, refLon float64) (*piaware.Data, error) {
+	clock := &replayClock{now: time.Unix(0, 0)}
+	table := newAircraftTable()
+	table.now = func() time.Time { return clock.now }
+	modes := newModeSDecoder(table, refLat, refLon)
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		frame, err := parseAVRLine(scanner.Text())
+		if err != nil || frame == nil {
+			continue
+		}
+		clock.advance(frame)
+		_, _ = modes.apply(frame.data, math.NaN())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	data := table.snapshot()
+	data.Now = 0
+	return data, nil
+}
+
+// AVRFile serves aircraft state decoded from an AVR capture file, such as
+// one recorded with netcat from port 30002. Each cycle sees the state at the
+// end of the capture; the file is only decoded again when it changes.
+type AVRFile struct {
+	path   string
+	refLat float64
+	refLon float64
+
+	mutex sync.Mutex
+	last  os.FileInfo
+	data  *piaware.Data
+}
+
+// NewAVRFile creates a source that decodes the AVR capture at path.
+func NewAVRFile(path string, refLat, refLon float64) *AVRFile {
+	return &AVRFile{path: path, refLat: refLat, refLon: refLon}
+}
+
+// Fetch returns the aircraft decoded from the capture. The url is ignored.
+func (f *AVRFile) Fetch(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+	data, err := f.FetchData(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return data.Aircraft, nil
+}
+
+// FetchData returns the capture's aircraft state as an aircraft.json snapshot.
+// Each call returns its own copy, which the caller may modify.
+func (f *AVRFile) FetchData(ctx context.Context, url string) (*piaware.Data, error) {
+	// #nosec G304 -- path is controlled via trusted config
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open AVR capture: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat AVR capture: %w", err)
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if changed(f.last, info) {
+		data, err := ReadAVR(file, f.refLat, f.refLon)
+		if err != nil {
+			return nil, err
+		}
+		f.last, f.data = info, data
+	}
+
+	data := *f.data
+	data.Aircraft = slices.Clone(f.data.Aircraft)
+	return &data, nil
+}
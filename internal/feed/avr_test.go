@@ -0,0 +1,191 @@
+package feed
+
+import (
+	"context"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+const avrCapture = `# captured with: nc receiver 30002 > capture.avr
+*8D4840D6202CC371C32CE0576098;
+*8D40621D58C386435CC412692AD6;
+@0123456789AB8D40621D58C382D690C8AC2863A7;
+*8D485020994409940838175B284F;
+*5D4840D6202CC3;
+not a frame
+*8D4840D6202CC371C32CE0576099;
+`
+
+func TestParseAVRLine(t *testing.T) {
+	tests := []struct {
+		name    string
+		line    string
+		wantLen int
+		wantErr bool
+	}{
+		{name: "long frame", line: "*8D4840D6202CC371C32CE0576098;", wantLen: 14},
+		{name: "short frame", line: "*5D4840D6202CC3;", wantLen: 7},
+		{name: "timestamped frame", line: "@0123456789AB8D4840D6202CC371C32CE0576098;", wantLen: 14},
+		{name: "missing terminator", line: "*8D4840D6202CC371C32CE0576098", wantLen: 14},
+		{name: "blank line", line: "   ", wantLen: 0},
+		{name: "comment", line: "# capture", wantLen: 0},
+		{name: "bad prefix", line: "8D4840D6202CC371C32CE0576098;", wantErr: true},
+		{name: "bad hex", line: "*8D4840D6202CC371C32CE05760ZZ;", wantErr: true},
+		{name: "bad length", line: "*8D4840;", wantErr: true},
+		{name: "short timestamped", line: "@0123;", wantErr: true},
+		{name: "bad timestamp", line: "@0123456789XB8D4840D6202CC371C32CE0576098;", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			frame, err := parseAVRLine(tt.line)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAVRLine() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got int
+			if frame != nil {
+				got = len(frame.data)
+			}
+			if got != tt.wantLen {
+				t.Errorf("expected frame length %d, got %d", tt.wantLen, got)
+			}
+		})
+	}
+}
+
+func TestReadAVR(t *testing.T) {
+	data, err := ReadAVR(strings.NewReader(avrCapture), 0, 0)
+	if err != nil {
+		t.Fatalf("ReadAVR() error = %v", err)
+	}
+	if len(data.Aircraft) != 3 {
+		t.Fatalf("expected 3 aircraft, got %d", len(data.Aircraft))
+	}
+
+	byHex := make(map[string]int)
+	for i, a := range data.Aircraft {
+		byHex[a.Hex] = i
+	}
+
+	klm := data.Aircraft[byHex["4840d6"]]
+	if klm.Flight != "KLM1023" {
+		t.Errorf("expected flight KLM1023, got %q", klm.Flight)
+	}
+
+	pos := data.Aircraft[byHex["40621d"]]
+	if math.Abs(pos.Lat-52.25720) > 0.0001 || math.Abs(pos.Lon-3.91937) > 0.0001 {
+		t.Errorf("expected position 52.25720,3.91937 got %.5f,%.5f", pos.Lat, pos.Lon)
+	}
+	if pos.AltBaro != 38000 {
+		t.Errorf("expected altitude 38000, got %d", pos.AltBaro)
+	}
+
+	vel := data.Aircraft[byHex["485020"]]
+	if math.Abs(vel.GS-159.20) > 0.01 || vel.GeomRate != -832 {
+		t.Errorf("unexpected velocity gs=%v geom_rate=%v", vel.GS, vel.GeomRate)
+	}
+}
+
+func TestReadAVRTimestamps(t *testing.T) {
+	tests := []struct {
+		name        string
+		capture     string
+		wantPos     bool
+		wantSeenPos float64
+	}{
+		{
+			name: "pair within a second",
+			capture: `@0100000000008D40621D58C386435CC412692AD6;
+@010000B71B008D40621D58C382D690C8AC2863A7;
+@0100162C45008D4840D6202CC371C32CE0576098;
+`,
+			wantPos:     true,
+			wantSeenPos: 30,
+		},
+		{
+			name: "pair recorded too far apart",
+			capture: `@0100000000008D40621D58C386435CC412692AD6;
+@01000E4E1C008D40621D58C382D690C8AC2863A7;
+`,
+		},
+		{
+			name: "untimed frames in order",
+			capture: `*8D40621D58C386435CC412692AD6;
+*8D40621D58C382D690C8AC2863A7;
+`,
+			wantPos: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ReadAVR(strings.NewReader(tt.capture), 0, 0)
+			if err != nil {
+				t.Fatalf("ReadAVR() error = %v", err)
+			}
+			if data.Now != 0 {
+				t.Errorf("expected now to be unset, got %v", data.Now)
+			}
+			var pos *piaware.Aircraft
+			for i := range data.Aircraft {
+				if data.Aircraft[i].Hex == "40621d" {
+					pos = &data.Aircraft[i]
+				}
+			}
+			if pos == nil {
+				t.Fatal("expected aircraft 40621d")
+			}
+			if hasPos := pos.Lat != 0 || pos.Lon != 0; hasPos != tt.wantPos {
+				t.Fatalf("expected position %v, got %.5f,%.5f", tt.wantPos, pos.Lat, pos.Lon)
+			}
+			if tt.wantPos && math.Abs(pos.SeenPos-tt.wantSeenPos) > 0.001 {
+				t.Errorf("expected seen_pos %v, got %v", tt.wantSeenPos, pos.SeenPos)
+			}
+		})
+	}
+}
+
+func TestAVRFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.avr")
+	if err := os.WriteFile(path, []byte(avrCapture), 0o600); err != nil {
+		t.Fatalf("write capture: %v", err)
+	}
+
+	source := NewAVRFile(path, 0, 0)
+	for i := 0; i < 2; i++ {
+		aircraft, err := source.Fetch(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if len(aircraft) != 3 {
+			t.Errorf("fetch %d: expected 3 aircraft, got %d", i, len(aircraft))
+		}
+		// Changes to one fetch's aircraft do not leak into the next.
+		aircraft[0].Hex = "changed"
+	}
+
+	// The capture is decoded again when it changes.
+	if err := os.WriteFile(path, []byte("*8D4840D6202CC371C32CE0576098;\n"), 0o600); err != nil {
+		t.Fatalf("write capture: %v", err)
+	}
+	if err := os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("touch capture: %v", err)
+	}
+	aircraft, err := source.Fetch(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if len(aircraft) != 1 || aircraft[0].Hex != "4840d6" {
+		t.Errorf("expected the rewritten capture, got %+v", aircraft)
+	}
+
+	if _, err := NewAVRFile(filepath.Join(t.TempDir(), "missing.avr"), 0, 0).Fetch(context.Background(), ""); err == nil {
+		t.Error("expected error for missing capture")
+	}
+}
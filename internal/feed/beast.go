@@ -0,0 +1,136 @@
+package feed
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Beast protocol framing.
+const (
+	beastEscape      = 0x1a
+	beastModeAC      = '1'
+	beastModeSShort  = '2'
+	beastModeSLong   = '3'
+	beastTimestamp   = 6
+	beastSignalLevel = 1
+)
+
+// errBeastResync is returned when a frame is interrupted by the start of
+// another frame; the reader resynchronises on the new frame.
+var errBeastResync = errors.New("beast frame interrupted")
+
+// NewBeast creates a stream that reads the Mode S Beast binary feed served
+// by dump1090/readsb on port 30005. refLat and refLon give the receiver
+// location used for local CPR position decoding.
+func NewBeast(addr string, refLat, refLon float64) *Stream {
+	s := newStream("beast", addr, decodeBeast)
+	s.modes = newModeSDecoder(s.table, refLat, refLon)
+	return s
+}
+
+// decodeBeast reads Beast frames from r until it fails.
+func decodeBeast(r io.Reader, s *Stream) error {
+	br := bufio.NewReader(r)
+	for {
+		frame, signal, err := readBeastFrame(br)
+		if err != nil {
+			return err
+		}
+		if frame == nil {
+			continue
+		}
+
+		position, err := s.modes.apply(frame, signalToRSSI(signal))
+		if err != nil {
+			// Damaged frames are common on a live feed; skip them.
+			continue
+		}
+		if position {
+			s.notifyUpdate()
+		}
+	}
+}
+
+// readBeastFrame reads the next Beast frame and returns its Mode S payload
+// and signal level. Mode A/C and unknown frame types return a nil payload.
+func readBeastFrame(r *bufio.Reader) ([]byte, byte, error) {
+	for {
+		// Scan for the escape byte that starts a frame.
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, 0, err
+		}
+		if b != beastEscape {
+			continue
+		}
+
+		for {
+			frame, signal, err := readBeastBody(r)
+			if !errors.Is(err, errBeastResync) {
+				return frame, signal, err
+			}
+			// The frame was interrupted by a new one whose escape byte has
+			// already been consumed, so read the new frame body directly.
+		}
+	}
+}
+
+// readBeastBody reads a frame after its leading escape byte.
+func readBeastBody(r *bufio.Reader) ([]byte, byte, error) {
+	t, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var payloadLen int
+	switch t {
+	case beastModeAC:
+		payloadLen = 2
+	case beastModeSShort:
+		payloadLen = 7
+	case beastModeSLong:
+		payloadLen = 14
+	default:
+		// Escaped data bytes and unknown frame types are skipped.
+		return nil, 0, nil
+	}
+
+	buf := make([]byte, beastTimestamp+beastSignalLevel+payloadLen)
+	if err := readBeastEscaped(r, buf); err != nil {
+		return nil, 0, err
+	}
+
+	signal := buf[beastTimestamp]
+	if t == beastModeAC {
+		return nil, signal, nil
+	}
+	return buf[beastTimestamp+beastSignalLevel:], signal, nil
+}
+
+// readBeastEscaped fills buf from r, collapsing doubled escape bytes. A lone
+// escape byte marks the start of a new frame; the byte after it is pushed
+// back and errBeastResync returned.
+func readBeastEscaped(r *bufio.Reader, buf []byte) error {
+	for i := range buf {
+		b, err := r.ReadByte()
+		if err != nil {
+			return err
+		}
+		if b == beastEscape {
+			next, err := r.ReadByte()
+			if err != nil {
+				return err
+			}
+			if next != beastEscape {
+				if err := r.UnreadByte(); err != nil {
+					return fmt.Errorf("beast resync: %w", err)
+				}
+				return errBeastResync
+			}
+		}
+		buf[i] = b
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package feed
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+// encodeBeast wraps a Mode S frame in Beast framing, escaping 0x1a bytes.
+func encodeBeast(t *testing.T, frameHex string, signal byte) []byte {
+	t.Helper()
+	frame, err := hex.DecodeString(frameHex)
+	if err != nil {
+		t.Fatalf("decode hex: %v", err)
+	}
+
+	frameType := byte(beastModeSLong)
+	if len(frame) == 7 {
+		frameType = beastModeSShort
+	}
+
+	body := append([]byte{0, 0, 0, 0, 0, 0, signal}, frame...)
+	out := []byte{beastEscape, frameType}
+	for _, b := range body {
+		out = append(out, b)
+		if b == beastEscape {
+			out = append(out, beastEscape)
+		}
+	}
+	return out
+}
+
+func TestDecodeBeast(t *testing.T) {
+	var feed []byte
+	feed = append(feed, 0x00, 0x42)                                              // leading noise before the first frame
+	feed = append(feed, encodeBeast(t, "8D40621D202CC371C32CE0000000", 0x80)...) // damaged CRC, skipped
+	feed = append(feed, encodeBeast(t, "8D40621D58C386435CC412692AD6", 0x80)...)
+	feed = append(feed, encodeBeast(t, "8D40621D58C382D690C8AC2863A7", 0x1a)...) // escaped signal byte
+	feed = append(feed, beastEscape, beastModeAC, 0, 0, 0, 0, 0, 0, 0x10, 0x12, 0x34)
+
+	s := NewBeast("unused:30005", 0, 0)
+	if err := decodeBeast(bytes.NewReader(feed), s); !errors.Is(err, io.EOF) {
+		t.Fatalf("decodeBeast() error = %v, expected EOF", err)
+	}
+
+	data := s.table.snapshot()
+	if len(data.Aircraft) != 1 {
+		t.Fatalf("expected 1 aircraft, got %d", len(data.Aircraft))
+	}
+
+	a := data.Aircraft[0]
+	if a.Hex != "40621d" {
+		t.Errorf("expected hex 40621d, got %s", a.Hex)
+	}
+	if a.AltBaro != 38000 {
+		t.Errorf("expected altitude 38000, got %d", a.AltBaro)
+	}
+	if math.Abs(a.Lat-52.25720) > 0.0001 || math.Abs(a.Lon-3.91937) > 0.0001 {
+		t.Errorf("expected position 52.25720,3.91937 got %.5f,%.5f", a.Lat, a.Lon)
+	}
+	if a.RSSI == 0 {
+		t.Error("expected RSSI from signal level")
+	}
+
+	select {
+	case <-s.Updates():
+	default:
+		t.Error("expected a position update signal")
+	}
+}
+
+func TestReadBeastFrameResync(t *testing.T) {
+	good := encodeBeast(t, "8D4840D6202CC371C32CE0576098", 0x50)
+
+	// A truncated frame interrupted by the start of a complete one.
+	feed := append([]byte{beastEscape, beastModeSLong, 1, 2, 3}, good...)
+
+	r := bufio.NewReader(bytes.NewReader(feed))
+	frame, signal, err := readBeastFrame(r)
+	if err != nil {
+		t.Fatalf("readBeastFrame() error = %v", err)
+	}
+	if hex.EncodeToString(frame) != "8d4840d6202cc371c32ce0576098" {
+		t.Errorf("unexpected frame %x", frame)
+	}
+	if signal != 0x50 {
+		t.Errorf("expected signal 0x50, got %#x", signal)
+	}
+}
+
+func TestModeSDecoderReferencePosition(t *testing.T) {
+	table := newAircraftTable()
+	d := newModeSDecoder(table, 52.2, 3.9)
+
+	frame, _ := hex.DecodeString("8D40621D58C382D690C8AC2863A7")
+	position, err := d.apply(frame, math.NaN())
+	if err != nil {
+		t.Fatalf("apply() error = %v", err)
+	}
+	if !position {
+		t.Fatal("expected a single frame to decode against the receiver location")
+	}
+
+	a := table.snapshot().Aircraft[0]
+	if math.Abs(a.Lat-52.25720) > 0.0001 || math.Abs(a.Lon-3.91937) > 0.0001 {
+		t.Errorf("expected position 52.25720,3.91937 got %.5f,%.5f", a.Lat, a.Lon)
+	}
+	if a.RSSI != 0 {
+		t.Errorf("expected no RSSI for unknown signal, got %v", a.RSSI)
+	}
+}
@@ -0,0 +1,152 @@
+package feed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/logger"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// filePollInterval is how often a watched file is checked for changes.
+// readsb rewrites aircraft.json once a second.
+const filePollInterval = 250 * time.Millisecond
+
+// File reads aircraft.json snapshots written to the local filesystem by
+// dump1090 or readsb, such as /run/readsb/aircraft.json.
+//
+// The writers replace the file atomically by renaming a temporary file over
+// it. Each read opens the path once and takes the change check from the
+// opened file, so a rename during a read yields either the old or the new
+// snapshot, never a mix of both.
+type File struct {
+	path     string
+	interval time.Duration
+	updates  chan struct{}
+
+	mutex sync.Mutex
+	last  os.FileInfo
+}
+
+// NewFile creates a source for the aircraft.json at path. If path is a
+// directory, aircraft.json inside it is read.
+func NewFile(path string) *File {
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		path = filepath.Join(path, "aircraft.json")
+	}
+	return &File{
+		path:     path,
+		interval: filePollInterval,
+		updates:  make(chan struct{}, 1),
+	}
+}
+
+// Start watches the file for modification in the background until ctx is
+// cancelled, signalling Updates whenever it changes.
+func (f *File) Start(ctx context.Context) {
+	go f.watch(ctx)
+}
+
+// Fetch returns the aircraft in the file. The url is ignored.
+func (f *File) Fetch(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+	data, err := f.FetchData(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return data.Aircraft, nil
+}
+
+// FetchData reads the snapshot from the file. It returns
+// piaware.ErrNotModified if the file has not changed since the last
+// successful read.
+func (f *File) FetchData(ctx context.Context, url string) (*piaware.Data, error) {
+	// #nosec G304 -- path is controlled via trusted config
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open aircraft data file: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat aircraft data file: %w", err)
+	}
+
+	f.mutex.Lock()
+	last := f.last
+	f.mutex.Unlock()
+	if !changed(last, info) {
+		return nil, piaware.ErrNotModified
+	}
+
+	var data piaware.Data
+	if err := json.NewDecoder(file).Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode aircraft data file: %w", err)
+	}
+
+	f.mutex.Lock()
+	f.last = info
+	f.mutex.Unlock()
+
+	return &data, nil
+}
+
+// Updates returns a channel that is signalled whenever the file changes.
+// Signals are coalesced, so a slow reader never blocks the watcher.
+func (f *File) Updates() <-chan struct{} {
+	return f.updates
+}
+
+// watch polls the path for changes. A missing file is tolerated so the
+// watcher survives the receiver restarting.
+func (f *File) watch(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	var last os.FileInfo
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		info, err := os.Stat(f.path)
+		if err != nil {
+			if last != nil {
+				logger.Warn("aircraft data file unavailable", map[string]interface{}{
+					"path":  f.path,
+					"error": err.Error(),
+				})
+			}
+			last = nil
+			continue
+		}
+		if !changed(last, info) {
+			continue
+		}
+		last = info
+
+		select {
+		case f.updates <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// changed reports whether info describes a different file or a different
+// version of the file than last. A rename over the path changes the file
+// identity even if the modification time is unchanged.
+func changed(last, info os.FileInfo) bool {
+	if last == nil {
+		return true
+	}
+	return !os.SameFile(last, info) || !last.ModTime().Equal(info.ModTime()) || last.Size() != info.Size()
+}
@@ -0,0 +1,104 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// writeAtomic replaces path the way readsb does, by renaming a temporary
+// file over it.
+func writeAtomic(t *testing.T, path, content string) {
+	t.Helper()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(content), 0o600); err != nil {
+		t.Fatalf("write temp file: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+}
+
+func TestFileFetchData(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "aircraft.json")
+	writeAtomic(t, path, `{"now":1000,"aircraft":[{"hex":"aaa111","lat":40.1,"lon":-74.1,"alt_baro":3000}]}`)
+
+	// A directory resolves to the aircraft.json inside it.
+	f := NewFile(dir)
+
+	data, err := f.FetchData(context.Background(), "")
+	if err != nil {
+		t.Fatalf("FetchData() error = %v", err)
+	}
+	if data.Now != 1000 || len(data.Aircraft) != 1 || data.Aircraft[0].Hex != "aaa111" {
+		t.Fatalf("unexpected snapshot %+v", data)
+	}
+
+	if _, err := f.FetchData(context.Background(), ""); !errors.Is(err, piaware.ErrNotModified) {
+		t.Fatalf("expected ErrNotModified for unchanged file, got %v", err)
+	}
+
+	writeAtomic(t, path, `{"now":1001,"aircraft":[{"hex":"bbb222","lat":40.2,"lon":-74.2,"alt_baro":4000}]}`)
+	data, err = f.FetchData(context.Background(), "")
+	if err != nil {
+		t.Fatalf("FetchData() after rename error = %v", err)
+	}
+	if data.Now != 1001 || data.Aircraft[0].Hex != "bbb222" {
+		t.Errorf("expected replaced snapshot, got %+v", data)
+	}
+}
+
+func TestFileFetchDataErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	f := NewFile(filepath.Join(dir, "missing.json"))
+	if _, err := f.FetchData(context.Background(), ""); err == nil {
+		t.Error("expected error for missing file")
+	}
+
+	path := filepath.Join(dir, "aircraft.json")
+	writeAtomic(t, path, `{"now":1000,"aircraft":[`)
+	f = NewFile(path)
+	if _, err := f.FetchData(context.Background(), ""); err == nil {
+		t.Fatal("expected error for truncated file")
+	}
+
+	// A failed read does not mark the file as seen.
+	writeAtomic(t, path, `{"now":1000,"aircraft":[]}`)
+	if _, err := f.FetchData(context.Background(), ""); err != nil {
+		t.Errorf("FetchData() error = %v", err)
+	}
+}
+
+func TestFileWatchSignalsUpdates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aircraft.json")
+	writeAtomic(t, path, `{"now":1000,"aircraft":[]}`)
+
+	f := NewFile(path)
+	f.interval = 10 * time.Millisecond
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	f.Start(ctx)
+
+	waitUpdate := func() {
+		t.Helper()
+		select {
+		case <-f.Updates():
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for update")
+		}
+	}
+
+	// The first poll reports the existing file.
+	waitUpdate()
+
+	writeAtomic(t, path, `{"now":1001,"aircraft":[{"hex":"aaa111"}]}`)
+	waitUpdate()
+}
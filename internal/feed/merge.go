@@ -0,0 +1,173 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/benvon/whats-flying-over-me/internal/logger"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// DataFetcher retrieves a complete aircraft.json snapshot from url.
+type DataFetcher func(ctx context.Context, url string) (*piaware.Data, error)
+
+// Receiver is one of several receivers merged into a single view.
+type Receiver struct {
+	Name  string
+	URL   string
+	Fetch DataFetcher
+}
+
+// Merger polls several receivers at once and merges their aircraft by hex,
+// keeping the freshest position for each aircraft. A receiver that fails
+// only degrades the merged view; Merger fails only when every receiver does.
+// Receivers are told apart by their position in the list, so two receivers
+// may share a name, as bare URLs on the same host do.
+type Merger struct {
+	receivers []Receiver
+	last      []*piaware.Data
+	mutex     sync.Mutex
+}
+
+// NewMerger creates a merger over the given receivers.
+func NewMerger(receivers []Receiver) *Merger {
+	return &Merger{
+		receivers: receivers,
+		last:      make([]*piaware.Data, len(receivers)),
+	}
+}
+
+// Fetch returns the merged aircraft. The url is ignored; each receiver uses
+// its own.
+func (m *Merger) Fetch(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+	data, err := m.FetchData(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return data.Aircraft, nil
+}
+
+// FetchData polls all receivers concurrently and returns the merged snapshot.
+// A receiver reporting piaware.ErrNotModified contributes its previous
+// snapshot.
+func (m *Merger) FetchData(ctx context.Context, url string) (*piaware.Data, error) {
+	results := make([]*piaware.Data, len(m.receivers))
+	errs := make([]error, len(m.receivers))
+
+	var wg sync.WaitGroup
+	for i, r := range m.receivers {
+		wg.Add(1)
+		go func(i int, r Receiver) {
+			defer wg.Done()
+			results[i], errs[i] = r.Fetch(ctx, r.URL)
+		}(i, r)
+	}
+	wg.Wait()
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	var failed []error
+	snapshots := make([]*piaware.Data, len(m.receivers))
+	received := 0
+	for i, r := range m.receivers {
+		if errors.Is(errs[i], piaware.ErrNotModified) {
+			if last := m.last[i]; last != nil {
+				snapshots[i] = last
+				received++
+				continue
+			}
+		}
+		if errs[i] != nil {
+			logger.Warn("receiver fetch failed", map[string]interface{}{
+				"receiver": r.Name,
+				"url":      r.URL,
+				"error":    errs[i].Error(),
+			})
+			failed = append(failed, fmt.Errorf("receiver %s: %w", r.Name, errs[i]))
+			continue
+		}
+		snapshots[i] = results[i]
+		m.last[i] = results[i]
+		received++
+	}
+
+	if received == 0 && len(m.receivers) > 0 {
+		return nil, fmt.Errorf("all receivers failed: %w", errors.Join(failed...))
+	}
+
+	return merge(m.receivers, snapshots), nil
+}
+
+// merge combines receiver snapshots, keeping for each hex the entry with
+// the most recent position, or the most recent message if none has a
+// position. Each aircraft is tagged with the receiver it was taken from, and
+// its seen and seen_pos are rebased onto the latest receiver now. snapshots
+// holds each receiver's snapshot by position, nil for receivers that failed.
+func merge(receivers []Receiver, snapshots []*piaware.Data) *piaware.Data {
+	merged := &piaware.Data{}
+	index := make(map[string]int)
+	snapshotTime := make(map[string]float64)
+
+	// Iterate in receiver order so ties resolve deterministically.
+	for n, r := range receivers {
+		data := snapshots[n]
+		if data == nil {
+			continue
+		}
+		if data.Now > merged.Now {
+			merged.Now = data.Now
+		}
+		merged.Messages += data.Messages
+
+		for _, a := range data.Aircraft {
+			a.Receiver = r.Name
+
+			i, seen := index[a.Hex]
+			if !seen {
+				index[a.Hex] = len(merged.Aircraft)
+				snapshotTime[a.Hex] = data.Now
+				merged.Aircraft = append(merged.Aircraft, a)
+				continue
+			}
+			if fresher(data.Now, a, snapshotTime[a.Hex], merged.Aircraft[i]) {
+				snapshotTime[a.Hex] = data.Now
+				merged.Aircraft[i] = a
+			}
+		}
+	}
+
+	// Express ages relative to the merged now so they stay comparable with
+	// it when positions are aged downstream.
+	for i := range merged.Aircraft {
+		a := &merged.Aircraft[i]
+		lag := merged.Now - snapshotTime[a.Hex]
+		a.Seen += lag
+		if a.Lat != 0 || a.Lon != 0 {
+			a.SeenPos += lag
+		}
+	}
+
+	return merged
+}
+
+// fresher reports whether aircraft a, from a snapshot taken at aNow, is
+// fresher than b from a snapshot taken at bNow. An entry with a position
+// beats one without; otherwise the later absolute position (now - seen_pos)
+// or message time (now - seen) wins.
+func fresher(aNow float64, a piaware.Aircraft, bNow float64, b piaware.Aircraft) bool {
+	aPos := a.Lat != 0 || a.Lon != 0
+	bPos := b.Lat != 0 || b.Lon != 0
+	switch {
+	case aPos && !bPos:
+		return true
+	case !aPos && bPos:
+		return false
+	case aPos:
+		return aNow-a.SeenPos > bNow-b.SeenPos
+	default:
+		return aNow-a.Seen > bNow-b.Seen
+	}
+}
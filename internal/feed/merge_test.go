@@ -0,0 +1,181 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func staticReceiver(name string, data *piaware.Data, err error) Receiver {
+	return Receiver{
+		Name: name,
+		URL:  "http://" + name + "/data/aircraft.json",
+		Fetch: func(ctx context.Context, url string) (*piaware.Data, error) {
+			if err != nil {
+				return nil, err
+			}
+			return data, nil
+		},
+	}
+}
+
+func TestMergerKeepsFreshestPosition(t *testing.T) {
+	north := &piaware.Data{Now: 1000, Aircraft: []piaware.Aircraft{
+		{Hex: "aaa111", Lat: 40.1, Lon: -74.1, SeenPos: 5},
+		{Hex: "bbb222", Lat: 40.2, Lon: -74.2, SeenPos: 1},
+		{Hex: "ccc333", Seen: 1},
+	}}
+	south := &piaware.Data{Now: 1002, Aircraft: []piaware.Aircraft{
+		{Hex: "aaa111", Lat: 40.15, Lon: -74.15, SeenPos: 1}, // position at 1001, fresher than 995
+		{Hex: "bbb222", Lat: 40.25, Lon: -74.25, SeenPos: 4}, // position at 998, older than 999
+		{Hex: "ccc333", Lat: 40.3, Lon: -74.3, SeenPos: 30},  // any position beats none
+		{Hex: "ddd444", Lat: 40.4, Lon: -74.4, SeenPos: 2},
+	}}
+
+	m := NewMerger([]Receiver{
+		staticReceiver("north", north, nil),
+		staticReceiver("south", south, nil),
+	})
+
+	data, err := m.FetchData(context.Background(), "")
+	if err != nil {
+		t.Fatalf("FetchData() error = %v", err)
+	}
+	if data.Now != 1002 {
+		t.Errorf("expected merged now 1002, got %v", data.Now)
+	}
+	if len(data.Aircraft) != 4 {
+		t.Fatalf("expected 4 aircraft, got %d", len(data.Aircraft))
+	}
+
+	want := map[string]string{
+		"aaa111": "south",
+		"bbb222": "north",
+		"ccc333": "south",
+		"ddd444": "south",
+	}
+	for _, a := range data.Aircraft {
+		if a.Receiver != want[a.Hex] {
+			t.Errorf("aircraft %s: expected receiver %s, got %s", a.Hex, want[a.Hex], a.Receiver)
+		}
+	}
+}
+
+func TestMergerDegradesOnReceiverFailure(t *testing.T) {
+	good := &piaware.Data{Now: 1000, Aircraft: []piaware.Aircraft{{Hex: "aaa111", Lat: 40.1, Lon: -74.1}}}
+
+	m := NewMerger([]Receiver{
+		staticReceiver("down", nil, errors.New("connection refused")),
+		staticReceiver("up", good, nil),
+	})
+
+	aircraft, err := m.Fetch(context.Background(), "")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v, expected degraded result", err)
+	}
+	if len(aircraft) != 1 || aircraft[0].Receiver != "up" {
+		t.Errorf("expected aircraft from the working receiver, got %+v", aircraft)
+	}
+}
+
+func TestMergerFailsWhenAllReceiversFail(t *testing.T) {
+	m := NewMerger([]Receiver{
+		staticReceiver("a", nil, errors.New("timeout")),
+		staticReceiver("b", nil, errors.New("connection refused")),
+	})
+
+	if _, err := m.Fetch(context.Background(), ""); err == nil {
+		t.Fatal("expected error when every receiver fails")
+	}
+}
+
+func TestMergerReusesSnapshotWhenNotModified(t *testing.T) {
+	snapshot := &piaware.Data{Now: 1000, Aircraft: []piaware.Aircraft{{Hex: "aaa111", Lat: 40.1, Lon: -74.1}}}
+	calls := 0
+
+	m := NewMerger([]Receiver{{
+		Name: "roof",
+		URL:  "http://roof/data/aircraft.json",
+		Fetch: func(ctx context.Context, url string) (*piaware.Data, error) {
+			calls++
+			if calls > 1 {
+				return nil, piaware.ErrNotModified
+			}
+			return snapshot, nil
+		},
+	}})
+
+	for i := 0; i < 2; i++ {
+		aircraft, err := m.Fetch(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Fetch() call %d error = %v", i+1, err)
+		}
+		if len(aircraft) != 1 || aircraft[0].Hex != "aaa111" {
+			t.Errorf("Fetch() call %d: expected previous snapshot, got %+v", i+1, aircraft)
+		}
+	}
+}
+
+func TestMergerRebasesAgesOntoMergedNow(t *testing.T) {
+	north := &piaware.Data{Now: 1000, Aircraft: []piaware.Aircraft{
+		{Hex: "aaa111", Lat: 40.1, Lon: -74.1, Seen: 1, SeenPos: 2},
+	}}
+	south := &piaware.Data{Now: 1004, Aircraft: []piaware.Aircraft{
+		{Hex: "bbb222", Lat: 40.2, Lon: -74.2, Seen: 1, SeenPos: 1},
+	}}
+
+	m := NewMerger([]Receiver{
+		staticReceiver("north", north, nil),
+		staticReceiver("south", south, nil),
+	})
+
+	data, err := m.FetchData(context.Background(), "")
+	if err != nil {
+		t.Fatalf("FetchData() error = %v", err)
+	}
+	for _, a := range data.Aircraft {
+		switch a.Hex {
+		case "aaa111":
+			if a.Seen != 5 || a.SeenPos != 6 {
+				t.Errorf("expected north ages rebased to 5/6, got %v/%v", a.Seen, a.SeenPos)
+			}
+		case "bbb222":
+			if a.Seen != 1 || a.SeenPos != 1 {
+				t.Errorf("expected south ages unchanged, got %v/%v", a.Seen, a.SeenPos)
+			}
+		}
+	}
+	if north.Aircraft[0].SeenPos != 2 {
+		t.Error("expected receiver snapshot to be left untouched")
+	}
+}
+
+func TestMergerReceiversOnSameHost(t *testing.T) {
+	// Bare URLs on the same host are both named after the host.
+	skyaware := &piaware.Data{Now: 1000, Aircraft: []piaware.Aircraft{{Hex: "aaa111", Lat: 40.1, Lon: -74.1}}}
+	tar1090 := &piaware.Data{Now: 1000, Aircraft: []piaware.Aircraft{{Hex: "bbb222", Lat: 40.2, Lon: -74.2}}}
+	cycle := 0
+	m := NewMerger([]Receiver{
+		{Name: "pi", URL: "http://pi/skyaware/data/aircraft.json", Fetch: func(ctx context.Context, url string) (*piaware.Data, error) {
+			if cycle > 0 {
+				return nil, piaware.ErrNotModified
+			}
+			return skyaware, nil
+		}},
+		{Name: "pi", URL: "http://pi/tar1090/data/aircraft.json", Fetch: func(ctx context.Context, url string) (*piaware.Data, error) {
+			return tar1090, nil
+		}},
+	})
+
+	for cycle = 0; cycle < 2; cycle++ {
+		aircraft, err := m.Fetch(context.Background(), "")
+		if err != nil {
+			t.Fatalf("cycle %d: Fetch() error = %v", cycle, err)
+		}
+		if len(aircraft) != 2 || aircraft[0].Hex != "aaa111" || aircraft[1].Hex != "bbb222" {
+			t.Errorf("cycle %d: expected aircraft from both receivers, got %+v", cycle, aircraft)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package feed
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/adsb"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// pruneInterval is how often stale per-aircraft decoder state is released.
+const pruneInterval = time.Minute
+
+// positionState holds the CPR decoder for one aircraft.
+type positionState struct {
+	decoder  adsb.PositionDecoder
+	lastSeen time.Time
+}
+
+// modeSDecoder applies binary Mode S frames to an aircraft table.
+type modeSDecoder struct {
+	table     *aircraftTable
+	refLat    float64
+	refLon    float64
+	hasRef    bool
+	positions map[string]*positionState
+	lastPrune time.Time
+}
+
+// newModeSDecoder creates a decoder that uses the given receiver location as
+// the reference for local CPR decoding. A 0,0 location disables it.
+func newModeSDecoder(table *aircraftTable, refLat, refLon float64) *modeSDecoder {
+	return &modeSDecoder{
+		table:     table,
+		refLat:    refLat,
+		refLon:    refLon,
+		hasRef:    refLat != 0 || refLon != 0,
+		positions: make(map[string]*positionState),
+	}
+}
+
+// apply decodes a Mode S frame and applies it to the table. rssi is the
+// signal level in dBFS, or NaN if unknown. It reports whether the frame
+// updated the aircraft position. Frames that are valid but not understood
+// are ignored without error.
+func (d *modeSDecoder) apply(frame []byte, rssi float64) (bool, error) {
+	msg, err := adsb.Decode(frame)
+	if err != nil {
+		if errors.Is(err, adsb.ErrUnsupported) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	now := d.table.now()
+	d.prune(now)
+
+	var lat, lon float64
+	position := false
+	if msg.HasCPR {
+		state, ok := d.positions[msg.ICAO]
+		if !ok {
+			state = &positionState{}
+			d.positions[msg.ICAO] = state
+		}
+		state.lastSeen = now
+		lat, lon, position = state.decoder.Decode(msg.CPR, now, d.refLat, d.refLon, d.hasRef)
+	}
+
+	d.table.update(msg.ICAO, position, func(a *piaware.Aircraft) {
+		a.Type = "adsb_icao"
+		if msg.DF == 18 {
+			a.Type = "adsr_icao"
+		}
+		if !math.IsNaN(rssi) {
+			a.RSSI = rssi
+		}
+		applyMessage(a, msg)
+		if position {
+			a.Lat = lat
+			a.Lon = lon
+		}
+	})
+
+	return position, nil
+}
+
+// applyMessage copies the decoded fields of msg onto the aircraft.
+func applyMessage(a *piaware.Aircraft, msg *adsb.Message) {
+	switch msg.Kind {
+	case adsb.KindIdentification:
+		a.Flight = msg.Callsign
+		if msg.Category != "" {
+			a.Category = msg.Category
+		}
+	case adsb.KindSurface:
+		a.OnGround = true
+	case adsb.KindAirborne:
+		a.OnGround = false
+		if msg.HasAlt {
+			if msg.GNSSAlt {
+				a.AltGeom = msg.Altitude
+			} else {
+				a.AltBaro = msg.Altitude
+			}
+		}
+	case adsb.KindVelocity:
+		if msg.HasGS {
+			a.GS = msg.GS
+			a.Track = msg.Track
+		}
+		if msg.HasAirspeed {
+			a.MagHeading = msg.Heading
+			if msg.TrueAirspeed {
+				a.TAS = msg.Airspeed
+			} else {
+				a.IAS = msg.Airspeed
+			}
+		}
+		if msg.HasVertRate {
+			if msg.BaroVertRate {
+				a.BaroRate = msg.VertRate
+			} else {
+				a.GeomRate = msg.VertRate
+			}
+		}
+	case adsb.KindStatus:
+		a.Emergency = msg.Emergency
+		a.Squawk = msg.Squawk
+	}
+}
+
+// prune releases decoder state for aircraft that have gone quiet.
+func (d *modeSDecoder) prune(now time.Time) {
+	if now.Sub(d.lastPrune) < pruneInterval {
+		return
+	}
+	d.lastPrune = now
+	for icao, state := range d.positions {
+		if now.Sub(state.lastSeen) > expireAfter {
+			delete(d.positions, icao)
+		}
+	}
+}
+
+// signalToRSSI converts a Beast signal level byte to dBFS.
+func signalToRSSI(signal byte) float64 {
+	if signal == 0 {
+		return math.NaN()
+	}
+	level := float64(signal) / 255
+	return 10 * math.Log10(level*level)
+}
@@ -0,0 +1,144 @@
+package feed
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// SBS field positions in a BaseStation MSG line.
+const (
+	sbsTransmissionType = 1
+	sbsHexIdent         = 4
+	sbsCallsign         = 10
+	sbsAltitude         = 11
+	sbsGroundSpeed      = 12
+	sbsTrack            = 13
+	sbsLat              = 14
+	sbsLon              = 15
+	sbsVerticalRate     = 16
+	sbsSquawk           = 17
+	sbsEmergency        = 19
+	sbsOnGround         = 21
+	sbsFieldCount       = 22
+)
+
+// NewSBS creates a stream that reads the BaseStation (SBS-1) CSV feed
+// served by dump1090/readsb on port 30003.
+func NewSBS(addr string) *Stream {
+	return newStream("sbs", addr, decodeSBS)
+}
+
+// decodeSBS reads SBS lines from r until it fails.
+func decodeSBS(r io.Reader, s *Stream) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		position, err := applySBSLine(s.table, scanner.Text())
+		if err != nil {
+			// Malformed lines are skipped; the feed carries on.
+			continue
+		}
+		if position {
+			s.notifyUpdate()
+		}
+	}
+	return scanner.Err()
+}
+
+// applySBSLine applies a single SBS message to the table. It reports
+// whether the message carried a position.
+func applySBSLine(t *aircraftTable, line string) (bool, error) {
+	fields := strings.Split(strings.TrimSpace(line), ",")
+	if len(fields) < sbsFieldCount || fields[0] != "MSG" {
+		return false, fmt.Errorf("not an SBS MSG line: %q", line)
+	}
+
+	msgType, err := strconv.Atoi(fields[sbsTransmissionType])
+	if err != nil || msgType < 1 || msgType > 8 {
+		return false, fmt.Errorf("invalid SBS transmission type %q", fields[sbsTransmissionType])
+	}
+
+	hex := strings.ToLower(strings.TrimSpace(fields[sbsHexIdent]))
+	if hex == "" {
+		return false, fmt.Errorf("SBS line without hex ident")
+	}
+
+	lat, latOK := parseSBSFloat(fields[sbsLat])
+	lon, lonOK := parseSBSFloat(fields[sbsLon])
+	position := latOK && lonOK
+
+	t.update(hex, position, func(a *piaware.Aircraft) {
+		if cs := strings.TrimSpace(fields[sbsCallsign]); cs != "" {
+			a.Flight = cs
+		}
+		if alt, ok := parseSBSInt(fields[sbsAltitude]); ok {
+			a.AltBaro = alt
+		}
+		if gs, ok := parseSBSFloat(fields[sbsGroundSpeed]); ok {
+			a.GS = gs
+		}
+		if track, ok := parseSBSFloat(fields[sbsTrack]); ok {
+			a.Track = track
+		}
+		if position {
+			a.Lat = lat
+			a.Lon = lon
+		}
+		if rate, ok := parseSBSInt(fields[sbsVerticalRate]); ok {
+			a.BaroRate = rate
+		}
+		if sq := strings.TrimSpace(fields[sbsSquawk]); sq != "" {
+			a.Squawk = sq
+		}
+		if emergency, ok := parseSBSFlag(fields[sbsEmergency]); ok {
+			if emergency {
+				a.Emergency = "general"
+			} else {
+				a.Emergency = "none"
+			}
+		}
+		if ground, ok := parseSBSFlag(fields[sbsOnGround]); ok {
+			a.OnGround = ground
+		}
+	})
+
+	return position, nil
+}
+
+// parseSBSFloat parses an optional numeric SBS field.
+func parseSBSFloat(s string) (float64, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+// parseSBSInt parses an optional integer SBS field.
+func parseSBSInt(s string) (int, bool) {
+	f, ok := parseSBSFloat(s)
+	if !ok {
+		return 0, false
+	}
+	return int(f), true
+}
+
+// parseSBSFlag parses an optional SBS flag, where -1 means set and 0 unset.
+func parseSBSFlag(s string) (bool, bool) {
+	switch strings.TrimSpace(s) {
+	case "-1", "1":
+		return true, true
+	case "0":
+		return false, true
+	default:
+		return false, false
+	}
+}
@@ -0,0 +1,96 @@
+package feed
+
+import (
+	"testing"
+)
+
+func TestApplySBSLine(t *testing.T) {
+	lines := []string{
+		"MSG,1,1,1,A1B2C3,1,2024/01/01,12:00:00.000,2024/01/01,12:00:00.000,UAL123  ,,,,,,,,,,,0",
+		"MSG,3,1,1,A1B2C3,1,2024/01/01,12:00:01.000,2024/01/01,12:00:01.000,,8000,,,37.61520,-122.38990,,,0,0,0,0",
+		"MSG,4,1,1,A1B2C3,1,2024/01/01,12:00:02.000,2024/01/01,12:00:02.000,,,251.3,271.5,,,-640,,,,,0",
+		"MSG,6,1,1,A1B2C3,1,2024/01/01,12:00:03.000,2024/01/01,12:00:03.000,,,,,,,,1200,0,0,0,0",
+	}
+
+	table := newAircraftTable()
+	positions := 0
+	for _, line := range lines {
+		position, err := applySBSLine(table, line)
+		if err != nil {
+			t.Fatalf("applySBSLine(%q) error = %v", line, err)
+		}
+		if position {
+			positions++
+		}
+	}
+
+	if positions != 1 {
+		t.Errorf("expected 1 position message, got %d", positions)
+	}
+
+	data := table.snapshot()
+	if len(data.Aircraft) != 1 {
+		t.Fatalf("expected 1 aircraft, got %d", len(data.Aircraft))
+	}
+
+	a := data.Aircraft[0]
+	if a.Hex != "a1b2c3" {
+		t.Errorf("expected hex a1b2c3, got %s", a.Hex)
+	}
+	if a.Flight != "UAL123" {
+		t.Errorf("expected flight UAL123, got %q", a.Flight)
+	}
+	if a.AltBaro != 8000 {
+		t.Errorf("expected altitude 8000, got %d", a.AltBaro)
+	}
+	if a.Lat != 37.6152 || a.Lon != -122.3899 {
+		t.Errorf("expected position 37.6152,-122.3899, got %v,%v", a.Lat, a.Lon)
+	}
+	if a.GS != 251.3 || a.Track != 271.5 || a.BaroRate != -640 {
+		t.Errorf("unexpected velocity gs=%v track=%v rate=%v", a.GS, a.Track, a.BaroRate)
+	}
+	if a.Squawk != "1200" {
+		t.Errorf("expected squawk 1200, got %q", a.Squawk)
+	}
+	if a.Messages != 4 {
+		t.Errorf("expected 4 messages, got %d", a.Messages)
+	}
+}
+
+func TestApplySBSLineOnGround(t *testing.T) {
+	table := newAircraftTable()
+	line := "MSG,2,1,1,ABCDEF,1,2024/01/01,12:00:00.000,2024/01/01,12:00:00.000,,,12,90,37.6,-122.3,,,,,,-1"
+	if _, err := applySBSLine(table, line); err != nil {
+		t.Fatalf("applySBSLine() error = %v", err)
+	}
+
+	data := table.snapshot()
+	if len(data.Aircraft) != 1 || !data.Aircraft[0].OnGround {
+		t.Fatalf("expected one aircraft on the ground, got %+v", data.Aircraft)
+	}
+}
+
+func TestApplySBSLineInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+	}{
+		{name: "empty", line: ""},
+		{name: "not a message", line: "STA,,1,1,A1B2C3,1,2024/01/01,12:00:00.000,2024/01/01,12:00:00.000,RM"},
+		{name: "too few fields", line: "MSG,3,1,1,A1B2C3"},
+		{name: "bad transmission type", line: "MSG,9,1,1,A1B2C3,1,,,,,,,,,,,,,,,,0"},
+		{name: "missing hex", line: "MSG,3,1,1,,1,,,,,,8000,,,37.6,-122.3,,,,,,0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := newAircraftTable()
+			if _, err := applySBSLine(table, tt.line); err == nil {
+				t.Errorf("expected error for %q", tt.line)
+			}
+			if len(table.snapshot().Aircraft) != 0 {
+				t.Error("expected invalid line to leave table empty")
+			}
+		})
+	}
+}
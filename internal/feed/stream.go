@@ -0,0 +1,152 @@
+package feed
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/logger"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+const (
+	// dialTimeout bounds a single connection attempt.
+	dialTimeout = 10 * time.Second
+	// minBackoff and maxBackoff bound the delay between reconnection attempts.
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+)
+
+// decodeFunc reads messages from r until it fails and applies them to the stream.
+type decodeFunc func(r io.Reader, s *Stream) error
+
+// Stream maintains aircraft state from a long-lived TCP feed such as the
+// BaseStation (SBS-1) output of dump1090/readsb. It reconnects on its own
+// whenever the connection drops.
+type Stream struct {
+	name    string
+	addr    string
+	decode  decodeFunc
+	table   *aircraftTable
+	modes   *modeSDecoder
+	updates chan struct{}
+	dial    func(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// newStream creates a stream that decodes the feed at addr with decode.
+func newStream(name, addr string, decode decodeFunc) *Stream {
+	dialer := &net.Dialer{Timeout: dialTimeout}
+	return &Stream{
+		name:    name,
+		addr:    addr,
+		decode:  decode,
+		table:   newAircraftTable(),
+		updates: make(chan struct{}, 1),
+		dial:    dialer.DialContext,
+	}
+}
+
+// Start connects to the feed in the background until ctx is cancelled.
+func (s *Stream) Start(ctx context.Context) {
+	go s.run(ctx)
+}
+
+// Fetch returns the aircraft currently tracked by the stream. The url is
+// ignored; it exists so Fetch matches the polling fetcher signature.
+func (s *Stream) Fetch(ctx context.Context, url string) ([]piaware.Aircraft, error) {
+	data, err := s.FetchData(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return data.Aircraft, nil
+}
+
+// FetchData returns the current aircraft state as an aircraft.json snapshot.
+func (s *Stream) FetchData(ctx context.Context, url string) (*piaware.Data, error) {
+	return s.table.snapshot(), nil
+}
+
+// Updates returns a channel that is signalled whenever an aircraft position
+// changes. Signals are coalesced, so a slow reader never blocks the stream.
+func (s *Stream) Updates() <-chan struct{} {
+	return s.updates
+}
+
+// notifyUpdate signals readers of Updates without blocking.
+func (s *Stream) notifyUpdate() {
+	select {
+	case s.updates <- struct{}{}:
+	default:
+	}
+}
+
+// run is the reconnect loop.
+func (s *Stream) run(ctx context.Context) {
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		err := s.connect(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		// A connection that stayed up for a while resets the backoff.
+		if time.Since(started) > maxBackoff {
+			backoff = minBackoff
+		}
+
+		logger.Warn("feed connection lost, reconnecting", map[string]interface{}{
+			"feed":    s.name,
+			"addr":    s.addr,
+			"error":   errString(err),
+			"backoff": backoff.String(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// connect dials the feed and decodes it until the connection fails.
+func (s *Stream) connect(ctx context.Context) error {
+	conn, err := s.dial(ctx, "tcp", s.addr)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", s.addr, err)
+	}
+
+	// Close the connection when the context is cancelled so decode returns.
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-done:
+		}
+		_ = conn.Close()
+	}()
+
+	logger.Info("feed connected", map[string]interface{}{
+		"feed": s.name,
+		"addr": s.addr,
+	})
+
+	return s.decode(conn, s)
+}
+
+// errString renders a possibly nil error for logging.
+func errString(err error) string {
+	if err == nil {
+		return "connection closed"
+	}
+	return err.Error()
+}
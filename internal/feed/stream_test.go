@@ -0,0 +1,85 @@
+package feed
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func TestAircraftTableExpiry(t *testing.T) {
+	table := newAircraftTable()
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	table.now = func() time.Time { return now }
+
+	table.update("abc123", false, func(a *piaware.Aircraft) { a.Flight = "TEST1" })
+
+	now = now.Add(10 * time.Second)
+	data := table.snapshot()
+	if len(data.Aircraft) != 1 {
+		t.Fatalf("expected 1 aircraft, got %d", len(data.Aircraft))
+	}
+	if data.Aircraft[0].Seen != 10 {
+		t.Errorf("expected seen 10, got %v", data.Aircraft[0].Seen)
+	}
+	if data.Aircraft[0].SeenPos != 0 {
+		t.Errorf("expected no position age without a position, got %v", data.Aircraft[0].SeenPos)
+	}
+
+	now = now.Add(expireAfter)
+	if data := table.snapshot(); len(data.Aircraft) != 0 {
+		t.Errorf("expected aircraft to expire, got %d", len(data.Aircraft))
+	}
+}
+
+func TestStreamReconnects(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer func() {
+		_ = ln.Close()
+	}()
+
+	lines := []string{
+		"MSG,3,1,1,AAAAAA,1,,,,,,5000,,,37.61,-122.38,,,,,,0\n",
+		"MSG,3,1,1,BBBBBB,1,,,,,,6000,,,37.62,-122.39,,,,,,0\n",
+	}
+
+	// Serve one line per connection, then drop it to force a reconnect.
+	go func() {
+		for _, line := range lines {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			_, _ = conn.Write([]byte(line))
+			_ = conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream := NewSBS(ln.Addr().String())
+	stream.Start(ctx)
+
+	deadline := time.After(10 * time.Second)
+	for {
+		aircraft, err := stream.Fetch(context.Background(), "")
+		if err != nil {
+			t.Fatalf("Fetch() error = %v", err)
+		}
+		if len(aircraft) == 2 {
+			break
+		}
+		select {
+		case <-deadline:
+			t.Fatalf("expected 2 aircraft after reconnect, got %d", len(aircraft))
+		case <-stream.Updates():
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package feed
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// expireAfter is how long an aircraft stays in the table without any message.
+const expireAfter = 60 * time.Second
+
+// tableEntry holds the accumulated state for a single aircraft.
+type tableEntry struct {
+	aircraft piaware.Aircraft
+	lastSeen time.Time
+	lastPos  time.Time
+}
+
+// aircraftTable accumulates aircraft state from streaming messages.
+type aircraftTable struct {
+	entries map[string]*tableEntry
+	now     func() time.Time
+	mutex   sync.RWMutex
+}
+
+// newAircraftTable creates an empty aircraft table.
+func newAircraftTable() *aircraftTable {
+	return &aircraftTable{
+		entries: make(map[string]*tableEntry),
+		now:     time.Now,
+	}
+}
+
+// update applies fn to the aircraft identified by hex, creating it if needed.
+// position indicates that fn updated the aircraft position.
+func (t *aircraftTable) update(hex string, position bool, fn func(a *piaware.Aircraft)) {
+	if hex == "" {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	e, ok := t.entries[hex]
+	if !ok {
+		e = &tableEntry{aircraft: piaware.Aircraft{Hex: hex}}
+		t.entries[hex] = e
+	}
+
+	now := t.now()
+	fn(&e.aircraft)
+	e.aircraft.Messages++
+	e.lastSeen = now
+	if position {
+		e.lastPos = now
+	}
+}
+
+// snapshot returns the current table contents in aircraft.json form and
+// drops aircraft that have not been heard from within expireAfter.
+func (t *aircraftTable) snapshot() *piaware.Data {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	now := t.now()
+	data := &piaware.Data{
+		Now:      float64(now.UnixNano()) / float64(time.Second),
+		Aircraft: make([]piaware.Aircraft, 0, len(t.entries)),
+	}
+
+	for hex, e := range t.entries {
+		if now.Sub(e.lastSeen) > expireAfter {
+			delete(t.entries, hex)
+			continue
+		}
+
+		a := e.aircraft
+		a.Seen = now.Sub(e.lastSeen).Seconds()
+		if !e.lastPos.IsZero() {
+			a.SeenPos = now.Sub(e.lastPos).Seconds()
+		}
+		data.Messages += a.Messages
+		data.Aircraft = append(data.Aircraft, a)
+	}
+
+	sort.Slice(data.Aircraft, func(i, j int) bool {
+		return data.Aircraft[i].Hex < data.Aircraft[j].Hex
+	})
+
+	return data
+}
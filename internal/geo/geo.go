@@ -0,0 +1,222 @@
+// Package geo holds the geodesy shared by the filters, predictions and
+// catalog: great-circle and ellipsoidal distances, bearings, destination
+// points and cross-track distances.
+package geo
+
+import "math"
+
+// EarthRadiusKm is the mean Earth radius used for spherical calculations.
+const EarthRadiusKm = 6371.0
+
+// WGS-84 ellipsoid, in kilometres.
+const (
+	// WGS84A is the semi-major (equatorial) axis.
+	WGS84A = 6378.137
+	// WGS84F is the flattening.
+	WGS84F = 1 / 298.257223563
+	// WGS84B is the semi-minor (polar) axis.
+	WGS84B = WGS84A * (1 - WGS84F)
+	// WGS84E2 is the square of the first eccentricity.
+	WGS84E2 = WGS84F * (2 - WGS84F)
+)
+
+const (
+	// vincentyMaxIterations bounds the Vincenty iteration, which does not
+	// converge for nearly antipodal points.
+	vincentyMaxIterations = 200
+
+	// boxMargin pads bounding boxes for the difference between the sphere
+	// and the ellipsoid, which is under 0.6%.
+	boxMargin = 0.01
+)
+
+// Mode selects how distances are calculated.
+type Mode string
+
+const (
+	// Spherical uses the haversine formula on a sphere of EarthRadiusKm.
+	// It is fast, and within about 0.5% of the ellipsoidal distance.
+	Spherical Mode = "spherical"
+	// Ellipsoidal uses Vincenty's formula on the WGS-84 ellipsoid, which is
+	// accurate to well under a metre.
+	Ellipsoidal Mode = "ellipsoidal"
+)
+
+// Valid reports whether m is Spherical or Ellipsoidal.
+func (m Mode) Valid() bool {
+	return m == Spherical || m == Ellipsoidal
+}
+
+// DistanceKm returns the distance between two points using the mode. Any
+// mode other than Ellipsoidal is spherical.
+func (m Mode) DistanceKm(lat1, lon1, lat2, lon2 float64) float64 {
+	if m == Ellipsoidal {
+		return VincentyKm(lat1, lon1, lat2, lon2)
+	}
+	return HaversineKm(lat1, lon1, lat2, lon2)
+}
+
+// HaversineKm returns the great-circle distance in kilometres between two
+// points.
+func HaversineKm(lat1, lon1, lat2, lon2 float64) float64 {
+	dLat := radians(lat2 - lat1)
+	dLon := radians(lon2 - lon1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(radians(lat1))*math.Cos(radians(lat2))*math.Sin(dLon/2)*math.Sin(dLon/2)
+	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+	return EarthRadiusKm * c
+}
+
+// VincentyKm returns the geodesic distance in kilometres between two points
+// on the WGS-84 ellipsoid using Vincenty's inverse formula. For nearly
+// antipodal points, where the formula does not converge, it falls back to
+// the haversine distance.
+func VincentyKm(lat1, lon1, lat2, lon2 float64) float64 {
+	l := radians(lon2 - lon1)
+	u1 := math.Atan((1 - WGS84F) * math.Tan(radians(lat1)))
+	u2 := math.Atan((1 - WGS84F) * math.Tan(radians(lat2)))
+	sinU1, cosU1 := math.Sin(u1), math.Cos(u1)
+	sinU2, cosU2 := math.Sin(u2), math.Cos(u2)
+
+	lambda := l
+	for i := 0; i < vincentyMaxIterations; i++ {
+		sinLambda, cosLambda := math.Sin(lambda), math.Cos(lambda)
+		sinSigma := math.Hypot(cosU2*sinLambda, cosU1*sinU2-sinU1*cosU2*cosLambda)
+		if sinSigma == 0 {
+			return 0 // coincident points
+		}
+		cosSigma := sinU1*sinU2 + cosU1*cosU2*cosLambda
+		sigma := math.Atan2(sinSigma, cosSigma)
+		sinAlpha := cosU1 * cosU2 * sinLambda / sinSigma
+		cos2Alpha := 1 - sinAlpha*sinAlpha
+		cos2SigmaM := 0.0
+		if cos2Alpha != 0 {
+			// Zero on the equator.
+			cos2SigmaM = cosSigma - 2*sinU1*sinU2/cos2Alpha
+		}
+		c := WGS84F / 16 * cos2Alpha * (4 + WGS84F*(4-3*cos2Alpha))
+		prev := lambda
+		lambda = l + (1-c)*WGS84F*sinAlpha*(sigma+c*sinSigma*(cos2SigmaM+c*cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)))
+		if math.Abs(lambda-prev) > 1e-12 {
+			continue
+		}
+
+		u2 := cos2Alpha * (WGS84A*WGS84A - WGS84B*WGS84B) / (WGS84B * WGS84B)
+		a := 1 + u2/16384*(4096+u2*(-768+u2*(320-175*u2)))
+		b := u2 / 1024 * (256 + u2*(-128+u2*(74-47*u2)))
+		deltaSigma := b * sinSigma * (cos2SigmaM + b/4*(cosSigma*(-1+2*cos2SigmaM*cos2SigmaM)-
+			b/6*cos2SigmaM*(-3+4*sinSigma*sinSigma)*(-3+4*cos2SigmaM*cos2SigmaM)))
+		return WGS84B * a * (sigma - deltaSigma)
+	}
+	return HaversineKm(lat1, lon1, lat2, lon2)
+}
+
+// BearingDeg returns the initial great-circle bearing in degrees, clockwise
+// from true north, from the first point to the second.
+func BearingDeg(lat1, lon1, lat2, lon2 float64) float64 {
+	phi1, phi2 := radians(lat1), radians(lat2)
+	dLon := radians(lon2 - lon1)
+	y := math.Sin(dLon) * math.Cos(phi2)
+	x := math.Cos(phi1)*math.Sin(phi2) - math.Sin(phi1)*math.Cos(phi2)*math.Cos(dLon)
+	return math.Mod(degrees(math.Atan2(y, x))+360, 360)
+}
+
+// Destination returns the point reached by travelling distanceKm along a
+// great circle from (lat, lon) on the initial bearing.
+func Destination(lat, lon, bearingDeg, distanceKm float64) (float64, float64) {
+	phi1, lambda1 := radians(lat), radians(lon)
+	theta := radians(bearingDeg)
+	delta := distanceKm / EarthRadiusKm
+
+	phi2 := math.Asin(math.Sin(phi1)*math.Cos(delta) + math.Cos(phi1)*math.Sin(delta)*math.Cos(theta))
+	lambda2 := lambda1 + math.Atan2(math.Sin(theta)*math.Sin(delta)*math.Cos(phi1), math.Cos(delta)-math.Sin(phi1)*math.Sin(phi2))
+	return degrees(phi2), wrapLon(degrees(lambda2))
+}
+
+// CrossTrackKm returns the distance of (lat, lon) from the great circle
+// through the start and end points. It is positive to the right of the path
+// from start to end and negative to the left.
+func CrossTrackKm(lat, lon, startLat, startLon, endLat, endLon float64) float64 {
+	delta13 := HaversineKm(startLat, startLon, lat, lon) / EarthRadiusKm
+	theta13 := radians(BearingDeg(startLat, startLon, lat, lon))
+	theta12 := radians(BearingDeg(startLat, startLon, endLat, endLon))
+	return math.Asin(math.Sin(delta13)*math.Sin(theta13-theta12)) * EarthRadiusKm
+}
+
+// Box is a latitude/longitude bounding box. MinLon is greater than MaxLon
+// when the box crosses the antimeridian.
+type Box struct {
+	MinLat float64
+	MinLon float64
+	MaxLat float64
+	MaxLon float64
+}
+
+// BoundingBox returns a box containing every point within radiusKm of
+// (lat, lon). The radius is padded by boxMargin so the box also contains
+// the points within radiusKm on the ellipsoid. A radius reaching a pole, or
+// an infinite radius, spans every longitude.
+func BoundingBox(lat, lon, radiusKm float64) Box {
+	delta := radiusKm * (1 + boxMargin) / EarthRadiusKm
+	if delta >= math.Pi {
+		return Box{MinLat: -90, MinLon: -180, MaxLat: 90, MaxLon: 180}
+	}
+	box := Box{
+		MinLat: math.Max(lat-degrees(delta), -90),
+		MaxLat: math.Min(lat+degrees(delta), 90),
+		MinLon: -180,
+		MaxLon: 180,
+	}
+	if box.MinLat == -90 || box.MaxLat == 90 {
+		return box
+	}
+	// The widest longitude of a circle on the sphere.
+	sinDLon := math.Sin(delta) / math.Cos(radians(lat))
+	if sinDLon >= 1 {
+		return box
+	}
+	dLon := degrees(math.Asin(sinDLon))
+	box.MinLon = wrapLon(lon - dLon)
+	box.MaxLon = wrapLon(lon + dLon)
+	return box
+}
+
+// Contains reports whether the point is inside the box.
+func (b Box) Contains(lat, lon float64) bool {
+	if lat < b.MinLat || lat > b.MaxLat {
+		return false
+	}
+	if b.MinLon <= b.MaxLon {
+		return lon >= b.MinLon && lon <= b.MaxLon
+	}
+	return lon >= b.MinLon || lon <= b.MaxLon
+}
+
+// ECEF converts a position on the WGS-84 ellipsoid, with its height above
+// the ellipsoid in kilometres, to earth-centred, earth-fixed coordinates in
+// kilometres.
+func ECEF(lat, lon, heightKm float64) (x, y, z float64) {
+	phi := radians(lat)
+	lambda := radians(lon)
+	sinPhi := math.Sin(phi)
+	n := WGS84A / math.Sqrt(1-WGS84E2*sinPhi*sinPhi)
+	x = (n + heightKm) * math.Cos(phi) * math.Cos(lambda)
+	y = (n + heightKm) * math.Cos(phi) * math.Sin(lambda)
+	z = (n*(1-WGS84E2) + heightKm) * sinPhi
+	return x, y, z
+}
+
+// wrapLon wraps a longitude into [-180, 180].
+func wrapLon(lon float64) float64 {
+	if lon < -180 || lon > 180 {
+		return math.Mod(lon+540, 360) - 180
+	}
+	return lon
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
@@ -0,0 +1,224 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDistanceKm(t *testing.T) {
+	tests := []struct {
+		name        string
+		lat1        float64
+		lon1        float64
+		lat2        float64
+		lon2        float64
+		spherical   float64
+		ellipsoidal float64
+		tolerance   float64
+	}{
+		{
+			name:      "same point",
+			lat1:      37.6213,
+			lon1:      -122.3790,
+			lat2:      37.6213,
+			lon2:      -122.3790,
+			tolerance: 0.001,
+		},
+		{
+			name:        "one tenth of a degree north",
+			lat1:        37.6213,
+			lon1:        -122.3790,
+			lat2:        37.7213,
+			lon2:        -122.3790,
+			spherical:   11.119,
+			ellipsoidal: 11.099,
+			tolerance:   0.001,
+		},
+		{
+			name:        "Flinders Peak to Buninyong",
+			lat1:        -37.95103342,
+			lon1:        144.42486789,
+			lat2:        -37.65282114,
+			lon2:        143.92649554,
+			spherical:   54.925,
+			ellipsoidal: 54.972271,
+			tolerance:   0.01,
+		},
+		{
+			name:        "pole to pole",
+			lat1:        90,
+			lon1:        0,
+			lat2:        -90,
+			lon2:        0,
+			spherical:   20015.087,
+			ellipsoidal: 20003.931,
+			tolerance:   0.01,
+		},
+		{
+			// Vincenty does not converge here and falls back to haversine.
+			name:        "antipodal on the equator",
+			lat1:        0,
+			lon1:        0,
+			lat2:        0,
+			lon2:        180,
+			spherical:   20015.087,
+			ellipsoidal: 20015.087,
+			tolerance:   0.01,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Spherical.DistanceKm(tt.lat1, tt.lon1, tt.lat2, tt.lon2); math.Abs(got-tt.spherical) > tt.tolerance {
+				t.Errorf("spherical distance = %.4f, want %.4f", got, tt.spherical)
+			}
+			if got := Ellipsoidal.DistanceKm(tt.lat1, tt.lon1, tt.lat2, tt.lon2); math.Abs(got-tt.ellipsoidal) > tt.tolerance {
+				t.Errorf("ellipsoidal distance = %.4f, want %.4f", got, tt.ellipsoidal)
+			}
+		})
+	}
+
+	if got, want := Mode("").DistanceKm(0, 0, 1, 1), HaversineKm(0, 0, 1, 1); got != want {
+		t.Errorf("empty mode distance = %v, want haversine %v", got, want)
+	}
+}
+
+func TestBearingDeg(t *testing.T) {
+	tests := []struct {
+		name string
+		lat2 float64
+		lon2 float64
+		want float64
+	}{
+		{"north", 41, -74, 0},
+		{"east", 40, -73, 89.68},
+		{"south", 39, -74, 180},
+		{"west", 40, -75, 270.32},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BearingDeg(40, -74, tt.lat2, tt.lon2); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("BearingDeg() = %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDestination(t *testing.T) {
+	for _, bearing := range []float64{0, 45, 135, 270} {
+		lat, lon := Destination(40, -74, bearing, 25)
+		if d := HaversineKm(40, -74, lat, lon); math.Abs(d-25) > 1e-6 {
+			t.Errorf("bearing %v: destination is %.6f km away, want 25", bearing, d)
+		}
+		if b := BearingDeg(40, -74, lat, lon); math.Abs(b-bearing) > 1e-6 {
+			t.Errorf("bearing %v: destination is on bearing %.6f", bearing, b)
+		}
+	}
+
+	// Crossing the antimeridian wraps the longitude.
+	if _, lon := Destination(0, 179.9, 90, 50); lon > -179 || lon < -180 {
+		t.Errorf("expected a wrapped longitude, got %v", lon)
+	}
+}
+
+func TestCrossTrackKm(t *testing.T) {
+	// A path due north along the -74 meridian.
+	tests := []struct {
+		name string
+		lat  float64
+		lon  float64
+		want float64
+	}{
+		{"on the path", 40.5, -74, 0},
+		{"east is right", 40.5, -73.9, 8.45},
+		{"west is left", 40.5, -74.1, -8.45},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := CrossTrackKm(tt.lat, tt.lon, 40, -74, 41, -74); math.Abs(got-tt.want) > 0.01 {
+				t.Errorf("CrossTrackKm() = %.3f, want %.3f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name     string
+		lat      float64
+		lon      float64
+		radiusKm float64
+		full     bool
+	}{
+		{"mid latitude", 40, -74, 25, false},
+		{"high latitude", 78, 15, 100, false},
+		{"across the antimeridian", -17, 179.9, 50, false},
+		{"over the pole", 89.9, 0, 50, true},
+		{"whole earth", 0, 0, math.Inf(1), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			box := BoundingBox(tt.lat, tt.lon, tt.radiusKm)
+			if full := box.MinLon == -180 && box.MaxLon == 180; full != tt.full {
+				t.Errorf("box %+v spans every longitude = %v, want %v", box, full, tt.full)
+			}
+			if math.IsInf(tt.radiusKm, 1) {
+				return
+			}
+			for bearing := 0.0; bearing < 360; bearing += 5 {
+				// Points just inside the radius on the sphere, and the
+				// ellipsoid, are inside the box.
+				lat, lon := Destination(tt.lat, tt.lon, bearing, tt.radiusKm*0.999)
+				if !box.Contains(lat, lon) {
+					t.Errorf("box %+v does not contain %v, %v on bearing %v", box, lat, lon, bearing)
+				}
+				lat, lon = Destination(tt.lat, tt.lon, bearing, tt.radiusKm*1.004)
+				if VincentyKm(tt.lat, tt.lon, lat, lon) <= tt.radiusKm && !box.Contains(lat, lon) {
+					t.Errorf("box %+v does not contain ellipsoidal neighbour %v, %v", box, lat, lon)
+				}
+				// Points well outside are not.
+				lat, lon = Destination(tt.lat, tt.lon, bearing, tt.radiusKm*3)
+				if !tt.full && bearing == 0 && box.Contains(lat, lon) {
+					t.Errorf("box %+v contains distant point %v, %v", box, lat, lon)
+				}
+			}
+		})
+	}
+}
+
+func TestECEF(t *testing.T) {
+	tests := []struct {
+		name                string
+		lat, lon, heightKm  float64
+		wantX, wantY, wantZ float64
+	}{
+		{"equator and prime meridian", 0, 0, 0, WGS84A, 0, 0},
+		{"equator at 90E, 1 km up", 0, 90, 1, 0, WGS84A + 1, 0},
+		{"north pole", 90, 0, 0, 0, 0, WGS84B},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			x, y, z := ECEF(tt.lat, tt.lon, tt.heightKm)
+			if math.Abs(x-tt.wantX) > 1e-6 || math.Abs(y-tt.wantY) > 1e-6 || math.Abs(z-tt.wantZ) > 1e-6 {
+				t.Errorf("ECEF() = %v, %v, %v; want %v, %v, %v", x, y, z, tt.wantX, tt.wantY, tt.wantZ)
+			}
+		})
+	}
+}
+
+func TestModeValid(t *testing.T) {
+	for _, m := range []Mode{Spherical, Ellipsoidal} {
+		if !m.Valid() {
+			t.Errorf("expected %q to be valid", m)
+		}
+	}
+	for _, m := range []Mode{"", "elipsoidal"} {
+		if m.Valid() {
+			t.Errorf("expected %q to be invalid", m)
+		}
+	}
+}
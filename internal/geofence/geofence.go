@@ -0,0 +1,212 @@
+// Package geofence matches aircraft positions against polygon zones loaded
+// from GeoJSON, such as an approach corridor or a property boundary.
+package geofence
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// point is a longitude/latitude pair in GeoJSON order.
+type point [2]float64
+
+// polygon is an exterior ring followed by any holes.
+type polygon [][]point
+
+// Zone is a named area with its own altitude band.
+type Zone struct {
+	Name string
+	// AltitudeMin and AltitudeMax bound the altitude (ft) of aircraft
+	// matched in the zone.
+	AltitudeMin int
+	AltitudeMax int
+	polygons    []polygon
+}
+
+// Contains reports whether the aircraft is inside the zone and its altitude
+// (ft) is inside the zone's altitude band.
+func (z *Zone) Contains(a piaware.Aircraft, altitude int) bool {
+	if altitude < z.AltitudeMin || altitude > z.AltitudeMax {
+		return false
+	}
+	for _, p := range z.polygons {
+		if p.contains(point{a.Lon, a.Lat}) {
+			return true
+		}
+	}
+	return false
+}
+
+// Fence is a set of zones.
+type Fence struct {
+	Zones []Zone
+	// Altitude returns the altitude compared against zone altitude bands.
+	// When nil, the barometric altitude is used.
+	Altitude func(piaware.Aircraft) int
+}
+
+// Match returns the name of the first zone containing the aircraft.
+func (f *Fence) Match(a piaware.Aircraft) (string, bool) {
+	altitude := a.AltBaro
+	if f.Altitude != nil {
+		altitude = f.Altitude(a)
+	}
+	for i := range f.Zones {
+		if f.Zones[i].Contains(a, altitude) {
+			return f.Zones[i].Name, true
+		}
+	}
+	return "", false
+}
+
+// Load reads zones from a GeoJSON FeatureCollection or single Feature with
+// Polygon or MultiPolygon geometries. Each feature is one zone, named by its
+// "name" property. Its "altitude_min" and "altitude_max" properties (ft) set
+// the altitude band; without them a zone has altMin as its floor (zero
+// meaning no floor) and altMax as its ceiling.
+func Load(path string, altMin, altMax int) (*Fence, error) {
+	// #nosec G304 -- path is supplied by the operator
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read geofence file: %w", err)
+	}
+	fence, err := Parse(data, altMin, altMax)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return fence, nil
+}
+
+// geoJSON covers the parts of a FeatureCollection or Feature that are used.
+type geoJSON struct {
+	Type       string     `json:"type"`
+	Features   []geoJSON  `json:"features"`
+	Geometry   *geometry  `json:"geometry"`
+	Properties properties `json:"properties"`
+}
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+type properties struct {
+	Name        string `json:"name"`
+	AltitudeMin *int   `json:"altitude_min"`
+	AltitudeMax *int   `json:"altitude_max"`
+}
+
+// Parse parses GeoJSON zones; see Load.
+func Parse(data []byte, altMin, altMax int) (*Fence, error) {
+	var doc geoJSON
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode GeoJSON: %w", err)
+	}
+
+	var features []geoJSON
+	switch doc.Type {
+	case "FeatureCollection":
+		features = doc.Features
+	case "Feature":
+		features = []geoJSON{doc}
+	default:
+		return nil, fmt.Errorf("unsupported GeoJSON type %q, expected FeatureCollection or Feature", doc.Type)
+	}
+
+	fence := &Fence{}
+	for i, feature := range features {
+		zone, err := parseZone(feature, altMin, altMax)
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %w", i, err)
+		}
+		if zone.Name == "" {
+			zone.Name = fmt.Sprintf("zone-%d", i+1)
+		}
+		fence.Zones = append(fence.Zones, zone)
+	}
+	if len(fence.Zones) == 0 {
+		return nil, errors.New("no features found")
+	}
+	return fence, nil
+}
+
+func parseZone(feature geoJSON, altMin, altMax int) (Zone, error) {
+	zone := Zone{
+		Name:        feature.Properties.Name,
+		AltitudeMin: altMin,
+		AltitudeMax: altMax,
+	}
+	if altMin == 0 {
+		zone.AltitudeMin = math.MinInt
+	}
+	if feature.Properties.AltitudeMin != nil {
+		zone.AltitudeMin = *feature.Properties.AltitudeMin
+	}
+	if feature.Properties.AltitudeMax != nil {
+		zone.AltitudeMax = *feature.Properties.AltitudeMax
+	}
+	if zone.AltitudeMin > zone.AltitudeMax {
+		return Zone{}, fmt.Errorf("altitude_min %d is above altitude_max %d", zone.AltitudeMin, zone.AltitudeMax)
+	}
+
+	if feature.Geometry == nil {
+		return Zone{}, errors.New("missing geometry")
+	}
+	switch feature.Geometry.Type {
+	case "Polygon":
+		var p polygon
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &p); err != nil {
+			return Zone{}, fmt.Errorf("invalid polygon coordinates: %w", err)
+		}
+		zone.polygons = []polygon{p}
+	case "MultiPolygon":
+		if err := json.Unmarshal(feature.Geometry.Coordinates, &zone.polygons); err != nil {
+			return Zone{}, fmt.Errorf("invalid multipolygon coordinates: %w", err)
+		}
+	default:
+		return Zone{}, fmt.Errorf("unsupported geometry type %q, expected Polygon or MultiPolygon", feature.Geometry.Type)
+	}
+
+	for _, p := range zone.polygons {
+		for _, ring := range p {
+			if len(ring) < 4 {
+				return Zone{}, errors.New("polygon rings need at least four positions")
+			}
+		}
+	}
+	return zone, nil
+}
+
+// contains reports whether pt is inside the exterior ring and outside every
+// hole.
+func (p polygon) contains(pt point) bool {
+	if len(p) == 0 || !inRing(p[0], pt) {
+		return false
+	}
+	for _, hole := range p[1:] {
+		if inRing(hole, pt) {
+			return false
+		}
+	}
+	return true
+}
+
+// inRing is the even-odd ray casting test. Coordinates are treated as planar,
+// which is accurate for zones of a few tens of kilometres away from the
+// antimeridian and poles.
+func inRing(ring []point, pt point) bool {
+	inside := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		a, b := ring[i], ring[j]
+		if (a[1] > pt[1]) != (b[1] > pt[1]) &&
+			pt[0] < (b[0]-a[0])*(pt[1]-a[1])/(b[1]-a[1])+a[0] {
+			inside = !inside
+		}
+	}
+	return inside
+}
@@ -0,0 +1,117 @@
+package geofence
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+const testZones = `{
+  "type": "FeatureCollection",
+  "features": [
+    {
+      "type": "Feature",
+      "properties": {"name": "approach corridor", "altitude_min": 500, "altitude_max": 4000},
+      "geometry": {
+        "type": "Polygon",
+        "coordinates": [
+          [[-74.10, 40.60], [-74.00, 40.60], [-74.00, 40.70], [-74.10, 40.70], [-74.10, 40.60]],
+          [[-74.06, 40.64], [-74.04, 40.64], [-74.04, 40.66], [-74.06, 40.66], [-74.06, 40.64]]
+        ]
+      }
+    },
+    {
+      "type": "Feature",
+      "properties": {},
+      "geometry": {
+        "type": "MultiPolygon",
+        "coordinates": [
+          [[[-73.90, 40.60], [-73.80, 40.60], [-73.80, 40.70], [-73.90, 40.60]]],
+          [[[-73.70, 40.60], [-73.60, 40.60], [-73.60, 40.70], [-73.70, 40.70], [-73.70, 40.60]]]
+        ]
+      }
+    }
+  ]
+}`
+
+func TestFenceMatch(t *testing.T) {
+	fence, err := Parse([]byte(testZones), 0, 10000)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(fence.Zones) != 2 || fence.Zones[1].Name != "zone-2" {
+		t.Fatalf("unexpected zones %+v", fence.Zones)
+	}
+
+	tests := []struct {
+		name     string
+		aircraft piaware.Aircraft
+		zone     string
+	}{
+		{"inside corridor", piaware.Aircraft{Lat: 40.62, Lon: -74.08, AltBaro: 2000}, "approach corridor"},
+		{"below corridor floor", piaware.Aircraft{Lat: 40.62, Lon: -74.08, AltBaro: 300}, ""},
+		{"above corridor ceiling", piaware.Aircraft{Lat: 40.62, Lon: -74.08, AltBaro: 5000}, ""},
+		{"in corridor hole", piaware.Aircraft{Lat: 40.65, Lon: -74.05, AltBaro: 2000}, ""},
+		{"inside triangle", piaware.Aircraft{Lat: 40.62, Lon: -73.82, AltBaro: 9000}, "zone-2"},
+		{"outside triangle", piaware.Aircraft{Lat: 40.68, Lon: -73.88, AltBaro: 9000}, ""},
+		{"second polygon", piaware.Aircraft{Lat: 40.65, Lon: -73.65, AltBaro: 0, OnGround: true}, "zone-2"},
+		{"above default ceiling", piaware.Aircraft{Lat: 40.65, Lon: -73.65, AltBaro: 12000}, ""},
+		{"outside everything", piaware.Aircraft{Lat: 41.0, Lon: -74.0, AltBaro: 2000}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			zone, ok := fence.Match(tt.aircraft)
+			if zone != tt.zone || ok != (tt.zone != "") {
+				t.Errorf("Match() = %q, %v; want %q", zone, ok, tt.zone)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+		want string
+	}{
+		{"not json", `{`, "failed to decode"},
+		{"bare geometry", `{"type": "Polygon", "coordinates": []}`, "unsupported GeoJSON type"},
+		{"no features", `{"type": "FeatureCollection", "features": []}`, "no features"},
+		{"point", `{"type": "Feature", "geometry": {"type": "Point", "coordinates": [0, 0]}}`, "unsupported geometry type"},
+		{"missing geometry", `{"type": "Feature", "properties": {"name": "x"}}`, "missing geometry"},
+		{"short ring", `{"type": "Feature", "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [0, 0]]]}}`, "at least four"},
+		{"inverted band", `{"type": "Feature", "properties": {"altitude_min": 5000, "altitude_max": 1000}, "geometry": {"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 0]]]}}`, "above altitude_max"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.doc), 0, 10000)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "zones.geojson")
+	if err := os.WriteFile(path, []byte(testZones), 0o600); err != nil {
+		t.Fatalf("write zones: %v", err)
+	}
+
+	fence, err := Load(path, 0, 10000)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(fence.Zones) != 2 {
+		t.Errorf("expected 2 zones, got %d", len(fence.Zones))
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.geojson"), 0, 10000); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,103 @@
+// Package geometry computes where an aircraft appears in the sky from a
+// ground observer: its azimuth, elevation angle and slant range.
+package geometry
+
+import (
+	"math"
+
+	"github.com/benvon/whats-flying-over-me/internal/geo"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+const feetToKm = 0.0003048
+
+// compassPoints are the 16 compass points, clockwise from north.
+var compassPoints = []string{
+	"N", "NNE", "NE", "ENE", "E", "ESE", "SE", "SSE",
+	"S", "SSW", "SW", "WSW", "W", "WNW", "NW", "NNW",
+}
+
+// Config holds observer settings.
+type Config struct {
+	// ElevationFt is the observer's ground elevation above mean sea level.
+	ElevationFt float64
+	// MinElevationDeg raises an alert for aircraft at least this high in
+	// the sky. Zero disables the trigger.
+	MinElevationDeg float64
+}
+
+// Observer is a location on the ground.
+type Observer struct {
+	Lat         float64
+	Lon         float64
+	ElevationFt float64
+	// Mode selects how ground distances from the observer are measured.
+	Mode geo.Mode
+}
+
+// DistanceKm returns the ground distance from the observer to a point.
+func (o Observer) DistanceKm(lat, lon float64) float64 {
+	return o.Mode.DistanceKm(o.Lat, o.Lon, lat, lon)
+}
+
+// Look is the direction and distance from an observer to an aircraft.
+type Look struct {
+	// AzimuthDeg is the true bearing, clockwise from north.
+	AzimuthDeg float64
+	// ElevationDeg is the angle above the horizon; negative below it.
+	ElevationDeg float64
+	// SlantRangeKm is the straight-line distance.
+	SlantRangeKm float64
+	// Direction is the compass point of the azimuth, such as "NE".
+	Direction string
+}
+
+// Look returns the look angles from the observer to a point at altitudeFt
+// above mean sea level.
+func (o Observer) Look(lat, lon, altitudeFt float64) Look {
+	ox, oy, oz := geo.ECEF(o.Lat, o.Lon, o.ElevationFt*feetToKm)
+	px, py, pz := geo.ECEF(lat, lon, altitudeFt*feetToKm)
+	dx, dy, dz := px-ox, py-oy, pz-oz
+
+	// Rotate into the observer's local east/north/up frame.
+	phi := o.Lat * math.Pi / 180
+	lambda := o.Lon * math.Pi / 180
+	sinPhi, cosPhi := math.Sin(phi), math.Cos(phi)
+	sinLambda, cosLambda := math.Sin(lambda), math.Cos(lambda)
+	east := -sinLambda*dx + cosLambda*dy
+	north := -sinPhi*cosLambda*dx - sinPhi*sinLambda*dy + cosPhi*dz
+	up := cosPhi*cosLambda*dx + cosPhi*sinLambda*dy + sinPhi*dz
+
+	azimuth := math.Mod(math.Atan2(east, north)*180/math.Pi+360, 360)
+	return Look{
+		AzimuthDeg:   azimuth,
+		ElevationDeg: math.Atan2(up, math.Hypot(east, north)) * 180 / math.Pi,
+		SlantRangeKm: math.Sqrt(dx*dx + dy*dy + dz*dz),
+		Direction:    Compass(azimuth),
+	}
+}
+
+// LookAt returns the look angles from the observer to an aircraft. The
+// geometric altitude is used when reported, and the barometric altitude
+// otherwise; aircraft on the ground are taken to be at the observer's
+// elevation.
+func (o Observer) LookAt(a piaware.Aircraft) Look {
+	altitude := float64(a.AltBaro)
+	switch {
+	case a.OnGround:
+		altitude = o.ElevationFt
+	case a.AltGeom != 0:
+		altitude = float64(a.AltGeom)
+	}
+	return o.Look(a.Lat, a.Lon, altitude)
+}
+
+// Compass returns the 16-point compass direction of an azimuth in degrees.
+func Compass(azimuthDeg float64) string {
+	azimuthDeg = math.Mod(azimuthDeg, 360)
+	if azimuthDeg < 0 {
+		azimuthDeg += 360
+	}
+	i := int(math.Round(azimuthDeg/22.5)) % len(compassPoints)
+	return compassPoints[i]
+}
@@ -0,0 +1,91 @@
+package geometry
+
+import (
+	"math"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func TestObserverLook(t *testing.T) {
+	observer := Observer{Lat: 40.0, Lon: -74.0, ElevationFt: 100}
+
+	tests := []struct {
+		name      string
+		lat       float64
+		lon       float64
+		altFt     float64
+		azimuth   float64
+		elevation float64
+		rangeKm   float64
+		direction string
+	}{
+		// 10 km north at 10,100 ft: ~3.05 km above the observer.
+		{"north", 40.0 + 10/111.03, -74.0, 10100, 0, 16.9, 10.45, "N"},
+		{"east", 40.0, -74.0 + 10/85.39, 10100, 90, 16.9, 10.45, "E"},
+		{"south west", 40.0 - 7.07/111.03, -74.0 - 7.07/85.39, 10100, 225, 16.9, 10.45, "SW"},
+		{"overhead", 40.0, -74.0, 5100, 0, 90, 1.524, "N"},
+		{"below the horizon", 40.0 + 300/111.03, -74.0, 100, 0, -1.35, 300, "N"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			look := observer.Look(tt.lat, tt.lon, tt.altFt)
+			if tt.elevation != 90 && math.Abs(angleDiff(look.AzimuthDeg, tt.azimuth)) > 0.5 {
+				t.Errorf("azimuth = %.2f, want %.2f", look.AzimuthDeg, tt.azimuth)
+			}
+			if math.Abs(look.ElevationDeg-tt.elevation) > 0.3 {
+				t.Errorf("elevation = %.2f, want %.2f", look.ElevationDeg, tt.elevation)
+			}
+			if math.Abs(look.SlantRangeKm-tt.rangeKm)/tt.rangeKm > 0.01 {
+				t.Errorf("slant range = %.3f km, want %.3f", look.SlantRangeKm, tt.rangeKm)
+			}
+			if tt.elevation != 90 && look.Direction != tt.direction {
+				t.Errorf("direction = %q, want %q", look.Direction, tt.direction)
+			}
+		})
+	}
+}
+
+func TestObserverLookAt(t *testing.T) {
+	observer := Observer{Lat: 40.0, Lon: -74.0, ElevationFt: 500}
+	lat := 40.0 + 5/111.03
+
+	geom := observer.LookAt(piaware.Aircraft{Lat: lat, Lon: -74.0, AltBaro: 3000, AltGeom: 3500})
+	baro := observer.LookAt(piaware.Aircraft{Lat: lat, Lon: -74.0, AltBaro: 3500})
+	if math.Abs(geom.ElevationDeg-baro.ElevationDeg) > 0.01 {
+		t.Errorf("expected geometric altitude to be preferred: %.2f vs %.2f", geom.ElevationDeg, baro.ElevationDeg)
+	}
+
+	ground := observer.LookAt(piaware.Aircraft{Lat: lat, Lon: -74.0, OnGround: true})
+	if ground.ElevationDeg > 0 || ground.ElevationDeg < -0.1 {
+		t.Errorf("expected ground traffic near the horizon, got %.2f", ground.ElevationDeg)
+	}
+}
+
+func TestCompass(t *testing.T) {
+	tests := []struct {
+		azimuth float64
+		want    string
+	}{
+		{0, "N"},
+		{11, "N"},
+		{12, "NNE"},
+		{45, "NE"},
+		{100, "E"},
+		{200, "SSW"},
+		{349, "N"},
+		{-45, "NW"},
+		{720 + 90, "E"},
+	}
+
+	for _, tt := range tests {
+		if got := Compass(tt.azimuth); got != tt.want {
+			t.Errorf("Compass(%v) = %q, want %q", tt.azimuth, got, tt.want)
+		}
+	}
+}
+
+func angleDiff(a, b float64) float64 {
+	return math.Mod(a-b+540, 360) - 180
+}
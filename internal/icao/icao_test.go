@@ -0,0 +1,86 @@
+package icao
+
+import (
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func TestLookup(t *testing.T) {
+	tests := []struct {
+		name string
+		hex  string
+		want Allocation
+		ok   bool
+	}{
+		{"United States civil", "a1b2c3", Allocation{Country: "United States"}, true},
+		{"United States military", "AE01CE", Allocation{Country: "United States", Military: true}, true},
+		{"United Kingdom civil", "406a93", Allocation{Country: "United Kingdom"}, true},
+		{"Royal Air Force", "43c6f1", Allocation{Country: "United Kingdom", Military: true}, true},
+		{"Germany", "3c6444", Allocation{Country: "Germany"}, true},
+		{"Luftwaffe", "3f4567", Allocation{Country: "Germany", Military: true}, true},
+		{"Hong Kong inside China", "789123", Allocation{Country: "Hong Kong"}, true},
+		{"China around Hong Kong", "78a000", Allocation{Country: "China"}, true},
+		{"first address of a block", "c00000", Allocation{Country: "Canada"}, true},
+		{"last address of a block", "c3ffff", Allocation{Country: "Canada", Military: true}, true},
+		{"small block", "4d2001", Allocation{Country: "Malta"}, true},
+		{"unallocated", "fff000", Allocation{}, false},
+		{"gap between blocks", "005000", Allocation{}, false},
+		{"non-ICAO address", "~a1b2c3", Allocation{}, false},
+		{"not hex", "zzzzzz", Allocation{}, false},
+		{"too long", "1000000", Allocation{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Lookup(tt.hex)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Lookup(%q) = %+v, %v; want %+v, %v", tt.hex, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestBlocksDoNotOverlapUnlessNested(t *testing.T) {
+	for name, blocks := range map[string][]block{"country": countryBlocks, "military": militaryBlocks} {
+		for i, b := range blocks {
+			if b.start > b.end {
+				t.Errorf("%s block %s starts after it ends", name, b.name)
+			}
+			if i == 0 {
+				continue
+			}
+			prev := blocks[i-1]
+			nested := b.start >= prev.start && b.end <= prev.end
+			if b.start <= prev.end && !nested {
+				t.Errorf("%s blocks %s and %s overlap", name, prev.name, b.name)
+			}
+		}
+	}
+
+	for _, b := range militaryBlocks {
+		for _, addr := range []uint32{b.start, b.end} {
+			if country, ok := find(countryBlocks, addr); !ok || country.name != b.name {
+				t.Errorf("military block %06X of %s is allocated to %q", addr, b.name, country.name)
+			}
+		}
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	aircraft := []piaware.Aircraft{
+		{Hex: "ae01ce"},
+		{Hex: "a1b2c3"},
+		{Hex: "~123456"},
+	}
+	Enrich(aircraft)
+	if a := aircraft[0]; a.Country != "United States" || !a.Military {
+		t.Errorf("unexpected military aircraft %+v", a)
+	}
+	if a := aircraft[1]; a.Country != "United States" || a.Military {
+		t.Errorf("unexpected civil aircraft %+v", a)
+	}
+	if a := aircraft[2]; a.Country != "" {
+		t.Errorf("expected no country for a non-ICAO address, got %+v", a)
+	}
+}
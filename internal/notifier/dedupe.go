@@ -18,19 +18,29 @@ type AlertRecord struct {
 // Deduplicator prevents duplicate alerts for the same aircraft within a time window.
 type Deduplicator struct {
 	cfg     config.AlertDedupeConfig
-	records map[string]*AlertRecord // key: tailNumber + ":" + transponder
+	records map[string]*AlertRecord // key: [zone + "|"] + tailNumber + ":" + transponder
+	now     func() time.Time
 	mutex   sync.RWMutex
 }
 
 // NewDeduplicator creates a new deduplicator.
 func NewDeduplicator(cfg config.AlertDedupeConfig) *Deduplicator {
+	return NewDeduplicatorWithClock(cfg, time.Now)
+}
+
+// NewDeduplicatorWithClock creates a deduplicator that measures blockout
+// periods with now instead of the wall clock (useful for replays).
+func NewDeduplicatorWithClock(cfg config.AlertDedupeConfig, now func() time.Time) *Deduplicator {
 	return &Deduplicator{
 		cfg:     cfg,
 		records: make(map[string]*AlertRecord),
+		now:     now,
 	}
 }
 
 // ShouldAlert determines if an alert should be sent for the given aircraft.
+// Aircraft are deduplicated separately in each zone, so an aircraft moving
+// into another zone is alerted there too.
 func (d *Deduplicator) ShouldAlert(aircraft piaware.NearbyAircraft) bool {
 	if !d.cfg.Enabled {
 		return true
@@ -39,11 +49,15 @@ func (d *Deduplicator) ShouldAlert(aircraft piaware.NearbyAircraft) bool {
 	d.mutex.Lock()
 	defer d.mutex.Unlock()
 
-	now := time.Now()
+	now := d.now()
 
-	// Create keys for both tail number and transponder
-	tailKey := d.makeKey(aircraft.Flight, aircraft.Hex)
-	transponderKey := d.makeKey("", aircraft.Hex)
+	// Create keys for both tail number and transponder, within the zone
+	zone := ""
+	if aircraft.Zone != "" {
+		zone = aircraft.Zone + "|"
+	}
+	tailKey := zone + d.makeKey(aircraft.Flight, aircraft.Hex)
+	transponderKey := zone + d.makeKey("", aircraft.Hex)
 
 	// Check if we should alert based on tail number
 	shouldAlertByTail := true
@@ -137,6 +137,35 @@ func TestShouldAlertAfterBlockoutExpires(t *testing.T) {
 	}
 }
 
+func TestShouldAlertFollowsClock(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	dedup := NewDeduplicatorWithClock(config.AlertDedupeConfig{
+		Enabled:     true,
+		BlockoutMin: 15 * time.Minute,
+	}, func() time.Time { return now })
+
+	aircraft := piaware.NearbyAircraft{
+		Aircraft: piaware.Aircraft{
+			Hex:    "ABC123",
+			Flight: "TEST1",
+		},
+	}
+
+	if !dedup.ShouldAlert(aircraft) {
+		t.Error("expected to alert for new aircraft")
+	}
+
+	now = now.Add(10 * time.Minute)
+	if dedup.ShouldAlert(aircraft) {
+		t.Error("expected blockout to hold on the injected clock")
+	}
+
+	now = now.Add(6 * time.Minute)
+	if !dedup.ShouldAlert(aircraft) {
+		t.Error("expected to alert once the injected clock passes the blockout")
+	}
+}
+
 func TestShouldAlertWithDeduplicationDisabled(t *testing.T) {
 	dedup := NewDeduplicator(config.AlertDedupeConfig{
 		Enabled:     false,
@@ -185,6 +214,34 @@ func TestShouldAlertAircraftWithoutFlight(t *testing.T) {
 	}
 }
 
+func TestShouldAlertEachZone(t *testing.T) {
+	dedup := NewDeduplicator(config.AlertDedupeConfig{
+		Enabled:     true,
+		BlockoutMin: 15 * time.Minute,
+	})
+
+	aircraft := piaware.NearbyAircraft{
+		Aircraft: piaware.Aircraft{
+			Hex:    "ABC123",
+			Flight: "TEST1",
+		},
+		Zone: "approach",
+	}
+
+	if !dedup.ShouldAlert(aircraft) {
+		t.Error("expected to alert in the first zone")
+	}
+	if dedup.ShouldAlert(aircraft) {
+		t.Error("expected not to alert again in the same zone")
+	}
+
+	// Moving into another zone is alerted there.
+	aircraft.Zone = "departure"
+	if !dedup.ShouldAlert(aircraft) {
+		t.Error("expected to alert in the second zone")
+	}
+}
+
 func TestCleanupOldRecords(t *testing.T) {
 	dedup := NewDeduplicator(config.AlertDedupeConfig{
 		Enabled:     true,
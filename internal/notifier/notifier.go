@@ -2,18 +2,51 @@ package notifier
 
 import (
 	"fmt"
+	"slices"
 	"time"
 
 	"github.com/benvon/whats-flying-over-me/internal/config"
 	"github.com/benvon/whats-flying-over-me/internal/piaware"
 )
 
+// PriorityHigh marks alerts that need attention straight away, such as
+// emergencies.
+const PriorityHigh = "high"
+
 // AlertData represents the data structure for notifications.
 type AlertData struct {
 	Timestamp   time.Time              `json:"timestamp"`
 	Aircraft    piaware.NearbyAircraft `json:"aircraft"`
 	AlertType   string                 `json:"alert_type"`
 	Description string                 `json:"description"`
+
+	// Priority is PriorityHigh for urgent alerts and empty otherwise.
+	Priority string `json:"priority,omitempty"`
+
+	// Alerts raised by a rule name it and carry its severity. Notifiers
+	// limits delivery to the named notifiers; empty sends to all.
+	Rule      string   `json:"rule,omitempty"`
+	Severity  string   `json:"severity,omitempty"`
+	Notifiers []string `json:"-"`
+
+	// Track lifecycle alerts share the TrackID of the pass they belong to.
+	TrackID       string  `json:"track_id,omitempty"`
+	MinDistanceKm float64 `json:"min_distance_km,omitempty"`
+	DwellSeconds  float64 `json:"dwell_seconds,omitempty"`
+
+	// Predicted approaches carry the closest point the aircraft is expected
+	// to reach and how long until it gets there.
+	PredictedMinDistanceKm float64 `json:"predicted_min_distance_km,omitempty"`
+	ETASeconds             float64 `json:"eta_seconds,omitempty"`
+
+	// Zone is the zone the aircraft was matched in, if any.
+	Zone string `json:"zone,omitempty"`
+
+	// Look angles from the observer at the base.
+	AzimuthDeg   float64 `json:"azimuth_deg,omitempty"`
+	ElevationDeg float64 `json:"elevation_deg,omitempty"`
+	SlantRangeKm float64 `json:"slant_range_km,omitempty"`
+	Direction    string  `json:"direction,omitempty"`
 }
 
 // Notifier defines a mechanism for sending notifications.
@@ -21,18 +54,46 @@ type Notifier interface {
 	Notify(alert AlertData) error
 }
 
+// Notifier names, as used to route alerts to some notifiers only.
+const (
+	NameConsole  = "console"
+	NameWebhook  = "webhook"
+	NameRabbitMQ = "rabbitmq"
+)
+
 // MultiNotifier sends notifications to multiple backends.
 type MultiNotifier struct {
-	notifiers []Notifier
+	notifiers []namedNotifier
+}
+
+// namedNotifier is a backend with the name alerts are routed to it by.
+type namedNotifier struct {
+	name string
+	Notifier
+}
+
+// Names returns the names of the notifiers enabled in the configuration.
+func Names(cfg config.NotifierConfig) []string {
+	var names []string
+	if cfg.Console {
+		names = append(names, NameConsole)
+	}
+	if cfg.Webhook.Enabled {
+		names = append(names, NameWebhook)
+	}
+	if cfg.RabbitMQ.Enabled {
+		names = append(names, NameRabbitMQ)
+	}
+	return names
 }
 
 // New creates a notifier based on the configuration.
 func New(cfg config.NotifierConfig) (Notifier, error) {
-	var notifiers []Notifier
+	var notifiers []namedNotifier
 
 	// Always add console notifier
 	if cfg.Console {
-		notifiers = append(notifiers, NewConsole())
+		notifiers = append(notifiers, namedNotifier{NameConsole, NewConsole()})
 	}
 
 	// Add webhook notifier if enabled
@@ -41,7 +102,7 @@ func New(cfg config.NotifierConfig) (Notifier, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create webhook notifier: %w", err)
 		}
-		notifiers = append(notifiers, webhook)
+		notifiers = append(notifiers, namedNotifier{NameWebhook, webhook})
 	}
 
 	// Add RabbitMQ notifier if enabled
@@ -50,7 +111,7 @@ func New(cfg config.NotifierConfig) (Notifier, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to create RabbitMQ notifier: %w", err)
 		}
-		notifiers = append(notifiers, rabbitmq)
+		notifiers = append(notifiers, namedNotifier{NameRabbitMQ, rabbitmq})
 	}
 
 	if len(notifiers) == 0 {
@@ -58,16 +119,20 @@ func New(cfg config.NotifierConfig) (Notifier, error) {
 	}
 
 	if len(notifiers) == 1 {
-		return notifiers[0], nil
+		return notifiers[0].Notifier, nil
 	}
 
 	return &MultiNotifier{notifiers: notifiers}, nil
 }
 
-// Notify sends notifications to all configured backends.
+// Notify sends notifications to all configured backends, or to those named
+// by alert.Notifiers when it is set.
 func (m *MultiNotifier) Notify(alert AlertData) error {
 	var lastErr error
 	for _, n := range m.notifiers {
+		if len(alert.Notifiers) > 0 && !slices.Contains(alert.Notifiers, n.name) {
+			continue
+		}
 		if err := n.Notify(alert); err != nil {
 			lastErr = err
 			// Continue with other notifiers even if one fails
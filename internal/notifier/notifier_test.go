@@ -1,6 +1,7 @@
 package notifier
 
 import (
+	"slices"
 	"testing"
 	"time"
 
@@ -100,9 +101,17 @@ func TestMultiNotifier(t *testing.T) {
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if _, ok := n.(*MultiNotifier); !ok {
+	multi, ok := n.(*MultiNotifier)
+	if !ok {
 		t.Fatalf("expected *MultiNotifier, got %T", n)
 	}
+	// Each backend carries the name alerts are routed to it by.
+	if len(multi.notifiers) != 2 || multi.notifiers[0].name != NameConsole || multi.notifiers[1].name != NameWebhook {
+		t.Fatalf("unexpected notifiers %+v", multi.notifiers)
+	}
+	if _, ok := multi.notifiers[1].Notifier.(*Webhook); !ok {
+		t.Errorf("expected the webhook notifier to be named %q, got %T", NameWebhook, multi.notifiers[1].Notifier)
+	}
 }
 
 func TestNoNotifiers(t *testing.T) {
@@ -237,7 +246,7 @@ func TestMultiNotifierNotify(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			multiNotifier := &MultiNotifier{notifiers: tt.notifiers}
+			multiNotifier := &MultiNotifier{notifiers: unnamed(tt.notifiers...)}
 
 			err := multiNotifier.Notify(tt.alert)
 			if (err != nil) != tt.wantErr {
@@ -262,7 +271,7 @@ func TestMultiNotifierNotifyMultipleAlerts(t *testing.T) {
 	notifier1 := NewMockNotifier()
 	notifier2 := NewMockNotifier()
 
-	multiNotifier := &MultiNotifier{notifiers: []Notifier{notifier1, notifier2}}
+	multiNotifier := &MultiNotifier{notifiers: unnamed(notifier1, notifier2)}
 
 	alerts := []AlertData{
 		{
@@ -321,3 +330,42 @@ func TestMultiNotifierNotifyMultipleAlerts(t *testing.T) {
 		}
 	}
 }
+
+func TestMultiNotifierRoutesAlerts(t *testing.T) {
+	console := NewMockNotifier()
+	webhook := NewMockNotifier()
+	multiNotifier := &MultiNotifier{notifiers: []namedNotifier{{NameConsole, console}, {NameWebhook, webhook}}}
+
+	if err := multiNotifier.Notify(AlertData{AlertType: "all"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := multiNotifier.Notify(AlertData{AlertType: "routed", Notifiers: []string{NameWebhook}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := console.GetNotificationCount(); got != 1 {
+		t.Errorf("console received %d notifications, expected 1", got)
+	}
+	if got := webhook.GetNotificationCount(); got != 2 {
+		t.Errorf("webhook received %d notifications, expected 2", got)
+	}
+}
+
+func TestNames(t *testing.T) {
+	cfg := config.NotifierConfig{
+		Console:  true,
+		RabbitMQ: config.RabbitMQConfig{Enabled: true},
+	}
+	if got := Names(cfg); !slices.Equal(got, []string{NameConsole, NameRabbitMQ}) {
+		t.Errorf("Names() = %v", got)
+	}
+}
+
+// unnamed wraps notifiers for a MultiNotifier that does not route alerts.
+func unnamed(notifiers ...Notifier) []namedNotifier {
+	named := make([]namedNotifier, len(notifiers))
+	for i, n := range notifiers {
+		named[i] = namedNotifier{Notifier: n}
+	}
+	return named
+}
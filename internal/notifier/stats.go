@@ -11,14 +11,22 @@ type Stats struct {
 	scrapeCount    int64
 	scrapeFailures int64
 	uniqueAircraft map[string]time.Time // hex -> first seen time
+	now            func() time.Time
 	mutex          sync.RWMutex
 }
 
 // NewStats creates a new statistics tracker.
 func NewStats() *Stats {
+	return NewStatsWithClock(time.Now)
+}
+
+// NewStatsWithClock creates a statistics tracker that reads time from now
+// instead of the wall clock (useful for replays).
+func NewStatsWithClock(now func() time.Time) *Stats {
 	return &Stats{
-		startTime:      time.Now(),
+		startTime:      now(),
 		uniqueAircraft: make(map[string]time.Time),
+		now:            now,
 	}
 }
 
@@ -46,7 +54,7 @@ func (s *Stats) RecordAircraft(hex string) {
 	defer s.mutex.Unlock()
 
 	if _, exists := s.uniqueAircraft[hex]; !exists {
-		s.uniqueAircraft[hex] = time.Now()
+		s.uniqueAircraft[hex] = s.now()
 	}
 }
 
@@ -55,7 +63,7 @@ func (s *Stats) GetStats() map[string]interface{} {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	uptime := time.Since(s.startTime)
+	uptime := s.now().Sub(s.startTime)
 
 	return map[string]interface{}{
 		"uptime":          uptime.String(),
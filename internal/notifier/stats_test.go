@@ -323,3 +323,24 @@ func TestGetStatsFields(t *testing.T) {
 		t.Error("expected start_time to be string")
 	}
 }
+
+func TestStatsFollowsClock(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	stats := NewStatsWithClock(func() time.Time { return now })
+
+	stats.RecordAircraft("ABC123")
+	now = now.Add(90 * time.Minute)
+
+	data := stats.GetStats()
+	if data["uptime_seconds"] != int64(5400) {
+		t.Errorf("expected uptime from the injected clock, got %v", data["uptime_seconds"])
+	}
+	if data["start_time"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("expected start time from the injected clock, got %v", data["start_time"])
+	}
+
+	list := stats.GetUniqueAircraftList()
+	if len(list) != 1 || list[0]["first_seen"] != "2024-05-01T12:00:00Z" {
+		t.Errorf("expected first seen time from the injected clock, got %v", list)
+	}
+}
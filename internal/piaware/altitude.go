@@ -0,0 +1,68 @@
+package piaware
+
+import "math"
+
+// Altitude sources for AltitudeFilter.
+const (
+	// AltitudeBaro uses the barometric (pressure) altitude, alt_baro.
+	AltitudeBaro = "baro"
+	// AltitudeGeom uses the GNSS geometric altitude, alt_geom.
+	AltitudeGeom = "geom"
+)
+
+const (
+	// standardPressure is the ISA sea level pressure (hPa) that barometric
+	// altitudes are referenced to.
+	standardPressure = 1013.25
+	// isaHeightScale and isaExponent are the ISA troposphere constants for
+	// converting between pressure (hPa) and altitude (ft).
+	isaHeightScale = 145366.45
+	isaExponent    = 0.190284
+)
+
+// AltitudeFilter selects the altitude compared against altitude limits, and
+// bounds it.
+type AltitudeFilter struct {
+	// Source is AltitudeBaro (the default) or AltitudeGeom. Aircraft that do
+	// not report a geometric altitude fall back to their barometric one.
+	Source string
+	// QNH is the local altimeter setting in hPa used to correct barometric
+	// altitudes; zero leaves them at the standard setting.
+	QNH float64
+	// Min and Max bound the altitude in feet. A Min of zero means no floor.
+	Min int
+	Max int
+}
+
+// Altitude returns the aircraft's altitude in feet from the configured
+// source. Aircraft on the ground are at zero.
+func (f AltitudeFilter) Altitude(a Aircraft) int {
+	if a.OnGround {
+		return 0
+	}
+	if f.Source == AltitudeGeom && a.AltGeom != 0 {
+		return a.AltGeom
+	}
+	return CorrectAltitude(a.AltBaro, f.QNH)
+}
+
+// Allows reports whether the aircraft's altitude is within the limits.
+func (f AltitudeFilter) Allows(a Aircraft) bool {
+	alt := f.Altitude(a)
+	if f.Min != 0 && alt < f.Min {
+		return false
+	}
+	return alt <= f.Max
+}
+
+// CorrectAltitude converts a barometric altitude (ft), which is referenced to
+// the standard 1013.25 hPa, to the altitude an altimeter set to qnh (hPa)
+// would show, using the ISA pressure model. A qnh of zero or less returns
+// the altitude unchanged.
+func CorrectAltitude(altBaro int, qnh float64) int {
+	if qnh <= 0 {
+		return altBaro
+	}
+	ratio := math.Pow(standardPressure/qnh, isaExponent)
+	return int(math.Round(isaHeightScale - ratio*(isaHeightScale-float64(altBaro))))
+}
@@ -0,0 +1,98 @@
+package piaware
+
+import "testing"
+
+func TestCorrectAltitude(t *testing.T) {
+	tests := []struct {
+		name     string
+		altBaro  int
+		qnh      float64
+		expected int
+	}{
+		{"no correction", 1500, 0, 1500},
+		{"standard pressure", 1500, 1013.25, 1500},
+		{"high pressure", 1000, 1023.25, 1270},
+		{"low pressure", 1000, 1003.25, 727},
+		{"high pressure at sea level", 0, 1033.25, 540},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := CorrectAltitude(tt.altBaro, tt.qnh)
+			if diff := got - tt.expected; diff < -2 || diff > 2 {
+				t.Errorf("CorrectAltitude(%d, %.2f) = %d, want about %d", tt.altBaro, tt.qnh, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestAltitudeFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   AltitudeFilter
+		aircraft Aircraft
+		altitude int
+		allowed  bool
+	}{
+		{
+			name:     "baro by default",
+			filter:   AltitudeFilter{Max: 3000},
+			aircraft: Aircraft{AltBaro: 2900, AltGeom: 3100},
+			altitude: 2900,
+			allowed:  true,
+		},
+		{
+			name:     "geometric altitude",
+			filter:   AltitudeFilter{Source: AltitudeGeom, Max: 3000},
+			aircraft: Aircraft{AltBaro: 2900, AltGeom: 3100},
+			altitude: 3100,
+			allowed:  false,
+		},
+		{
+			name:     "geometric falls back to corrected baro",
+			filter:   AltitudeFilter{Source: AltitudeGeom, QNH: 1023.25, Max: 3000},
+			aircraft: Aircraft{AltBaro: 2900},
+			altitude: 3166,
+			allowed:  false,
+		},
+		{
+			name:     "QNH correction brings aircraft under the ceiling",
+			filter:   AltitudeFilter{QNH: 1003.25, Max: 3000},
+			aircraft: Aircraft{AltBaro: 3100},
+			altitude: 2831,
+			allowed:  true,
+		},
+		{
+			name:     "below the floor",
+			filter:   AltitudeFilter{Min: 500, Max: 3000},
+			aircraft: Aircraft{AltBaro: 400},
+			altitude: 400,
+			allowed:  false,
+		},
+		{
+			name:     "ground traffic below the floor",
+			filter:   AltitudeFilter{Source: AltitudeGeom, QNH: 1033.25, Min: 100, Max: 3000},
+			aircraft: Aircraft{OnGround: true, AltGeom: 25},
+			altitude: 0,
+			allowed:  false,
+		},
+		{
+			name:     "ground traffic without a floor",
+			filter:   AltitudeFilter{Max: 3000},
+			aircraft: Aircraft{OnGround: true},
+			altitude: 0,
+			allowed:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Altitude(tt.aircraft); got-tt.altitude < -2 || got-tt.altitude > 2 {
+				t.Errorf("Altitude() = %d, want about %d", got, tt.altitude)
+			}
+			if got := tt.filter.Allows(tt.aircraft); got != tt.allowed {
+				t.Errorf("Allows() = %v, want %v", got, tt.allowed)
+			}
+		})
+	}
+}
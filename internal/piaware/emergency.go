@@ -0,0 +1,61 @@
+package piaware
+
+import "strings"
+
+// Emergency statuses, as written to the emergency field by readsb.
+const (
+	EmergencyNone      = "none"
+	EmergencyGeneral   = "general"
+	EmergencyLifeguard = "lifeguard"
+	EmergencyMinFuel   = "minfuel"
+	EmergencyNoRadio   = "nordo"
+	EmergencyUnlawful  = "unlawful"
+	EmergencyDowned    = "downed"
+	EmergencyReserved  = "reserved"
+)
+
+// emergencySquawks maps the transponder codes reserved for emergencies to
+// the status they signal.
+var emergencySquawks = map[string]string{
+	"7500": EmergencyUnlawful,
+	"7600": EmergencyNoRadio,
+	"7700": EmergencyGeneral,
+}
+
+var emergencyDescriptions = map[string]string{
+	EmergencyGeneral:   "general emergency",
+	EmergencyLifeguard: "lifeguard (medical) emergency",
+	EmergencyMinFuel:   "minimum fuel",
+	EmergencyNoRadio:   "radio failure",
+	EmergencyUnlawful:  "unlawful interference",
+	EmergencyDowned:    "downed aircraft",
+	EmergencyReserved:  "reserved emergency",
+}
+
+// EmergencySquawk returns the squawk when it is one of the emergency codes
+// 7500, 7600 or 7700, and otherwise "".
+func (a Aircraft) EmergencySquawk() string {
+	if _, ok := emergencySquawks[a.Squawk]; ok {
+		return a.Squawk
+	}
+	return ""
+}
+
+// EmergencyStatus returns the emergency the aircraft is declaring, such as
+// EmergencyGeneral, taken from the emergency field or else from an emergency
+// squawk. It returns "" when there is none.
+func (a Aircraft) EmergencyStatus() string {
+	if status := strings.ToLower(strings.TrimSpace(a.Emergency)); status != "" && status != EmergencyNone {
+		return status
+	}
+	return emergencySquawks[a.Squawk]
+}
+
+// DescribeEmergency renders an emergency status for people, such as "radio
+// failure" for EmergencyNoRadio. Unknown statuses are returned as they are.
+func DescribeEmergency(status string) string {
+	if d, ok := emergencyDescriptions[status]; ok {
+		return d
+	}
+	return status
+}
@@ -0,0 +1,39 @@
+package piaware
+
+import "testing"
+
+func TestEmergencyStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		aircraft   Aircraft
+		wantStatus string
+		wantSquawk string
+	}{
+		{"no emergency", Aircraft{Squawk: "1200", Emergency: "none"}, "", ""},
+		{"hijack squawk", Aircraft{Squawk: "7500"}, EmergencyUnlawful, "7500"},
+		{"radio failure squawk", Aircraft{Squawk: "7600", Emergency: "none"}, EmergencyNoRadio, "7600"},
+		{"general emergency squawk", Aircraft{Squawk: "7700"}, EmergencyGeneral, "7700"},
+		{"emergency field", Aircraft{Squawk: "2301", Emergency: "lifeguard"}, EmergencyLifeguard, ""},
+		{"emergency field takes precedence", Aircraft{Squawk: "7700", Emergency: "minfuel"}, EmergencyMinFuel, "7700"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.aircraft.EmergencyStatus(); got != tt.wantStatus {
+				t.Errorf("EmergencyStatus() = %q, want %q", got, tt.wantStatus)
+			}
+			if got := tt.aircraft.EmergencySquawk(); got != tt.wantSquawk {
+				t.Errorf("EmergencySquawk() = %q, want %q", got, tt.wantSquawk)
+			}
+		})
+	}
+}
+
+func TestDescribeEmergency(t *testing.T) {
+	if got := DescribeEmergency(EmergencyNoRadio); got != "radio failure" {
+		t.Errorf("DescribeEmergency(nordo) = %q", got)
+	}
+	if got := DescribeEmergency("unknown"); got != "unknown" {
+		t.Errorf("DescribeEmergency(unknown) = %q", got)
+	}
+}
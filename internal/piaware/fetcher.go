@@ -0,0 +1,196 @@
+package piaware
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFetchTimeout is used when no timeout is configured.
+const defaultFetchTimeout = 10 * time.Second
+
+// ErrNotModified is returned when the receiver reports that the snapshot has
+// not changed since the previous fetch.
+var ErrNotModified = errors.New("aircraft data not modified")
+
+// HTTPConfig holds settings for fetching aircraft.json over HTTP.
+type HTTPConfig struct {
+	Timeout     time.Duration
+	Username    string
+	Password    string
+	BearerToken string
+	Headers     map[string]string
+	CAFile      string
+	CertFile    string
+	KeyFile     string
+}
+
+// validators are the cache validators returned with the last snapshot.
+type validators struct {
+	etag         string
+	lastModified string
+}
+
+// HTTPFetcher fetches aircraft.json snapshots over HTTP. It is safe for
+// concurrent use and remembers the ETag and Last-Modified validators of each
+// URL so unchanged snapshots are reported as ErrNotModified without being
+// downloaded or decoded again.
+type HTTPFetcher struct {
+	cfg        HTTPConfig
+	client     *http.Client
+	validators map[string]validators
+	mutex      sync.Mutex
+}
+
+// NewHTTPFetcher creates a fetcher from the configuration, loading any
+// configured CA and client certificates.
+func NewHTTPFetcher(cfg HTTPConfig) (*HTTPFetcher, error) {
+	if cfg.Timeout == 0 {
+		cfg.Timeout = defaultFetchTimeout
+	}
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	// Compression is negotiated explicitly so gzip-encoded snapshots are
+	// handled even when custom headers are set.
+	transport.DisableCompression = true
+
+	return &HTTPFetcher{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout, Transport: transport},
+		validators: make(map[string]validators),
+	}, nil
+}
+
+// newTLSConfig builds the TLS configuration for custom CA and client certificates.
+func newTLSConfig(cfg HTTPConfig) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if cfg.CAFile != "" {
+		// #nosec G304 -- path is controlled via trusted config
+		pem, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		if cfg.CertFile == "" || cfg.KeyFile == "" {
+			return nil, fmt.Errorf("both client certificate and key files are required")
+		}
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// Fetch retrieves the aircraft from url.
+func (f *HTTPFetcher) Fetch(ctx context.Context, url string) ([]Aircraft, error) {
+	data, err := f.FetchData(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	return data.Aircraft, nil
+}
+
+// FetchData retrieves the aircraft.json snapshot from url. It returns
+// ErrNotModified if the receiver reports the snapshot unchanged.
+func (f *HTTPFetcher) FetchData(ctx context.Context, url string) (*Data, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	f.setHeaders(req)
+
+	f.mutex.Lock()
+	v := f.validators[url]
+	f.mutex.Unlock()
+	if v.etag != "" {
+		req.Header.Set("If-None-Match", v.etag)
+	}
+	if v.lastModified != "" {
+		req.Header.Set("If-Modified-Since", v.lastModified)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, ErrNotModified
+	}
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, string(b))
+	}
+
+	body := io.Reader(resp.Body)
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress response: %w", err)
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		body = gz
+	}
+
+	var data Data
+	if err := json.NewDecoder(body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	f.mutex.Lock()
+	f.validators[url] = validators{
+		etag:         resp.Header.Get("ETag"),
+		lastModified: resp.Header.Get("Last-Modified"),
+	}
+	f.mutex.Unlock()
+
+	return &data, nil
+}
+
+// setHeaders applies authentication, compression and custom headers.
+func (f *HTTPFetcher) setHeaders(req *http.Request) {
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Encoding", "gzip")
+	for k, v := range f.cfg.Headers {
+		req.Header.Set(k, v)
+	}
+
+	switch {
+	case f.cfg.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+f.cfg.BearerToken)
+	case f.cfg.Username != "":
+		req.SetBasicAuth(f.cfg.Username, f.cfg.Password)
+	}
+}
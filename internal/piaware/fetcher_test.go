@@ -0,0 +1,214 @@
+package piaware
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const snapshotJSON = `{"now":1,"aircraft":[{"hex":"abc","flight":"TEST","lat":1,"lon":2,"alt_baro":300}]}`
+
+func TestHTTPFetcherAuthAndHeaders(t *testing.T) {
+	tests := []struct {
+		name     string
+		cfg      HTTPConfig
+		wantAuth string
+	}{
+		{
+			name:     "basic auth",
+			cfg:      HTTPConfig{Username: "user", Password: "pass"},
+			wantAuth: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name:     "bearer token",
+			cfg:      HTTPConfig{BearerToken: "secret"},
+			wantAuth: "Bearer secret",
+		},
+		{
+			name:     "no auth",
+			cfg:      HTTPConfig{},
+			wantAuth: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.cfg.Headers = map[string]string{"X-Receiver": "roof"}
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if got := r.Header.Get("Authorization"); got != tt.wantAuth {
+					t.Errorf("expected Authorization %q, got %q", tt.wantAuth, got)
+				}
+				if got := r.Header.Get("X-Receiver"); got != "roof" {
+					t.Errorf("expected custom header, got %q", got)
+				}
+				_, _ = io.WriteString(w, snapshotJSON)
+			}))
+			defer srv.Close()
+
+			f, err := NewHTTPFetcher(tt.cfg)
+			if err != nil {
+				t.Fatalf("NewHTTPFetcher() error = %v", err)
+			}
+			aircraft, err := f.Fetch(context.Background(), srv.URL)
+			if err != nil {
+				t.Fatalf("Fetch() error = %v", err)
+			}
+			if len(aircraft) != 1 {
+				t.Errorf("expected 1 aircraft, got %d", len(aircraft))
+			}
+		})
+	}
+}
+
+func TestHTTPFetcherGzip(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			t.Errorf("expected gzip to be requested, got %q", r.Header.Get("Accept-Encoding"))
+		}
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		_, _ = io.WriteString(gz, snapshotJSON)
+		_ = gz.Close()
+	}))
+	defer srv.Close()
+
+	f, err := NewHTTPFetcher(HTTPConfig{})
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher() error = %v", err)
+	}
+	data, err := f.FetchData(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("FetchData() error = %v", err)
+	}
+	if len(data.Aircraft) != 1 || data.Aircraft[0].Hex != "abc" {
+		t.Errorf("unexpected aircraft %+v", data.Aircraft)
+	}
+}
+
+func TestHTTPFetcherConditionalRequests(t *testing.T) {
+	const etag = `"snapshot-1"`
+	const lastModified = "Mon, 01 Jan 2024 12:00:00 GMT"
+	requests := 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastModified {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastModified)
+		_, _ = io.WriteString(w, snapshotJSON)
+	}))
+	defer srv.Close()
+
+	f, err := NewHTTPFetcher(HTTPConfig{})
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher() error = %v", err)
+	}
+
+	if _, err := f.FetchData(context.Background(), srv.URL); err != nil {
+		t.Fatalf("first FetchData() error = %v", err)
+	}
+	if _, err := f.FetchData(context.Background(), srv.URL); !errors.Is(err, ErrNotModified) {
+		t.Fatalf("expected ErrNotModified on unchanged snapshot, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 requests, got %d", requests)
+	}
+}
+
+func TestHTTPFetcherContextAndTimeout(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	f, err := NewHTTPFetcher(HTTPConfig{Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher() error = %v", err)
+	}
+	if _, err := f.FetchData(context.Background(), srv.URL); err == nil {
+		t.Error("expected timeout error")
+	}
+
+	f, err = NewHTTPFetcher(HTTPConfig{Timeout: time.Minute})
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher() error = %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := f.FetchData(ctx, srv.URL); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context deadline error, got %v", err)
+	}
+}
+
+func TestHTTPFetcherCustomCA(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.WriteString(w, snapshotJSON)
+	}))
+	defer srv.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	if err := os.WriteFile(caFile, caPEM, 0o600); err != nil {
+		t.Fatalf("write CA: %v", err)
+	}
+
+	// Without the CA the self-signed certificate is rejected.
+	f, err := NewHTTPFetcher(HTTPConfig{})
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher() error = %v", err)
+	}
+	if _, err := f.FetchData(context.Background(), srv.URL); err == nil {
+		t.Error("expected certificate verification error without custom CA")
+	}
+
+	f, err = NewHTTPFetcher(HTTPConfig{CAFile: caFile})
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher() error = %v", err)
+	}
+	if _, err := f.FetchData(context.Background(), srv.URL); err != nil {
+		t.Errorf("FetchData() with custom CA error = %v", err)
+	}
+}
+
+func TestNewHTTPFetcherInvalidTLS(t *testing.T) {
+	dir := t.TempDir()
+	empty := filepath.Join(dir, "empty.pem")
+	if err := os.WriteFile(empty, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  HTTPConfig
+	}{
+		{name: "missing CA file", cfg: HTTPConfig{CAFile: filepath.Join(dir, "missing.pem")}},
+		{name: "CA file without certificates", cfg: HTTPConfig{CAFile: empty}},
+		{name: "certificate without key", cfg: HTTPConfig{CertFile: empty}},
+		{name: "invalid key pair", cfg: HTTPConfig{CertFile: empty, KeyFile: empty}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewHTTPFetcher(tt.cfg); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package piaware
+
+import (
+	"math"
+	"slices"
+
+	"github.com/benvon/whats-flying-over-me/internal/geo"
+)
+
+const (
+	// DefaultCellDeg is the grid cell size used when NewIndex is given none.
+	// Half a degree is about 55 km of latitude, so a typical radius touches
+	// only a handful of cells.
+	DefaultCellDeg = 0.5
+	// MinCellDeg is the smallest grid cell size, which keeps cell numbers
+	// within 32 bits.
+	MinCellDeg = 0.01
+)
+
+// Index is a latitude/longitude grid of aircraft positions. Radius queries
+// only run the precise distance test on aircraft in the cells overlapping
+// the radius's bounding box, which keeps filtering large merged feeds
+// against several zones cheap. An Index is built once per snapshot and is
+// not updated.
+//
+// Aircraft are kept sorted by cell, numbered row by row from the south-west,
+// so the cells of one row of a bounding box are a single run found by binary
+// search.
+type Index struct {
+	aircraft []Aircraft
+	cellDeg  float64
+	// cols is the number of cells in a row.
+	cols int
+	// entries hold each aircraft's cell in the high 32 bits and its position
+	// in the feed in the low 32 bits, so sorting them orders by cell and
+	// then by feed position.
+	entries []uint64
+}
+
+// NewIndex builds an index of the aircraft with a position, using cells of
+// cellDeg degrees. Positions out of range, as from a corrupt feed, would
+// fall in the wrong cell and are left out. A cellDeg of zero or less uses DefaultCellDeg, and cells
+// smaller than MinCellDeg are enlarged to it.
+func NewIndex(aircraft []Aircraft, cellDeg float64) *Index {
+	if cellDeg <= 0 {
+		cellDeg = DefaultCellDeg
+	}
+	cellDeg = math.Max(cellDeg, MinCellDeg)
+	ix := &Index{
+		aircraft: aircraft,
+		cellDeg:  cellDeg,
+		cols:     int(math.Floor(360/cellDeg)) + 1,
+		entries:  make([]uint64, 0, len(aircraft)),
+	}
+	for i, a := range aircraft {
+		if (a.Lat == 0 && a.Lon == 0) || !validPosition(a) {
+			continue
+		}
+		ix.entries = append(ix.entries, uint64(ix.cellOf(ix.row(a.Lat), ix.col(a.Lon)))<<32|uint64(i))
+	}
+	slices.Sort(ix.entries)
+	return ix
+}
+
+// Within returns the indexed aircraft within the radius (km) whose altitude
+// is allowed by alt, measuring distances with mode. It returns the same
+// aircraft, in the same order, as FilterAircraftWithin.
+func (ix *Index) Within(baseLat, baseLon, radiusKm float64, alt AltitudeFilter, mode geo.Mode) []NearbyAircraft {
+	box := geo.BoundingBox(baseLat, baseLon, radiusKm)
+	var result []NearbyAircraft
+	for _, i := range ix.candidates(box) {
+		a := ix.aircraft[i]
+		if !box.Contains(a.Lat, a.Lon) {
+			continue
+		}
+		dist := mode.DistanceKm(baseLat, baseLon, a.Lat, a.Lon)
+		if dist <= radiusKm && alt.Allows(a) {
+			result = append(result, NearbyAircraft{Aircraft: a, DistanceKm: dist})
+		}
+	}
+	return result
+}
+
+// candidates returns the feed positions, in feed order, of the aircraft in
+// the cells overlapping the box.
+func (ix *Index) candidates(box geo.Box) []int {
+	// Column ranges; a box crossing the antimeridian has two.
+	cols := [][2]int{{ix.col(box.MinLon), ix.col(box.MaxLon)}}
+	if box.MinLon > box.MaxLon {
+		cols = [][2]int{{ix.col(box.MinLon), ix.cols - 1}, {0, ix.col(box.MaxLon)}}
+	}
+
+	var found []int
+	for row := ix.row(box.MinLat); row <= ix.row(box.MaxLat); row++ {
+		for _, c := range cols {
+			first := uint64(ix.cellOf(row, c[0])) << 32
+			last := uint64(ix.cellOf(row, c[1])) << 32
+			start, _ := slices.BinarySearch(ix.entries, first)
+			for _, e := range ix.entries[start:] {
+				if e&^0xffffffff > last {
+					break
+				}
+				found = append(found, int(e&0xffffffff))
+			}
+		}
+	}
+	slices.Sort(found)
+	return found
+}
+
+// validPosition reports whether the aircraft's latitude and longitude are
+// within [-90, 90] and [-180, 180].
+func validPosition(a Aircraft) bool {
+	return a.Lat >= -90 && a.Lat <= 90 && a.Lon >= -180 && a.Lon <= 180
+}
+
+// row and col return the grid row and column of a latitude and longitude.
+func (ix *Index) row(lat float64) int {
+	return int(math.Floor((lat + 90) / ix.cellDeg))
+}
+
+func (ix *Index) col(lon float64) int {
+	return int(math.Floor((lon + 180) / ix.cellDeg))
+}
+
+func (ix *Index) cellOf(row, col int) int {
+	return row*ix.cols + col
+}
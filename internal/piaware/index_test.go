@@ -0,0 +1,158 @@
+package piaware
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/geo"
+)
+
+// syntheticSnapshot returns n aircraft spread worldwide, with a tenth of
+// them clustered within about 100 km of (40, -74) and a few without a
+// position.
+func syntheticSnapshot(n int) []Aircraft {
+	r := rand.New(rand.NewSource(1))
+	aircraft := make([]Aircraft, n)
+	for i := range aircraft {
+		a := Aircraft{
+			Hex:     fmt.Sprintf("%06x", i),
+			AltBaro: r.Intn(40000),
+		}
+		switch {
+		case i%100 == 0:
+			// No position.
+		case i%10 == 0:
+			a.Lat = 40 + r.Float64()*2 - 1
+			a.Lon = -74 + r.Float64()*2 - 1
+		default:
+			a.Lat = r.Float64()*180 - 90
+			a.Lon = r.Float64()*360 - 180
+		}
+		aircraft[i] = a
+	}
+	return aircraft
+}
+
+// benchmarkZones are the areas queried per snapshot in the benchmarks.
+var benchmarkZones = []struct {
+	lat      float64
+	lon      float64
+	radiusKm float64
+}{
+	{40.0, -74.0, 25},
+	{40.6, -73.8, 10},
+	{40.8, -74.2, 50},
+}
+
+func TestIndexWithinMatchesFilter(t *testing.T) {
+	aircraft := syntheticSnapshot(20000)
+	aircraft = append(aircraft,
+		Aircraft{Hex: "am1", Lat: -17.0, Lon: 179.95, AltBaro: 1000},
+		Aircraft{Hex: "am2", Lat: -17.0, Lon: -179.95, AltBaro: 1000},
+		Aircraft{Hex: "pole", Lat: 89.95, Lon: 120, AltBaro: 1000},
+	)
+	alt := AltitudeFilter{Min: 500, Max: 30000}
+
+	tests := []struct {
+		name     string
+		lat      float64
+		lon      float64
+		radiusKm float64
+		cellDeg  float64
+		mode     geo.Mode
+	}{
+		{"default cells", 40, -74, 25, 0, geo.Spherical},
+		{"small cells", 40, -74, 80, 0.1, geo.Spherical},
+		{"ellipsoidal", 40.3, -74.4, 60, 0, geo.Ellipsoidal},
+		{"odd cell size", 40, -74, 120, 0.7, geo.Spherical},
+		{"across the antimeridian", -17, 180, 20, 0, geo.Spherical},
+		{"over the pole", 89.9, 0, 50, 0, geo.Spherical},
+		{"large radius", 30, 10, 3000, 0, geo.Spherical},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := FilterAircraftWithin(aircraft, tt.lat, tt.lon, tt.radiusKm, alt, tt.mode)
+			got := NewIndex(aircraft, tt.cellDeg).Within(tt.lat, tt.lon, tt.radiusKm, alt, tt.mode)
+			if len(want) == 0 {
+				t.Fatal("expected the filter to find aircraft")
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Within() returned %d aircraft, FilterAircraftWithin() %d", len(got), len(want))
+			}
+		})
+	}
+}
+
+func TestIndexSkipsAircraftWithoutPosition(t *testing.T) {
+	aircraft := []Aircraft{
+		{Hex: "nopos", AltBaro: 1000},
+		{Hex: "near", Lat: 0.01, Lon: 0.01, AltBaro: 1000},
+	}
+	got := NewIndex(aircraft, 0).Within(0, 0, 10, AltitudeFilter{Max: 10000}, geo.Spherical)
+	if len(got) != 1 || got[0].Hex != "near" {
+		t.Errorf("expected only the positioned aircraft, got %+v", got)
+	}
+}
+
+func TestIndexSkipsInvalidPositions(t *testing.T) {
+	aircraft := []Aircraft{
+		// A latitude below -90 once gave a negative row, which wrapped the
+		// cell number into another part of the grid.
+		{Hex: "south", Lat: -91, Lon: 0.01, AltBaro: 1000},
+		{Hex: "north", Lat: 90.5, Lon: 0.01, AltBaro: 1000},
+		{Hex: "east", Lat: 0.01, Lon: 181, AltBaro: 1000},
+		{Hex: "nan", Lat: math.NaN(), Lon: 0.01, AltBaro: 1000},
+		{Hex: "near", Lat: 0.01, Lon: 0.01, AltBaro: 1000},
+	}
+	ix := NewIndex(aircraft, 0)
+	if len(ix.entries) != 1 {
+		t.Errorf("expected only the valid position to be indexed, got %d entries", len(ix.entries))
+	}
+	got := ix.Within(0, 0, 10, AltitudeFilter{Max: 10000}, geo.Spherical)
+	if len(got) != 1 || got[0].Hex != "near" {
+		t.Errorf("expected only the valid position, got %+v", got)
+	}
+	if want := FilterAircraftWithin(aircraft, 0, 0, 10, AltitudeFilter{Max: 10000}, geo.Spherical); !reflect.DeepEqual(got, want) {
+		t.Errorf("Within() = %+v, FilterAircraftWithin() = %+v", got, want)
+	}
+}
+
+func BenchmarkFilterAircraftWithin(b *testing.B) {
+	aircraft := syntheticSnapshot(50000)
+	alt := AltitudeFilter{Max: 10000}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, z := range benchmarkZones {
+			FilterAircraftWithin(aircraft, z.lat, z.lon, z.radiusKm, alt, geo.Spherical)
+		}
+	}
+}
+
+func BenchmarkIndexWithin(b *testing.B) {
+	aircraft := syntheticSnapshot(50000)
+	alt := AltitudeFilter{Max: 10000}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Building the index is part of every cycle.
+		ix := NewIndex(aircraft, 0)
+		for _, z := range benchmarkZones {
+			ix.Within(z.lat, z.lon, z.radiusKm, alt, geo.Spherical)
+		}
+	}
+}
+
+func BenchmarkIndexWithinPrebuilt(b *testing.B) {
+	aircraft := syntheticSnapshot(50000)
+	alt := AltitudeFilter{Max: 10000}
+	ix := NewIndex(aircraft, 0)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, z := range benchmarkZones {
+			ix.Within(z.lat, z.lon, z.radiusKm, alt, geo.Spherical)
+		}
+	}
+}
@@ -3,75 +3,231 @@ package piaware
 import (
 	"encoding/json"
 	"fmt"
-	"io"
 	"math"
-	"net/http"
 	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/geo"
 )
 
 // Aircraft represents an aircraft entry from piaware.
+//
+// The fields mirror the aircraft.json schema written by dump1090-fa and
+// readsb. Fields that are absent from the feed are left at their zero value.
 type Aircraft struct {
-	Hex     string  `json:"hex"`
-	Flight  string  `json:"flight"`
-	Lat     float64 `json:"lat"`
-	Lon     float64 `json:"lon"`
-	AltBaro int     `json:"alt_baro"`
+	Hex         string   `json:"hex"`
+	Type        string   `json:"type,omitempty"`
+	Flight      string   `json:"flight"`
+	Lat         float64  `json:"lat"`
+	Lon         float64  `json:"lon"`
+	AltBaro     int      `json:"alt_baro"`
+	OnGround    bool     `json:"on_ground,omitempty"`
+	AltGeom     int      `json:"alt_geom,omitempty"`
+	GS          float64  `json:"gs,omitempty"`
+	IAS         int      `json:"ias,omitempty"`
+	TAS         int      `json:"tas,omitempty"`
+	Mach        float64  `json:"mach,omitempty"`
+	Track       float64  `json:"track,omitempty"`
+	TrackRate   float64  `json:"track_rate,omitempty"`
+	Roll        float64  `json:"roll,omitempty"`
+	MagHeading  float64  `json:"mag_heading,omitempty"`
+	TrueHeading float64  `json:"true_heading,omitempty"`
+	BaroRate    int      `json:"baro_rate,omitempty"`
+	GeomRate    int      `json:"geom_rate,omitempty"`
+	Squawk      string   `json:"squawk,omitempty"`
+	Emergency   string   `json:"emergency,omitempty"`
+	Category    string   `json:"category,omitempty"`
+	NavQNH      float64  `json:"nav_qnh,omitempty"`
+	NavAltitude int      `json:"nav_altitude_mcp,omitempty"`
+	NavHeading  float64  `json:"nav_heading,omitempty"`
+	NIC         int      `json:"nic,omitempty"`
+	NACp        int      `json:"nac_p,omitempty"`
+	Version     int      `json:"version,omitempty"`
+	MLAT        []string `json:"mlat,omitempty"`
+	TISB        []string `json:"tisb,omitempty"`
+	Messages    int      `json:"messages,omitempty"`
+	Seen        float64  `json:"seen,omitempty"`
+	SeenPos     float64  `json:"seen_pos,omitempty"`
+	RSSI        float64  `json:"rssi,omitempty"`
+
+	// Registration, TypeCode, Model, Operator and Year are written by readsb
+	// when it has an aircraft database, and are otherwise filled in from the
+	// configured one. Manufacturer is not part of the aircraft.json schema.
+	Registration string `json:"r,omitempty"`
+	TypeCode     string `json:"t,omitempty"`
+	Manufacturer string `json:"manufacturer,omitempty"`
+	Model        string `json:"desc,omitempty"`
+	Operator     string `json:"ownOp,omitempty"`
+	Year         string `json:"year,omitempty"`
+
+	// Country and Military are decoded from the ICAO address. They are not
+	// part of the aircraft.json schema.
+	Country  string `json:"country,omitempty"`
+	Military bool   `json:"military,omitempty"`
+
+	// Callsign is Flight without padding. Airline, Telephony and FlightNumber
+	// are decoded from an airline callsign. They are not part of the
+	// aircraft.json schema.
+	Callsign     string `json:"callsign,omitempty"`
+	Airline      string `json:"airline,omitempty"`
+	Telephony    string `json:"telephony,omitempty"`
+	FlightNumber string `json:"flight_number,omitempty"`
+
+	// Origin and Destination are the airport codes of the route flown under
+	// the callsign, and OriginName and DestinationName the airport names,
+	// from the configured routes file. They are not part of the aircraft.json
+	// schema.
+	Origin          string `json:"origin,omitempty"`
+	OriginName      string `json:"origin_name,omitempty"`
+	Destination     string `json:"destination,omitempty"`
+	DestinationName string `json:"destination_name,omitempty"`
+
+	// Receiver names the receiver the entry was taken from when several
+	// receivers are merged. It is not part of the aircraft.json schema.
+	Receiver string `json:"receiver,omitempty"`
+
+	// PositionAge is the age in seconds of the position when the snapshot
+	// was processed, as set by PositionAger. It is not part of the
+	// aircraft.json schema.
+	PositionAge float64 `json:"position_age,omitempty"`
+}
+
+// UnmarshalJSON decodes an aircraft entry, accepting the string "ground"
+// for alt_baro as reported for aircraft on the surface. An on_ground field,
+// as written when a decoded entry is encoded again, is kept.
+func (a *Aircraft) UnmarshalJSON(data []byte) error {
+	type alias Aircraft
+	*a = Aircraft{}
+	aux := struct {
+		*alias
+		AltBaro json.RawMessage `json:"alt_baro"`
+	}{alias: (*alias)(a)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	a.AltBaro = 0
+	if len(aux.AltBaro) == 0 || string(aux.AltBaro) == "null" {
+		return nil
+	}
+
+	var s string
+	if err := json.Unmarshal(aux.AltBaro, &s); err == nil {
+		if s != "ground" {
+			return fmt.Errorf("invalid alt_baro %q", s)
+		}
+		a.OnGround = true
+		return nil
+	}
+
+	var alt float64
+	if err := json.Unmarshal(aux.AltBaro, &alt); err != nil {
+		return fmt.Errorf("invalid alt_baro: %w", err)
+	}
+	a.AltBaro = int(math.Round(alt))
+	return nil
 }
 
 // Data represents the piaware aircraft JSON response.
 type Data struct {
 	Now      float64    `json:"now"`
+	Messages int        `json:"messages,omitempty"`
 	Aircraft []Aircraft `json:"aircraft"`
 }
 
-// Fetch retrieves aircraft data from the given URL.
-func Fetch(url string) ([]Aircraft, error) {
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(url)
-	if err != nil {
-		return nil, err
+// PositionAger sets PositionAge on the aircraft in successive snapshots
+// from one source. Ages come from the feed's own seen_pos, so a clock
+// difference between the receiver and this host does not skew them. Once a
+// snapshot's now stops advancing, the time this host has seen it stand
+// still is added, so positions in a feed that stopped updating keep getting
+// older. A PositionAger is not safe for concurrent use.
+type PositionAger struct {
+	now   float64
+	since time.Time
+}
+
+// Age sets PositionAge on every aircraft in d with a position, as of at.
+// Snapshots without a now are aged by seen_pos alone.
+func (p *PositionAger) Age(d *Data, at time.Time) {
+	var stalled float64
+	if d.Now > 0 {
+		if d.Now != p.now || p.since.IsZero() {
+			p.now = d.Now
+			p.since = at
+		}
+		stalled = math.Max(0, at.Sub(p.since).Seconds())
 	}
-	defer func() {
-		_ = resp.Body.Close()
-	}()
-	if resp.StatusCode != http.StatusOK {
-		b, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("unexpected status %s: %s", resp.Status, string(b))
+	for i := range d.Aircraft {
+		a := &d.Aircraft[i]
+		if a.Lat == 0 && a.Lon == 0 {
+			continue
+		}
+		a.PositionAge = a.SeenPos + stalled
+	}
+}
+
+// FreshAircraft drops aircraft whose position is older than maxAge and
+// returns the remaining aircraft along with the number dropped. Aircraft
+// without a position are kept. A maxAge of zero disables the check.
+func FreshAircraft(aircraft []Aircraft, maxAge time.Duration) ([]Aircraft, int) {
+	if maxAge <= 0 {
+		return aircraft, 0
 	}
-	var data Data
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
+	fresh := make([]Aircraft, 0, len(aircraft))
+	for _, a := range aircraft {
+		if a.PositionAge > maxAge.Seconds() {
+			continue
+		}
+		fresh = append(fresh, a)
 	}
-	return data.Aircraft, nil
+	return fresh, len(aircraft) - len(fresh)
 }
 
-// NearbyAircraft is an aircraft with associated distance from the base.
+// NearbyAircraft is an aircraft with associated distance from the base, and
+// the zone it was matched in when filtering by zone. When selected by an
+// alert rule, Rule names it and Zone holds the same name.
 type NearbyAircraft struct {
 	Aircraft
 	DistanceKm float64
+	Zone       string `json:"-"`
+	Rule       string `json:"-"`
 }
 
 // FilterAircraft returns aircraft within the radius (km) and below altitude.
 func FilterAircraft(aircraft []Aircraft, baseLat, baseLon, radiusKm float64, altMax int) []NearbyAircraft {
+	return FilterAircraftWithin(aircraft, baseLat, baseLon, radiusKm, AltitudeFilter{Max: altMax}, geo.Spherical)
+}
+
+// FilterAircraftWithin returns aircraft within the radius (km) whose altitude
+// is allowed by alt, measuring distances with mode. Aircraft whose position
+// is out of range are skipped. It tests every aircraft; use an Index to
+// query a large snapshot several times.
+func FilterAircraftWithin(aircraft []Aircraft, baseLat, baseLon, radiusKm float64, alt AltitudeFilter, mode geo.Mode) []NearbyAircraft {
 	var result []NearbyAircraft
 	for _, a := range aircraft {
-		if a.Lat == 0 && a.Lon == 0 {
+		if (a.Lat == 0 && a.Lon == 0) || !validPosition(a) {
 			continue
 		}
-		dist := distance(baseLat, baseLon, a.Lat, a.Lon)
-		if dist <= radiusKm && a.AltBaro <= altMax {
+		dist := mode.DistanceKm(baseLat, baseLon, a.Lat, a.Lon)
+		if dist <= radiusKm && alt.Allows(a) {
 			result = append(result, NearbyAircraft{Aircraft: a, DistanceKm: dist})
 		}
 	}
 	return result
 }
 
-// distance calculates the haversine distance in kilometers between two points.
-func distance(lat1, lon1, lat2, lon2 float64) float64 {
-	const R = 6371.0 // Earth radius in kilometers
-	dLat := (lat2 - lat1) * math.Pi / 180
-	dLon := (lon2 - lon1) * math.Pi / 180
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*math.Sin(dLon/2)*math.Sin(dLon/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	return R * c
+// FilterAircraftInZones returns aircraft for which match reports a zone, with
+// their distance from the base measured with mode, and the zone name.
+func FilterAircraftInZones(aircraft []Aircraft, baseLat, baseLon float64, match func(Aircraft) (string, bool), mode geo.Mode) []NearbyAircraft {
+	var result []NearbyAircraft
+	for _, a := range aircraft {
+		if a.Lat == 0 && a.Lon == 0 {
+			continue
+		}
+		zone, ok := match(a)
+		if !ok {
+			continue
+		}
+		result = append(result, NearbyAircraft{Aircraft: a, DistanceKm: mode.DistanceKm(baseLat, baseLon, a.Lat, a.Lon), Zone: zone})
+	}
+	return result
 }
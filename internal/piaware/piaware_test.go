@@ -1,6 +1,8 @@
 package piaware
 
 import (
+	"context"
+	"encoding/json"
 	"io"
 	"net/http"
 	"net/http/httptest"
@@ -8,70 +10,6 @@ import (
 	"time"
 )
 
-func TestDistance(t *testing.T) {
-	tests := []struct {
-		name      string
-		lat1      float64
-		lon1      float64
-		lat2      float64
-		lon2      float64
-		expected  float64
-		tolerance float64
-	}{
-		{
-			name:      "same point",
-			lat1:      0,
-			lon1:      0,
-			lat2:      0,
-			lon2:      0,
-			expected:  0,
-			tolerance: 0.001,
-		},
-		{
-			name:      "different points",
-			lat1:      37.6213,
-			lon1:      -122.3790,
-			lat2:      37.7213,
-			lon2:      -122.3790,
-			expected:  11.1, // Approximately 11.1 km
-			tolerance: 1.0,
-		},
-		{
-			name:      "antipodal points",
-			lat1:      0,
-			lon1:      0,
-			lat2:      0,
-			lon2:      180,
-			expected:  20015.1, // Half the Earth's circumference
-			tolerance: 100.0,
-		},
-		{
-			name:      "north pole to south pole",
-			lat1:      90,
-			lon1:      0,
-			lat2:      -90,
-			lon2:      0,
-			expected:  20015.1, // Half the Earth's circumference
-			tolerance: 100.0,
-		},
-	}
-
-	for _, tt := range tests {
-		t.Run(tt.name, func(t *testing.T) {
-			result := distance(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
-			if tt.name == "same point" {
-				if result > tt.tolerance {
-					t.Errorf("distance() = %v, expected close to 0", result)
-				}
-			} else {
-				if result < tt.expected-tt.tolerance || result > tt.expected+tt.tolerance {
-					t.Errorf("distance() = %v, expected close to %v (tolerance: %v)", result, tt.expected, tt.tolerance)
-				}
-			}
-		})
-	}
-}
-
 func TestFilterAircraft(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -300,7 +238,11 @@ func TestFetch(t *testing.T) {
 			}))
 			defer srv.Close()
 
-			ac, err := Fetch(srv.URL)
+			f, err := NewHTTPFetcher(HTTPConfig{})
+			if err != nil {
+				t.Fatalf("NewHTTPFetcher() error = %v", err)
+			}
+			ac, err := f.Fetch(context.Background(), srv.URL)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("Fetch() error = %v, wantErr %v (%s)", err, tt.wantErr, tt.description)
 				return
@@ -345,8 +287,202 @@ func TestFetchTimeout(t *testing.T) {
 	}))
 	defer srv.Close()
 
-	_, err := Fetch(srv.URL)
-	if err == nil {
+	f, err := NewHTTPFetcher(HTTPConfig{})
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher() error = %v", err)
+	}
+	if _, err := f.Fetch(context.Background(), srv.URL); err == nil {
 		t.Fatal("expected timeout error")
 	}
 }
+
+func TestAircraftUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantErr      bool
+		wantAltBaro  int
+		wantOnGround bool
+	}{
+		{
+			name:        "numeric altitude",
+			input:       `{"hex":"abc","alt_baro":3250}`,
+			wantAltBaro: 3250,
+		},
+		{
+			name:         "ground altitude",
+			input:        `{"hex":"abc","alt_baro":"ground"}`,
+			wantAltBaro:  0,
+			wantOnGround: true,
+		},
+		{
+			name:         "re-encoded ground aircraft",
+			input:        `{"hex":"abc","alt_baro":0,"on_ground":true}`,
+			wantAltBaro:  0,
+			wantOnGround: true,
+		},
+		{
+			name:        "missing altitude",
+			input:       `{"hex":"abc"}`,
+			wantAltBaro: 0,
+		},
+		{
+			name:        "null altitude",
+			input:       `{"hex":"abc","alt_baro":null}`,
+			wantAltBaro: 0,
+		},
+		{
+			name:        "fractional altitude",
+			input:       `{"hex":"abc","alt_baro":1024.6}`,
+			wantAltBaro: 1025,
+		},
+		{
+			name:    "unknown string altitude",
+			input:   `{"hex":"abc","alt_baro":"high"}`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid altitude type",
+			input:   `{"hex":"abc","alt_baro":true}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var a Aircraft
+			err := json.Unmarshal([]byte(tt.input), &a)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if a.Hex != "abc" {
+				t.Errorf("expected hex abc, got %s", a.Hex)
+			}
+			if a.AltBaro != tt.wantAltBaro {
+				t.Errorf("expected alt_baro %d, got %d", tt.wantAltBaro, a.AltBaro)
+			}
+			if a.OnGround != tt.wantOnGround {
+				t.Errorf("expected on_ground %v, got %v", tt.wantOnGround, a.OnGround)
+			}
+		})
+	}
+}
+
+func TestFetchFullSchema(t *testing.T) {
+	response := `{"now":1700000000.5,"messages":1234,"aircraft":[
+		{"hex":"a1b2c3","type":"adsb_icao","flight":"UAL123  ","alt_baro":8000,"alt_geom":8225,"gs":250.4,"track":271.3,
+		 "baro_rate":-640,"squawk":"1200","emergency":"none","category":"A3","lat":37.7,"lon":-122.4,
+		 "nic":8,"nac_p":9,"version":2,"mlat":[],"tisb":[],"messages":512,"seen":0.4,"seen_pos":1.2,"rssi":-18.5},
+		{"hex":"d4e5f6","flight":"N123AB  ","alt_baro":"ground","gs":12.1,"lat":37.61,"lon":-122.38,"seen":2.0}
+	]}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.WriteString(w, response); err != nil {
+			t.Fatalf("write: %v", err)
+		}
+	}))
+	defer srv.Close()
+
+	f, err := NewHTTPFetcher(HTTPConfig{})
+	if err != nil {
+		t.Fatalf("NewHTTPFetcher() error = %v", err)
+	}
+	data, err := f.FetchData(context.Background(), srv.URL)
+	if err != nil {
+		t.Fatalf("FetchData() error = %v", err)
+	}
+	if data.Now != 1700000000.5 {
+		t.Errorf("expected now 1700000000.5, got %v", data.Now)
+	}
+	if data.Messages != 1234 {
+		t.Errorf("expected messages 1234, got %d", data.Messages)
+	}
+	if len(data.Aircraft) != 2 {
+		t.Fatalf("expected 2 aircraft, got %d", len(data.Aircraft))
+	}
+
+	a := data.Aircraft[0]
+	if a.GS != 250.4 || a.Track != 271.3 || a.BaroRate != -640 || a.AltGeom != 8225 {
+		t.Errorf("unexpected kinematics: gs=%v track=%v baro_rate=%v alt_geom=%v", a.GS, a.Track, a.BaroRate, a.AltGeom)
+	}
+	if a.Squawk != "1200" || a.Category != "A3" || a.Emergency != "none" {
+		t.Errorf("unexpected identification: squawk=%q category=%q emergency=%q", a.Squawk, a.Category, a.Emergency)
+	}
+	if a.RSSI != -18.5 || a.Seen != 0.4 || a.SeenPos != 1.2 {
+		t.Errorf("unexpected reception: rssi=%v seen=%v seen_pos=%v", a.RSSI, a.Seen, a.SeenPos)
+	}
+
+	ground := data.Aircraft[1]
+	if !ground.OnGround || ground.AltBaro != 0 {
+		t.Errorf("expected aircraft on ground, got on_ground=%v alt_baro=%d", ground.OnGround, ground.AltBaro)
+	}
+}
+
+func TestPositionAger(t *testing.T) {
+	var ager PositionAger
+	snapshot := func(now float64) *Data {
+		return &Data{
+			Now: now,
+			Aircraft: []Aircraft{
+				{Hex: "aaa111", Lat: 40.1, Lon: -74.1, SeenPos: 2},
+				{Hex: "bbb222", Seen: 1},
+			},
+		}
+	}
+
+	// The host clock is a minute ahead of the receiver; that does not age
+	// positions from a feed that is still updating.
+	data := snapshot(1000)
+	ager.Age(data, time.Unix(1060, 0))
+	if data.Aircraft[0].PositionAge != 2 {
+		t.Errorf("expected position age 2s, got %v", data.Aircraft[0].PositionAge)
+	}
+	if data.Aircraft[1].PositionAge != 0 {
+		t.Errorf("expected no position age without a position, got %v", data.Aircraft[1].PositionAge)
+	}
+
+	data = snapshot(1001)
+	ager.Age(data, time.Unix(1061, 0))
+	if data.Aircraft[0].PositionAge != 2 {
+		t.Errorf("expected position age 2s, got %v", data.Aircraft[0].PositionAge)
+	}
+
+	// A snapshot that stopped updating keeps getting older.
+	data = snapshot(1001)
+	ager.Age(data, time.Unix(1066, 0))
+	if data.Aircraft[0].PositionAge != 7 {
+		t.Errorf("expected position age 7s, got %v", data.Aircraft[0].PositionAge)
+	}
+
+	// Without a feed clock only seen_pos counts.
+	var unclocked PositionAger
+	data = snapshot(0)
+	unclocked.Age(data, time.Unix(1000, 0))
+	unclocked.Age(data, time.Unix(1100, 0))
+	if data.Aircraft[0].PositionAge != 2 {
+		t.Errorf("expected position age 2s, got %v", data.Aircraft[0].PositionAge)
+	}
+}
+
+func TestFreshAircraft(t *testing.T) {
+	aircraft := []Aircraft{
+		{Hex: "fresh", Lat: 40.1, Lon: -74.1, PositionAge: 3},
+		{Hex: "stale", Lat: 40.2, Lon: -74.2, PositionAge: 45},
+		{Hex: "nopos"},
+	}
+
+	fresh, stale := FreshAircraft(aircraft, 30*time.Second)
+	if stale != 1 {
+		t.Errorf("expected 1 stale aircraft, got %d", stale)
+	}
+	if len(fresh) != 2 || fresh[0].Hex != "fresh" || fresh[1].Hex != "nopos" {
+		t.Errorf("unexpected fresh aircraft %+v", fresh)
+	}
+
+	if all, stale := FreshAircraft(aircraft, 0); len(all) != 3 || stale != 0 {
+		t.Errorf("expected check to be disabled, got %d aircraft and %d stale", len(all), stale)
+	}
+}
@@ -0,0 +1,86 @@
+package piaware
+
+import (
+	"math"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/geo"
+)
+
+// knotsToKmPerSecond converts ground speed in knots to km/s.
+const knotsToKmPerSecond = 1.852 / 3600
+
+// Approach is an aircraft's predicted closest point of approach to a
+// location, assuming it holds its current ground speed and track.
+type Approach struct {
+	// DistanceKm is the distance of the reported position.
+	DistanceKm float64
+	// MinDistanceKm is the predicted distance at the closest point.
+	MinDistanceKm float64
+	// ETA is the time from now until the closest point.
+	ETA time.Duration
+	// EnterIn is the time from now until the aircraft is predicted to come
+	// within the radius. It is zero if it is already inside.
+	EnterIn time.Duration
+	// Lat and Lon are the predicted position at the closest point.
+	Lat float64
+	Lon float64
+}
+
+// PredictApproach projects a's path along its track at its ground speed and
+// returns its closest point of approach to (baseLat, baseLon), and when it
+// comes within radiusKm. The projection starts from the position's age, so
+// an old position is carried forward to now. It reports false if the
+// aircraft has no position, is not moving, has already passed its closest
+// point or will not come within radiusKm.
+//
+// The path is projected on a plane tangent at the base, which is accurate
+// over the tens of kilometres the monitor looks at.
+func PredictApproach(a Aircraft, baseLat, baseLon, radiusKm float64) (Approach, bool) {
+	if (a.Lat == 0 && a.Lon == 0) || a.GS <= 0 {
+		return Approach{}, false
+	}
+
+	// Position (km) relative to the base and velocity (km/s).
+	cosLat := math.Cos(baseLat * math.Pi / 180)
+	x := (a.Lon - baseLon) * math.Pi / 180 * cosLat * geo.EarthRadiusKm
+	y := (a.Lat - baseLat) * math.Pi / 180 * geo.EarthRadiusKm
+	speed := a.GS * knotsToKmPerSecond
+	track := a.Track * math.Pi / 180
+	vx := speed * math.Sin(track)
+	vy := speed * math.Cos(track)
+
+	// Seconds after the position report at which the distance is smallest.
+	pv := x*vx + y*vy
+	vv := vx*vx + vy*vy
+	tMin := -pv / vv
+	if tMin <= a.PositionAge {
+		return Approach{}, false
+	}
+
+	cx := x + vx*tMin
+	cy := y + vy*tMin
+	minDist := math.Hypot(cx, cy)
+	if minDist > radiusKm {
+		return Approach{}, false
+	}
+
+	// First time the path crosses the radius.
+	pp := x*x + y*y
+	tEnter := (-pv - math.Sqrt(pv*pv-vv*(pp-radiusKm*radiusKm))) / vv
+	enterIn := math.Max(0, tEnter-a.PositionAge)
+
+	return Approach{
+		DistanceKm:    math.Hypot(x, y),
+		MinDistanceKm: minDist,
+		ETA:           seconds(tMin - a.PositionAge),
+		EnterIn:       seconds(enterIn),
+		Lat:           baseLat + cy/geo.EarthRadiusKm*180/math.Pi,
+		Lon:           baseLon + cx/(geo.EarthRadiusKm*cosLat)*180/math.Pi,
+	}, true
+}
+
+// seconds converts fractional seconds to a duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
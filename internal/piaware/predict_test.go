@@ -0,0 +1,93 @@
+package piaware
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/geo"
+)
+
+func TestPredictApproach(t *testing.T) {
+	const (
+		baseLat = 40.0
+		baseLon = -74.0
+	)
+	// 0.18 degrees of latitude is about 20 km, which takes 108s at 360 kt.
+	tests := []struct {
+		name        string
+		aircraft    Aircraft
+		ok          bool
+		minDistance float64
+		eta         time.Duration
+		enterIn     time.Duration
+	}{
+		{
+			name:        "heading straight for the base",
+			aircraft:    Aircraft{Hex: "a1", Lat: baseLat - 0.18, Lon: baseLon, GS: 360, Track: 0},
+			ok:          true,
+			minDistance: 0,
+			eta:         108 * time.Second,
+			enterIn:     54 * time.Second,
+		},
+		{
+			name:        "old position carried forward",
+			aircraft:    Aircraft{Hex: "a2", Lat: baseLat - 0.18, Lon: baseLon, GS: 360, Track: 0, PositionAge: 30},
+			ok:          true,
+			minDistance: 0,
+			eta:         78 * time.Second,
+			enterIn:     24 * time.Second,
+		},
+		{
+			name:        "passes abeam inside the radius",
+			aircraft:    Aircraft{Hex: "a3", Lat: baseLat - 0.18, Lon: baseLon + 0.0587, GS: 360, Track: 0},
+			ok:          true,
+			minDistance: 5,
+			eta:         108 * time.Second,
+			enterIn:     61 * time.Second,
+		},
+		{
+			name:     "passes wide of the radius",
+			aircraft: Aircraft{Hex: "a4", Lat: baseLat - 0.18, Lon: baseLon + 0.2, GS: 360, Track: 0},
+		},
+		{
+			name:     "flying away",
+			aircraft: Aircraft{Hex: "a5", Lat: baseLat - 0.18, Lon: baseLon, GS: 360, Track: 180},
+		},
+		{
+			name:     "not moving",
+			aircraft: Aircraft{Hex: "a6", Lat: baseLat - 0.18, Lon: baseLon},
+		},
+		{
+			name:     "no position",
+			aircraft: Aircraft{Hex: "a7", GS: 360},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			approach, ok := PredictApproach(tt.aircraft, baseLat, baseLon, 10)
+			if ok != tt.ok {
+				t.Fatalf("expected ok %v, got %v (%+v)", tt.ok, ok, approach)
+			}
+			if !ok {
+				return
+			}
+			if math.Abs(approach.MinDistanceKm-tt.minDistance) > 0.1 {
+				t.Errorf("expected min distance %.1f km, got %.2f", tt.minDistance, approach.MinDistanceKm)
+			}
+			if d := approach.ETA - tt.eta; d < -2*time.Second || d > 2*time.Second {
+				t.Errorf("expected ETA %s, got %s", tt.eta, approach.ETA)
+			}
+			if d := approach.EnterIn - tt.enterIn; d < -2*time.Second || d > 2*time.Second {
+				t.Errorf("expected to enter in %s, got %s", tt.enterIn, approach.EnterIn)
+			}
+			if math.Abs(approach.DistanceKm-geo.HaversineKm(baseLat, baseLon, tt.aircraft.Lat, tt.aircraft.Lon)) > 0.1 {
+				t.Errorf("unexpected current distance %.2f", approach.DistanceKm)
+			}
+			if math.Abs(approach.Lat-baseLat) > 0.01 {
+				t.Errorf("expected closest point abeam the base, got %.4f,%.4f", approach.Lat, approach.Lon)
+			}
+		})
+	}
+}
@@ -0,0 +1,303 @@
+// Package registry looks up aircraft details, such as the registration and
+// type, in a local aircraft database file, and reloads the file when it
+// changes.
+package registry
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/csvfile"
+	"github.com/benvon/whats-flying-over-me/internal/logger"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// Config holds aircraft database settings.
+type Config struct {
+	// File is the aircraft database; empty disables enrichment.
+	File string
+	// ReloadInterval is how often the file is checked for changes. Zero
+	// disables reloading.
+	ReloadInterval time.Duration
+}
+
+// Record is what the database knows about an aircraft.
+type Record struct {
+	Registration string
+	TypeCode     string
+	Manufacturer string
+	Model        string
+	Operator     string
+	Year         string
+}
+
+// Database is an aircraft database keyed by ICAO hex. It is safe for
+// concurrent use.
+type Database struct {
+	path string
+
+	mutex   sync.RWMutex
+	records map[string]Record
+	last    os.FileInfo
+}
+
+// Load reads the aircraft database at path. Two formats are understood, and
+// either may be gzip compressed:
+//
+//   - the tar1090-db aircraft.csv, with semicolon separated
+//     icao;registration;type;flags;description;year;owner lines and no
+//     header.
+//   - a comma separated export with a header row, such as a BaseStation or
+//     OpenSky aircraft database export. Columns are found by name: ModeS or
+//     icao24, Registration, ICAOTypeCode or typecode, Manufacturer or
+//     manufacturername, Type or model, RegisteredOwners, operator or owner,
+//     and YearBuilt or built.
+func Load(path string) (*Database, error) {
+	d := &Database{path: path}
+	if _, err := d.reload(); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Len returns the number of aircraft in the database.
+func (d *Database) Len() int {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	return len(d.records)
+}
+
+// Lookup returns the record for an ICAO hex, matched regardless of case. It
+// reports false for non-ICAO addresses, which readsb prefixes with "~", as
+// they do not identify an airframe.
+func (d *Database) Lookup(hex string) (Record, bool) {
+	if nonICAO(hex) {
+		return Record{}, false
+	}
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	r, ok := d.records[normalizeHex(hex)]
+	return r, ok
+}
+
+// Enrich fills in the registry fields of each aircraft that the feed left
+// empty. Aircraft with non-ICAO addresses are left alone.
+func (d *Database) Enrich(aircraft []piaware.Aircraft) {
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+	for i := range aircraft {
+		if nonICAO(aircraft[i].Hex) {
+			continue
+		}
+		r, ok := d.records[normalizeHex(aircraft[i].Hex)]
+		if !ok {
+			continue
+		}
+		a := &aircraft[i]
+		fill(&a.Registration, r.Registration)
+		fill(&a.TypeCode, r.TypeCode)
+		fill(&a.Manufacturer, r.Manufacturer)
+		fill(&a.Model, r.Model)
+		fill(&a.Operator, r.Operator)
+		fill(&a.Year, r.Year)
+	}
+}
+
+// Start reloads the database in the background whenever the file changes,
+// checking every interval until ctx is cancelled. A file that cannot be read
+// or parsed is logged and the previous records are kept.
+func (d *Database) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	go d.watch(ctx, interval)
+}
+
+func (d *Database) watch(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		reloaded, err := d.reload()
+		if err != nil {
+			logger.Warn("failed to reload aircraft database", map[string]interface{}{
+				"path":  d.path,
+				"error": err.Error(),
+			})
+			continue
+		}
+		if reloaded {
+			logger.Info("reloaded aircraft database", map[string]interface{}{
+				"path":     d.path,
+				"aircraft": d.Len(),
+			})
+		}
+	}
+}
+
+// reload reads the file if it has changed since it was last read, and
+// reports whether it did.
+func (d *Database) reload() (bool, error) {
+	// #nosec G304 -- path is supplied by the operator
+	file, err := os.Open(d.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to open aircraft database: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return false, fmt.Errorf("failed to stat aircraft database: %w", err)
+	}
+	d.mutex.RLock()
+	last := d.last
+	d.mutex.RUnlock()
+	if last != nil && os.SameFile(last, info) && last.ModTime().Equal(info.ModTime()) && last.Size() == info.Size() {
+		return false, nil
+	}
+
+	records, err := Parse(file)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", d.path, err)
+	}
+
+	d.mutex.Lock()
+	d.records = records
+	d.last = info
+	d.mutex.Unlock()
+	return true, nil
+}
+
+// Parse reads an aircraft database in either format described by Load,
+// returning the records by lower-case ICAO hex.
+func Parse(r io.Reader) (map[string]Record, error) {
+	br := bufio.NewReader(r)
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		br = bufio.NewReader(gz)
+	}
+
+	first, err := br.Peek(4096)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, fmt.Errorf("failed to read aircraft database: %w", err)
+	}
+	line, _, _ := strings.Cut(string(first), "\n")
+	if strings.Count(line, ";") > strings.Count(line, ",") {
+		return parseTar1090(br)
+	}
+	return parseExport(br)
+}
+
+// parseTar1090 parses tar1090-db lines.
+func parseTar1090(r io.Reader) (map[string]Record, error) {
+	records := make(map[string]Record)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Split(scanner.Text(), ";")
+		if len(fields) < 3 || fields[0] == "" {
+			continue
+		}
+		// icao;registration;type;flags;description;year;owner
+		field := func(i int) string {
+			if i < len(fields) {
+				return strings.TrimSpace(fields[i])
+			}
+			return ""
+		}
+		records[normalizeHex(fields[0])] = Record{
+			Registration: field(1),
+			TypeCode:     field(2),
+			Model:        field(4),
+			Year:         field(5),
+			Operator:     field(6),
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read aircraft database: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no aircraft found")
+	}
+	return records, nil
+}
+
+// exportColumns are the accepted header names of each field, lower case.
+var exportColumns = map[string][]string{
+	"hex":          {"modes", "icao24", "icao", "hex"},
+	"registration": {"registration", "reg"},
+	"type":         {"icaotypecode", "typecode", "type_code"},
+	"manufacturer": {"manufacturer", "manufacturername"},
+	"model":        {"type", "model"},
+	"operator":     {"registeredowners", "operator", "owner"},
+	"year":         {"yearbuilt", "year", "built"},
+}
+
+// parseExport parses a comma separated export with a header row.
+func parseExport(r io.Reader) (map[string]Record, error) {
+	records := make(map[string]Record)
+	err := csvfile.Read(r, exportColumns, []string{"hex"}, func(field func(string) string) {
+		hex := field("hex")
+		if hex == "" {
+			return
+		}
+		year := field("year")
+		if len(year) > 4 {
+			// OpenSky's built column is a date.
+			year = year[:4]
+		}
+		records[normalizeHex(hex)] = Record{
+			Registration: field("registration"),
+			TypeCode:     field("type"),
+			Manufacturer: field("manufacturer"),
+			Model:        field("model"),
+			Operator:     field("operator"),
+			Year:         year,
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aircraft database: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, errors.New("no aircraft found")
+	}
+	return records, nil
+}
+
+func normalizeHex(hex string) string {
+	return strings.ToLower(strings.TrimSpace(hex))
+}
+
+// nonICAO reports whether hex is a non-ICAO address, such as a TIS-B or
+// anonymous one, which readsb prefixes with "~".
+func nonICAO(hex string) bool {
+	return strings.HasPrefix(strings.TrimSpace(hex), "~")
+}
+
+// fill sets *field to value when it is empty.
+func fill(field *string, value string) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -0,0 +1,203 @@
+package registry
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+const tar1090DB = `a835af;N628TS;GLF6;00;GULFSTREAM AEROSPACE G-650;2014;TVPX AIRCRAFT SOLUTIONS INC TRUSTEE
+ae01ce;;C130;01;LOCKHEED C-130H HERCULES;;
+A1B2C3;N12345;B738;00;BOEING 737-800;2005;UNITED AIRLINES INC
+`
+
+const baseStationDB = `ModeS,ModeSCountry,Registration,ICAOTypeCode,Manufacturer,Type,SerialNo,YearBuilt,RegisteredOwners
+4CA7B5,Ireland,EI-DCL,B738,Boeing,737-8AS,33544,2004,Ryanair
+406A93,United Kingdom,G-EUPT,A319,Airbus,A319-131,1380,2000,"British Airways, PLC"
+`
+
+const openSkyDB = `"icao24","registration","manufacturericao","manufacturername","model","typecode","operator","owner","built"
+"3c6444","D-AIBD","AIRBUS","Airbus","A319 112","A319","Lufthansa","","2010-01-01"
+`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		hex  string
+		want Record
+	}{
+		{
+			name: "tar1090-db",
+			data: tar1090DB,
+			hex:  "a1b2c3",
+			want: Record{Registration: "N12345", TypeCode: "B738", Model: "BOEING 737-800", Operator: "UNITED AIRLINES INC", Year: "2005"},
+		},
+		{
+			name: "tar1090-db without registration",
+			data: tar1090DB,
+			hex:  "ae01ce",
+			want: Record{TypeCode: "C130", Model: "LOCKHEED C-130H HERCULES"},
+		},
+		{
+			name: "BaseStation export",
+			data: baseStationDB,
+			hex:  "406a93",
+			want: Record{Registration: "G-EUPT", TypeCode: "A319", Manufacturer: "Airbus", Model: "A319-131", Operator: "British Airways, PLC", Year: "2000"},
+		},
+		{
+			name: "OpenSky export",
+			data: openSkyDB,
+			hex:  "3c6444",
+			want: Record{Registration: "D-AIBD", TypeCode: "A319", Manufacturer: "Airbus", Model: "A319 112", Operator: "Lufthansa", Year: "2010"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := Parse(strings.NewReader(tt.data))
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if got := records[tt.hex]; got != tt.want {
+				t.Errorf("record %s = %+v, want %+v", tt.hex, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(tar1090DB)); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("gzip: %v", err)
+	}
+
+	records, err := Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if len(records) != 3 || records["a835af"].Registration != "N628TS" {
+		t.Errorf("unexpected records %+v", records)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"empty", "", "header"},
+		{"no hex column", "Registration,Type\nN1,C172\n", "no modes or icao24 or icao or hex column"},
+		{"header only", "ModeS,Registration\n", "no aircraft found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDatabaseLookupAndEnrich(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aircraft.csv")
+	if err := os.WriteFile(path, []byte(tar1090DB), 0o600); err != nil {
+		t.Fatalf("write database: %v", err)
+	}
+	db, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if db.Len() != 3 {
+		t.Errorf("expected 3 aircraft, got %d", db.Len())
+	}
+	if r, ok := db.Lookup("A835AF"); !ok || r.TypeCode != "GLF6" {
+		t.Errorf("Lookup() = %+v, %v", r, ok)
+	}
+	if r, ok := db.Lookup("~a835af"); ok {
+		t.Errorf("expected no record for a non-ICAO address, got %+v", r)
+	}
+	if _, ok := db.Lookup("000000"); ok {
+		t.Error("expected no record for an unknown hex")
+	}
+
+	aircraft := []piaware.Aircraft{
+		{Hex: "a1b2c3"},
+		// Details reported by the feed are kept.
+		{Hex: "a835af", Registration: "N628TS", Operator: "From the feed"},
+		{Hex: "ffffff"},
+		// A TIS-B target is not the airframe with the same address.
+		{Hex: "~a1b2c3"},
+	}
+	db.Enrich(aircraft)
+	if a := aircraft[0]; a.Registration != "N12345" || a.TypeCode != "B738" || a.Model != "BOEING 737-800" || a.Operator != "UNITED AIRLINES INC" || a.Year != "2005" {
+		t.Errorf("unexpected enriched aircraft %+v", a)
+	}
+	if a := aircraft[1]; a.Operator != "From the feed" || a.TypeCode != "GLF6" {
+		t.Errorf("expected feed details to be kept, got %+v", a)
+	}
+	if a := aircraft[2]; a.Registration != "" {
+		t.Errorf("expected unknown aircraft to be left alone, got %+v", a)
+	}
+	if a := aircraft[3]; a.Registration != "" || a.TypeCode != "" {
+		t.Errorf("expected non-ICAO aircraft to be left alone, got %+v", a)
+	}
+}
+
+func TestDatabaseReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "aircraft.csv")
+	if err := os.WriteFile(path, []byte(tar1090DB), 0o600); err != nil {
+		t.Fatalf("write database: %v", err)
+	}
+	db, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	db.Start(ctx, 10*time.Millisecond)
+
+	// A broken file is ignored and the previous records kept.
+	if err := os.WriteFile(path, []byte("Registration\n"), 0o600); err != nil {
+		t.Fatalf("write database: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if db.Len() != 3 {
+		t.Fatalf("expected the previous records after a bad reload, got %d", db.Len())
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte("c0ffee;C-GABC;DH8D;00;DE HAVILLAND DHC-8-400;2009;PORTER\n"), 0o600); err != nil {
+		t.Fatalf("write database: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("rename: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if r, ok := db.Lookup("c0ffee"); ok {
+			if r.Registration != "C-GABC" || db.Len() != 1 {
+				t.Errorf("unexpected reloaded database: %+v, %d aircraft", r, db.Len())
+			}
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("database was not reloaded")
+}
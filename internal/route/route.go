@@ -0,0 +1,226 @@
+// Package route looks up the origin and destination of a flight by its
+// callsign in a local routes file, naming the airports from an optional
+// airports file.
+package route
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/benvon/whats-flying-over-me/internal/csvfile"
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// Config holds route lookup settings.
+type Config struct {
+	// File is the routes file; empty disables route lookup.
+	File string
+	// AirportsFile names the airports in the routes file. Without it,
+	// airports are known by their code only.
+	AirportsFile string
+}
+
+// Airport is an airport on a route.
+type Airport struct {
+	ICAO string
+	IATA string
+	Name string
+}
+
+// Code returns the IATA code of the airport when known, as passengers know
+// it, and its ICAO code otherwise.
+func (a Airport) Code() string {
+	if a.IATA != "" {
+		return a.IATA
+	}
+	return a.ICAO
+}
+
+// Route is the airports a flight calls at, in order. It has at least two.
+type Route []Airport
+
+// Origin returns the first airport of the route.
+func (r Route) Origin() Airport {
+	return r[0]
+}
+
+// Destination returns the last airport of the route.
+func (r Route) Destination() Airport {
+	return r[len(r)-1]
+}
+
+// Table maps callsigns to routes. It is not modified once loaded, so it is
+// safe for concurrent use.
+type Table struct {
+	routes map[string][]string
+	// airports are keyed by both ICAO and IATA code.
+	airports map[string]Airport
+}
+
+// Load reads the routes file, and the airports file when configured. Both are
+// comma separated with a header row, as in the standing data published by
+// the Virtual Radar Server and adsb-db projects:
+//
+//   - routes.csv, where the Callsign column is matched and AirportCodes lists
+//     the airports of the route separated by "-", such as "KSFO-KORD".
+//   - airports.csv, with Code or ICAO, IATA and Name columns. The ident,
+//     iata_code and name columns of an OurAirports export are also accepted.
+func Load(cfg Config) (*Table, error) {
+	t := &Table{airports: make(map[string]Airport)}
+	err := readFile(cfg.File, func(r io.Reader) error {
+		routes, err := ParseRoutes(r)
+		t.routes = routes
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if cfg.AirportsFile != "" {
+		err := readFile(cfg.AirportsFile, func(r io.Reader) error {
+			airports, err := ParseAirports(r)
+			t.airports = airports
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Len returns the number of routes in the table.
+func (t *Table) Len() int {
+	return len(t.routes)
+}
+
+// Lookup returns the route flown under a callsign, ignoring case and padding.
+func (t *Table) Lookup(callsign string) (Route, bool) {
+	codes, ok := t.routes[strings.ToUpper(strings.TrimSpace(callsign))]
+	if !ok {
+		return nil, false
+	}
+	route := make(Route, len(codes))
+	for i, code := range codes {
+		route[i] = t.airport(code)
+	}
+	return route, true
+}
+
+// Enrich sets the origin and destination of each aircraft whose callsign has
+// a route. Callsign must already be set.
+func (t *Table) Enrich(aircraft []piaware.Aircraft) {
+	for i := range aircraft {
+		a := &aircraft[i]
+		if a.Callsign == "" {
+			continue
+		}
+		route, ok := t.Lookup(a.Callsign)
+		if !ok {
+			continue
+		}
+		origin, destination := route.Origin(), route.Destination()
+		a.Origin = origin.Code()
+		a.OriginName = origin.Name
+		a.Destination = destination.Code()
+		a.DestinationName = destination.Name
+	}
+}
+
+// airport returns the named airport, or one known only by code when the
+// airports file does not have it.
+func (t *Table) airport(code string) Airport {
+	if a, ok := t.airports[code]; ok {
+		return a
+	}
+	if len(code) == 3 {
+		return Airport{IATA: code}
+	}
+	return Airport{ICAO: code}
+}
+
+// routeColumns and airportColumns are the accepted header names of each
+// field, lower case.
+var (
+	routeColumns = map[string][]string{
+		"callsign": {"callsign"},
+		"airports": {"airportcodes", "airport_codes", "route"},
+	}
+	airportColumns = map[string][]string{
+		"icao": {"icao", "code", "ident", "gps_code"},
+		"iata": {"iata", "iata_code"},
+		"name": {"name"},
+	}
+)
+
+// ParseRoutes reads a routes file described by Load, returning the airport
+// codes of each route by upper-case callsign. Routes with fewer than two
+// airports are skipped.
+func ParseRoutes(r io.Reader) (map[string][]string, error) {
+	routes := make(map[string][]string)
+	err := csvfile.Read(r, routeColumns, []string{"callsign", "airports"}, func(field func(string) string) {
+		callsign := strings.ToUpper(field("callsign"))
+		var codes []string
+		for _, code := range strings.Split(field("airports"), "-") {
+			if code = strings.ToUpper(strings.TrimSpace(code)); code != "" {
+				codes = append(codes, code)
+			}
+		}
+		if callsign == "" || len(codes) < 2 {
+			return
+		}
+		routes[callsign] = codes
+	})
+	if err != nil {
+		return nil, fmt.Errorf("routes: %w", err)
+	}
+	if len(routes) == 0 {
+		return nil, errors.New("routes: no routes found")
+	}
+	return routes, nil
+}
+
+// ParseAirports reads an airports file described by Load, returning the
+// airports by upper-case ICAO and IATA code.
+func ParseAirports(r io.Reader) (map[string]Airport, error) {
+	airports := make(map[string]Airport)
+	err := csvfile.Read(r, airportColumns, []string{"icao"}, func(field func(string) string) {
+		a := Airport{
+			ICAO: strings.ToUpper(field("icao")),
+			IATA: strings.ToUpper(field("iata")),
+			Name: field("name"),
+		}
+		if a.ICAO == "" {
+			return
+		}
+		airports[a.ICAO] = a
+		if a.IATA != "" {
+			airports[a.IATA] = a
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("airports: %w", err)
+	}
+	if len(airports) == 0 {
+		return nil, errors.New("airports: no airports found")
+	}
+	return airports, nil
+}
+
+// readFile opens the file at path and passes it to parse.
+func readFile(path string, parse func(io.Reader) error) error {
+	// #nosec G304 -- path is supplied by the operator
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	if err := parse(file); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package route
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+const routesCSV = `Callsign,Code,Number,AirlineCode,AirportCodes
+UAL123,UA,123,UAL,KSFO-KORD
+DLH400,LH,400,DLH,EDDF-KJFK
+BAW286,BA,286,BAW,EGLL-KSFO-EGLL
+EZY1,U2,1,EZY,EGKK
+`
+
+const airportsCSV = `Code,Name,ICAO,IATA,Location,CountryISO2,Latitude,Longitude,AltitudeFeet
+KSFO,San Francisco International Airport,KSFO,SFO,San Francisco,US,37.618999,-122.375,13
+KORD,Chicago O'Hare International Airport,KORD,ORD,Chicago,US,41.9786,-87.9048,672
+EGLL,London Heathrow Airport,EGLL,LHR,London,GB,51.4706,-0.461941,83
+`
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes(strings.NewReader(routesCSV))
+	if err != nil {
+		t.Fatalf("ParseRoutes() error = %v", err)
+	}
+	want := map[string][]string{
+		"UAL123": {"KSFO", "KORD"},
+		"DLH400": {"EDDF", "KJFK"},
+		"BAW286": {"EGLL", "KSFO", "EGLL"},
+	}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("ParseRoutes() = %v, want %v", routes, want)
+	}
+}
+
+func TestParseAirports(t *testing.T) {
+	ourAirports := "\"id\",\"ident\",\"type\",\"name\",\"iata_code\"\n3384,\"KSFO\",\"large_airport\",\"San Francisco International Airport\",\"SFO\"\n"
+	for name, data := range map[string]string{"standing data": airportsCSV, "OurAirports": ourAirports} {
+		t.Run(name, func(t *testing.T) {
+			airports, err := ParseAirports(strings.NewReader(data))
+			if err != nil {
+				t.Fatalf("ParseAirports() error = %v", err)
+			}
+			want := Airport{ICAO: "KSFO", IATA: "SFO", Name: "San Francisco International Airport"}
+			if airports["KSFO"] != want || airports["SFO"] != want {
+				t.Errorf("unexpected airports %+v", airports)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		parse func(string) error
+		data  string
+		want  string
+	}{
+		{"empty routes", parseRoutes, "", "routes: failed to read header"},
+		{"no airport codes column", parseRoutes, "Callsign,Code\nUAL123,UA\n", "routes: no airportcodes or airport_codes or route column"},
+		{"no routes", parseRoutes, "Callsign,AirportCodes\nEZY1,EGKK\n", "routes: no routes found"},
+		{"no code column", parseAirports, "Name,IATA\nSan Francisco,SFO\n", "airports: no icao or code or ident or gps_code column"},
+		{"no airports", parseAirports, "ICAO,Name\n", "airports: no airports found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.parse(tt.data)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func parseRoutes(data string) error {
+	_, err := ParseRoutes(strings.NewReader(data))
+	return err
+}
+
+func parseAirports(data string) error {
+	_, err := ParseAirports(strings.NewReader(data))
+	return err
+}
+
+func TestLoadAndEnrich(t *testing.T) {
+	dir := t.TempDir()
+	cfg := Config{File: filepath.Join(dir, "routes.csv"), AirportsFile: filepath.Join(dir, "airports.csv")}
+	if err := os.WriteFile(cfg.File, []byte(routesCSV), 0o600); err != nil {
+		t.Fatalf("write routes: %v", err)
+	}
+	if err := os.WriteFile(cfg.AirportsFile, []byte(airportsCSV), 0o600); err != nil {
+		t.Fatalf("write airports: %v", err)
+	}
+	table, err := Load(cfg)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if table.Len() != 3 {
+		t.Errorf("expected 3 routes, got %d", table.Len())
+	}
+
+	aircraft := []piaware.Aircraft{
+		{Hex: "a1b2c3", Callsign: "UAL123"},
+		// Airports missing from the airports file are known by code.
+		{Hex: "3c6444", Callsign: "DLH400"},
+		{Hex: "406a93", Callsign: "BAW286"},
+		{Hex: "a00001", Callsign: "N12345"},
+	}
+	table.Enrich(aircraft)
+	if a := aircraft[0]; a.Origin != "SFO" || a.OriginName != "San Francisco International Airport" || a.Destination != "ORD" || a.DestinationName != "Chicago O'Hare International Airport" {
+		t.Errorf("unexpected route %+v", a)
+	}
+	if a := aircraft[1]; a.Origin != "EDDF" || a.OriginName != "" || a.Destination != "KJFK" {
+		t.Errorf("unexpected route without airport names %+v", a)
+	}
+	if a := aircraft[2]; a.Origin != "LHR" || a.Destination != "LHR" {
+		t.Errorf("unexpected multi-leg route %+v", a)
+	}
+	if a := aircraft[3]; a.Origin != "" || a.Destination != "" {
+		t.Errorf("expected no route, got %+v", a)
+	}
+
+	if _, err := Load(Config{File: filepath.Join(dir, "missing.csv")}); err == nil {
+		t.Error("expected an error for a missing routes file")
+	}
+}
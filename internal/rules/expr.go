@@ -0,0 +1,467 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// kind is the type of an expression.
+type kind int
+
+const (
+	kindBool kind = iota
+	kindNumber
+	kindString
+)
+
+func (k kind) String() string {
+	switch k {
+	case kindNumber:
+		return "number"
+	case kindString:
+		return "string"
+	default:
+		return "bool"
+	}
+}
+
+// node is a compiled expression. Exactly the function matching its kind is
+// set.
+type node struct {
+	kind kind
+	b    func(*Subject) bool
+	n    func(*Subject) float64
+	s    func(*Subject) string
+	// literal is set for constants, which are the only operands allowed in
+	// lists and on the right of =~.
+	literal bool
+}
+
+// Expr is a compiled rule expression.
+type Expr struct {
+	source string
+	eval   func(*Subject) bool
+}
+
+// String returns the expression as written.
+func (e *Expr) String() string {
+	return e.source
+}
+
+// Match reports whether the subject satisfies the expression.
+func (e *Expr) Match(s *Subject) bool {
+	return e.eval(s)
+}
+
+// Compile parses an expression, checking that every field exists and that
+// operands have the right types. The grammar is:
+//
+//	expr       = or
+//	or         = and { "||" and }
+//	and        = unary { "&&" unary }
+//	unary      = "!" unary | comparison
+//	comparison = operand [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) operand
+//	                     | [ "not" ] "in" list
+//	                     | "=~" string ]
+//	operand    = field | number | string | "true" | "false" | "(" expr ")"
+//	list       = "[" [ literal { "," literal } ] "]"
+//
+// Strings are double or single quoted. String comparisons and lists ignore
+// case; =~ matches a regular expression, which can be made case-insensitive
+// with (?i).
+func Compile(source string) (*Expr, error) {
+	tokens, err := lex(source)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	if n.kind != kindBool {
+		return nil, fmt.Errorf("expression is a %s, not a condition", n.kind)
+	}
+	return &Expr{source: source, eval: n.b}, nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// operators are the operator tokens, longest first so "<=" is not read as
+// "<".
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "=~", "<", ">", "!", "(", ")", "[", "]", ","}
+
+func lex(source string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexRune(source[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at column %d", i+1)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: source[i+1 : i+1+end], pos: i})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.' || c == '-' && i+1 < len(source) && (source[i+1] >= '0' && source[i+1] <= '9' || source[i+1] == '.'):
+			start := i
+			i++
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: source[start:i], pos: start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(source) && (source[i] == '_' || unicode.IsLetter(rune(source[i])) || unicode.IsDigit(rune(source[i]))) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: source[start:i], pos: start})
+		default:
+			op := ""
+			for _, o := range operators {
+				if strings.HasPrefix(source[i:], o) {
+					op = o
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected %q at column %d", c, i+1)
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(source)}), nil
+}
+
+type parser struct {
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokenEOF {
+		p.next++
+	}
+	return t
+}
+
+// accept takes the next token if it is the operator or keyword text.
+func (p *parser) accept(text string) bool {
+	t := p.peek()
+	if (t.kind == tokenOp || t.kind == tokenIdent) && t.text == text {
+		p.next++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(text string) error {
+	if !p.accept(text) {
+		t := p.peek()
+		return p.errorf(t, "expected %q, found %s", text, t)
+	}
+	return nil
+}
+
+func (p *parser) errorf(t token, format string, args ...interface{}) error {
+	return fmt.Errorf("column %d: %s", t.pos+1, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) or() (node, error) {
+	left, err := p.and()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("||") {
+			return left, nil
+		}
+		right, err := p.and()
+		if err != nil {
+			return node{}, err
+		}
+		if left.kind != kindBool || right.kind != kindBool {
+			return node{}, p.errorf(t, "|| needs conditions on both sides")
+		}
+		l, r := left.b, right.b
+		left = node{kind: kindBool, b: func(s *Subject) bool { return l(s) || r(s) }}
+	}
+}
+
+func (p *parser) and() (node, error) {
+	left, err := p.unary()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("&&") {
+			return left, nil
+		}
+		right, err := p.unary()
+		if err != nil {
+			return node{}, err
+		}
+		if left.kind != kindBool || right.kind != kindBool {
+			return node{}, p.errorf(t, "&& needs conditions on both sides")
+		}
+		l, r := left.b, right.b
+		left = node{kind: kindBool, b: func(s *Subject) bool { return l(s) && r(s) }}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	t := p.peek()
+	if !p.accept("!") {
+		return p.comparison()
+	}
+	operand, err := p.unary()
+	if err != nil {
+		return node{}, err
+	}
+	if operand.kind != kindBool {
+		return node{}, p.errorf(t, "! needs a condition")
+	}
+	f := operand.b
+	return node{kind: kindBool, b: func(s *Subject) bool { return !f(s) }}, nil
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.operand()
+	if err != nil {
+		return node{}, err
+	}
+
+	t := p.peek()
+	switch {
+	case p.accept("in"):
+		return p.in(left, t, false)
+	case p.accept("not"):
+		if err := p.expect("in"); err != nil {
+			return node{}, err
+		}
+		return p.in(left, t, true)
+	case p.accept("=~"):
+		return p.matches(left, t)
+	}
+
+	if t.kind != tokenOp {
+		return left, nil
+	}
+	switch t.text {
+	case "==", "!=", "<", "<=", ">", ">=":
+		p.take()
+	default:
+		return left, nil
+	}
+	right, err := p.operand()
+	if err != nil {
+		return node{}, err
+	}
+	if left.kind != right.kind {
+		return node{}, p.errorf(t, "cannot compare %s with %s", left.kind, right.kind)
+	}
+	return compare(p, t, left, right)
+}
+
+func compare(p *parser, t token, left, right node) (node, error) {
+	op := t.text
+	switch left.kind {
+	case kindNumber:
+		l, r := left.n, right.n
+		var f func(a, b float64) bool
+		switch op {
+		case "==":
+			f = func(a, b float64) bool { return a == b }
+		case "!=":
+			f = func(a, b float64) bool { return a != b }
+		case "<":
+			f = func(a, b float64) bool { return a < b }
+		case "<=":
+			f = func(a, b float64) bool { return a <= b }
+		case ">":
+			f = func(a, b float64) bool { return a > b }
+		case ">=":
+			f = func(a, b float64) bool { return a >= b }
+		}
+		return node{kind: kindBool, b: func(s *Subject) bool { return f(l(s), r(s)) }}, nil
+	case kindString:
+		l, r := left.s, right.s
+		switch op {
+		case "==":
+			return node{kind: kindBool, b: func(s *Subject) bool { return strings.EqualFold(l(s), r(s)) }}, nil
+		case "!=":
+			return node{kind: kindBool, b: func(s *Subject) bool { return !strings.EqualFold(l(s), r(s)) }}, nil
+		}
+	case kindBool:
+		l, r := left.b, right.b
+		switch op {
+		case "==":
+			return node{kind: kindBool, b: func(s *Subject) bool { return l(s) == r(s) }}, nil
+		case "!=":
+			return node{kind: kindBool, b: func(s *Subject) bool { return l(s) != r(s) }}, nil
+		}
+	}
+	return node{}, p.errorf(t, "%s cannot be used with %ss", op, left.kind)
+}
+
+// in parses the list after "in" and tests membership of left.
+func (p *parser) in(left node, t token, negate bool) (node, error) {
+	if err := p.expect("["); err != nil {
+		return node{}, err
+	}
+	var numbers []float64
+	var texts []string
+	for !p.accept("]") {
+		if len(numbers)+len(texts) > 0 {
+			if err := p.expect(","); err != nil {
+				return node{}, err
+			}
+		}
+		et := p.peek()
+		element, err := p.operand()
+		if err != nil {
+			return node{}, err
+		}
+		if !element.literal {
+			return node{}, p.errorf(et, "lists may only hold numbers and strings")
+		}
+		if element.kind != left.kind {
+			return node{}, p.errorf(et, "cannot look for a %s in a list of %ss", left.kind, element.kind)
+		}
+		switch element.kind {
+		case kindNumber:
+			numbers = append(numbers, element.n(nil))
+		case kindString:
+			texts = append(texts, strings.ToUpper(element.s(nil)))
+		}
+	}
+
+	var f func(*Subject) bool
+	switch left.kind {
+	case kindNumber:
+		value := left.n
+		f = func(s *Subject) bool {
+			v := value(s)
+			for _, n := range numbers {
+				if v == n {
+					return true
+				}
+			}
+			return false
+		}
+	case kindString:
+		value := left.s
+		f = func(s *Subject) bool {
+			v := strings.ToUpper(value(s))
+			for _, text := range texts {
+				if v == text {
+					return true
+				}
+			}
+			return false
+		}
+	default:
+		return node{}, p.errorf(t, "in cannot be used with bools")
+	}
+	if negate {
+		return node{kind: kindBool, b: func(s *Subject) bool { return !f(s) }}, nil
+	}
+	return node{kind: kindBool, b: f}, nil
+}
+
+// matches parses the pattern after "=~".
+func (p *parser) matches(left node, t token) (node, error) {
+	if left.kind != kindString {
+		return node{}, p.errorf(t, "=~ needs a string on the left")
+	}
+	pt := p.peek()
+	if pt.kind != tokenString {
+		return node{}, p.errorf(pt, "=~ needs a quoted pattern, found %s", pt)
+	}
+	p.take()
+	re, err := regexp.Compile(pt.text)
+	if err != nil {
+		return node{}, p.errorf(pt, "invalid pattern: %v", err)
+	}
+	value := left.s
+	return node{kind: kindBool, b: func(s *Subject) bool { return re.MatchString(value(s)) }}, nil
+}
+
+func (p *parser) operand() (node, error) {
+	t := p.take()
+	switch t.kind {
+	case tokenNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return node{}, p.errorf(t, "invalid number %q", t.text)
+		}
+		return node{kind: kindNumber, n: func(*Subject) float64 { return v }, literal: true}, nil
+	case tokenString:
+		v := t.text
+		return node{kind: kindString, s: func(*Subject) string { return v }, literal: true}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true", "false":
+			v := t.text == "true"
+			return node{kind: kindBool, b: func(*Subject) bool { return v }, literal: true}, nil
+		}
+		f, ok := fields[t.text]
+		if !ok {
+			return node{}, p.errorf(t, "unknown field %q", t.text)
+		}
+		return f, nil
+	case tokenOp:
+		if t.text == "(" {
+			n, err := p.or()
+			if err != nil {
+				return node{}, err
+			}
+			if err := p.expect(")"); err != nil {
+				return node{}, err
+			}
+			return n, nil
+		}
+	}
+	return node{}, p.errorf(t, "unexpected %s", t)
+}
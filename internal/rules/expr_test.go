@@ -0,0 +1,90 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func TestCompileAndMatch(t *testing.T) {
+	jumbo := &Subject{
+		Aircraft:   piaware.Aircraft{Hex: "4ca7b5", Callsign: "BAW286", TypeCode: "B744", Squawk: "2301", GS: 180, GeomRate: -700, Country: "United Kingdom"},
+		DistanceKm: 12.5,
+		Altitude:   8000,
+	}
+	fighter := &Subject{
+		Aircraft:   piaware.Aircraft{Hex: "ae01ce", TypeCode: "F16", Military: true},
+		DistanceKm: 40,
+		Altitude:   25000,
+	}
+
+	tests := []struct {
+		expr    string
+		jumbo   bool
+		fighter bool
+	}{
+		{`type in ["B744","A388"] && alt < 10000 && distance < 20`, true, false},
+		{`type in ['b744'] && alt < 5000`, false, false},
+		{`type not in ["B744"]`, false, true},
+		{`military`, false, true},
+		{`!military && !on_ground`, true, false},
+		{`military == true || distance <= 12.5`, true, true},
+		{`alt >= 25000 || (type == "b744" && vrate < -500)`, true, true},
+		{`callsign =~ "^BAW[0-9]+$"`, true, false},
+		{`country != "United Kingdom"`, false, true},
+		{`speed > 150 && speed != 0`, true, false},
+		{`alt in [8000, 25000]`, true, true},
+		{`distance > -1.5`, true, true},
+		{`squawk == ""`, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			e, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile() error = %v", err)
+			}
+			if got := e.Match(jumbo); got != tt.jumbo {
+				t.Errorf("jumbo: Match() = %v, want %v", got, tt.jumbo)
+			}
+			if got := e.Match(fighter); got != tt.fighter {
+				t.Errorf("fighter: Match() = %v, want %v", got, tt.fighter)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{``, "column 1: unexpected end of expression"},
+		{`alt`, "expression is a number, not a condition"},
+		{`altitude < 1000`, `column 1: unknown field "altitude"`},
+		{`alt < "high"`, "column 5: cannot compare number with string"},
+		{`type < "B744"`, "column 6: < cannot be used with strings"},
+		{`type in ["B744", 747]`, "column 18: cannot look for a string in a list of numbers"},
+		{`type in [callsign]`, "column 10: lists may only hold numbers and strings"},
+		{`military in [1]`, "cannot look for a bool in a list of numbers"},
+		{`type in "B744"`, `column 9: expected "[", found "B744"`},
+		{`alt < 1000 && distance`, "column 12: && needs conditions on both sides"},
+		{`!alt`, "column 1: ! needs a condition"},
+		{`(alt < 1000`, `expected ")", found end of expression`},
+		{`alt < 1000 distance`, `column 12: unexpected "distance"`},
+		{`callsign =~ "("`, "column 13: invalid pattern"},
+		{`alt =~ "1"`, "column 5: =~ needs a string on the left"},
+		{`type == "B744`, "unterminated string at column 9"},
+		{`alt < 10 # comment`, `unexpected '#' at column 10`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Compile(%q) error = %v, want %q", tt.expr, err, tt.want)
+			}
+		})
+	}
+}
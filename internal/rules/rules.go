@@ -0,0 +1,144 @@
+// Package rules selects the aircraft to alert on with named rules, each a
+// small boolean expression over the aircraft's fields such as
+// `type in ["B744","A388"] && alt < 10000 && distance < 20`.
+package rules
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// Rule severities.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Rule is a named condition that raises alerts for the aircraft matching it.
+type Rule struct {
+	Name string
+	// When is the expression aircraft must match; see Compile.
+	When string
+	// Severity is SeverityInfo (the default), SeverityWarning or
+	// SeverityCritical.
+	Severity string
+	// Notifiers names the notifiers the rule's alerts are sent to; empty
+	// sends them to all.
+	Notifiers []string
+	// BlockoutMin is the rule's own alert deduplication blockout; zero uses
+	// the global blockout.
+	BlockoutMin time.Duration
+}
+
+// Compiled is a rule ready to be evaluated.
+type Compiled struct {
+	Rule
+	Expr *Expr
+}
+
+// CompileAll compiles the rules, filling in the default severity. Rule
+// names must be present and unique, and notifiers must be among available.
+func CompileAll(rules []Rule, available []string) ([]*Compiled, error) {
+	compiled := make([]*Compiled, 0, len(rules))
+	names := make(map[string]bool, len(rules))
+	for i, r := range rules {
+		if r.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i+1)
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("rule %q is defined twice", r.Name)
+		}
+		names[r.Name] = true
+
+		switch r.Severity {
+		case "":
+			r.Severity = SeverityInfo
+		case SeverityInfo, SeverityWarning, SeverityCritical:
+		default:
+			return nil, fmt.Errorf("rule %q: unknown severity %q", r.Name, r.Severity)
+		}
+		for _, n := range r.Notifiers {
+			if !slices.Contains(available, n) {
+				return nil, fmt.Errorf("rule %q: notifier %q is not enabled", r.Name, n)
+			}
+		}
+		if r.When == "" {
+			return nil, fmt.Errorf("rule %q has no condition", r.Name)
+		}
+		expr, err := Compile(r.When)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: %w", r.Name, err)
+		}
+		compiled = append(compiled, &Compiled{Rule: r, Expr: expr})
+	}
+	if len(compiled) == 0 {
+		return nil, errors.New("no rules")
+	}
+	return compiled, nil
+}
+
+// Subject is an aircraft a rule is evaluated against, with what the monitor
+// measured of it.
+type Subject struct {
+	Aircraft piaware.Aircraft
+	// DistanceKm is the distance from the base, or NaN when the aircraft
+	// has no position, so that comparisons with it fail.
+	DistanceKm float64
+	// Altitude is the altitude in feet from the configured source.
+	Altitude int
+	// ElevationDeg is the angle above the observer's horizon, or NaN when
+	// the aircraft has no position.
+	ElevationDeg float64
+}
+
+func number(f func(*Subject) float64) node {
+	return node{kind: kindNumber, n: f}
+}
+
+func text(f func(*Subject) string) node {
+	return node{kind: kindString, s: f}
+}
+
+func boolean(f func(*Subject) bool) node {
+	return node{kind: kindBool, b: f}
+}
+
+// fields are the names rules can use. Fields the aircraft does not report
+// are zero or empty.
+var fields = map[string]node{
+	"hex":          text(func(s *Subject) string { return s.Aircraft.Hex }),
+	"callsign":     text(func(s *Subject) string { return s.Aircraft.Callsign }),
+	"type":         text(func(s *Subject) string { return s.Aircraft.TypeCode }),
+	"reg":          text(func(s *Subject) string { return s.Aircraft.Registration }),
+	"category":     text(func(s *Subject) string { return s.Aircraft.Category }),
+	"squawk":       text(func(s *Subject) string { return s.Aircraft.Squawk }),
+	"emergency":    text(func(s *Subject) string { return s.Aircraft.EmergencyStatus() }),
+	"country":      text(func(s *Subject) string { return s.Aircraft.Country }),
+	"airline":      text(func(s *Subject) string { return s.Aircraft.Airline }),
+	"operator":     text(func(s *Subject) string { return s.Aircraft.Operator }),
+	"origin":       text(func(s *Subject) string { return s.Aircraft.Origin }),
+	"destination":  text(func(s *Subject) string { return s.Aircraft.Destination }),
+	"alt":          number(func(s *Subject) float64 { return float64(s.Altitude) }),
+	"distance":     number(func(s *Subject) float64 { return s.DistanceKm }),
+	"elevation":    number(func(s *Subject) float64 { return s.ElevationDeg }),
+	"speed":        number(func(s *Subject) float64 { return s.Aircraft.GS }),
+	"track":        number(func(s *Subject) float64 { return s.Aircraft.Track }),
+	"vrate":        number(vrate),
+	"position_age": number(func(s *Subject) float64 { return s.Aircraft.PositionAge }),
+	"military":     boolean(func(s *Subject) bool { return s.Aircraft.Military }),
+	"on_ground":    boolean(func(s *Subject) bool { return s.Aircraft.OnGround }),
+}
+
+// vrate is the barometric rate of climb in feet per minute, or the
+// geometric one for aircraft that only report that.
+func vrate(s *Subject) float64 {
+	if s.Aircraft.BaroRate != 0 {
+		return float64(s.Aircraft.BaroRate)
+	}
+	return float64(s.Aircraft.GeomRate)
+}
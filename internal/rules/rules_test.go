@@ -0,0 +1,44 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileAll(t *testing.T) {
+	compiled, err := CompileAll([]Rule{
+		{Name: "heavies", When: `type in ["B744","A388"] && alt < 10000`, Severity: SeverityWarning, Notifiers: []string{"webhook"}},
+		{Name: "low", When: `alt < 1000 && !on_ground`},
+	}, []string{"console", "webhook"})
+	if err != nil {
+		t.Fatalf("CompileAll() error = %v", err)
+	}
+	if len(compiled) != 2 || compiled[0].Severity != SeverityWarning || compiled[1].Severity != SeverityInfo {
+		t.Errorf("unexpected rules %+v", compiled)
+	}
+	if compiled[1].Expr.String() != `alt < 1000 && !on_ground` {
+		t.Errorf("unexpected expression %q", compiled[1].Expr)
+	}
+
+	tests := []struct {
+		name  string
+		rules []Rule
+		want  string
+	}{
+		{"none", nil, "no rules"},
+		{"no name", []Rule{{When: "military"}}, "rule 1 has no name"},
+		{"duplicate", []Rule{{Name: "a", When: "military"}, {Name: "a", When: "military"}}, `rule "a" is defined twice`},
+		{"no condition", []Rule{{Name: "a"}}, `rule "a" has no condition`},
+		{"bad severity", []Rule{{Name: "a", When: "military", Severity: "urgent"}}, `rule "a": unknown severity "urgent"`},
+		{"notifier not enabled", []Rule{{Name: "a", When: "military", Notifiers: []string{"rabbitmq"}}}, `rule "a": notifier "rabbitmq" is not enabled`},
+		{"bad expression", []Rule{{Name: "a", When: "alt <"}}, `rule "a": column 6: unexpected end of expression`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := CompileAll(tt.rules, []string{"console", "webhook"})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CompileAll() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
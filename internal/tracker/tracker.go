@@ -0,0 +1,192 @@
+// Package tracker follows aircraft through the area of interest across
+// monitoring cycles and turns each flyover into a short lifecycle of
+// events: entered, closest approach and exited.
+package tracker
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// Event types emitted over the life of a track.
+const (
+	EventEntered         = "aircraft_entered"
+	EventClosestApproach = "aircraft_closest_approach"
+	EventExited          = "aircraft_exited"
+)
+
+const (
+	// defaultExitAfter is used when Config.ExitAfter is not set.
+	defaultExitAfter = time.Minute
+	// closestApproachMargin is how far (km) an aircraft must move away from
+	// its closest point before the approach is reported, so position noise
+	// does not report it early.
+	closestApproachMargin = 0.1
+	// maxTrackPoints bounds the history kept for a single track.
+	maxTrackPoints = 1000
+)
+
+// Config holds tracker settings.
+type Config struct {
+	Enabled bool
+	// ExitAfter is how long an aircraft must be missing from the area
+	// before its track is closed.
+	ExitAfter time.Duration
+}
+
+// Point is one observation of a tracked aircraft.
+type Point struct {
+	Time       time.Time
+	Lat        float64
+	Lon        float64
+	AltBaro    int
+	DistanceKm float64
+}
+
+// Track is the history of one aircraft's pass through the area.
+type Track struct {
+	ID        string
+	Hex       string
+	EnteredAt time.Time
+	LastSeen  time.Time
+	Points    []Point
+
+	// Closest is the aircraft state at the closest approach so far.
+	Closest         piaware.NearbyAircraft
+	closestReported bool
+	last            piaware.NearbyAircraft
+}
+
+// Dwell is the time between the first and last observation of the track.
+func (t *Track) Dwell() time.Duration {
+	return t.LastSeen.Sub(t.EnteredAt)
+}
+
+// Event is a lifecycle event for a track.
+type Event struct {
+	Type     string
+	TrackID  string
+	Time     time.Time
+	Aircraft piaware.NearbyAircraft
+	// MinDistanceKm is the closest approach so far; for exits, the closest
+	// approach of the whole pass.
+	MinDistanceKm float64
+	// Dwell is how long the aircraft was in the area. It is set for exits.
+	Dwell time.Duration
+}
+
+// Tracker keeps the active track of every aircraft in the area.
+type Tracker struct {
+	exitAfter time.Duration
+	tracks    map[string]*Track
+}
+
+// New creates a tracker.
+func New(cfg Config) *Tracker {
+	exitAfter := cfg.ExitAfter
+	if exitAfter <= 0 {
+		exitAfter = defaultExitAfter
+	}
+	return &Tracker{
+		exitAfter: exitAfter,
+		tracks:    make(map[string]*Track),
+	}
+}
+
+// Update records the aircraft currently in the area, observed at the given
+// time, and returns the lifecycle events that result. Events are ordered by
+// hex, with a track's events in lifecycle order.
+func (t *Tracker) Update(at time.Time, nearby []piaware.NearbyAircraft) []Event {
+	var events []Event
+	present := make(map[string]bool, len(nearby))
+
+	for _, a := range nearby {
+		present[a.Hex] = true
+
+		track, ok := t.tracks[a.Hex]
+		if !ok {
+			track = &Track{
+				ID:        fmt.Sprintf("%s-%d", a.Hex, at.Unix()),
+				Hex:       a.Hex,
+				EnteredAt: at,
+				Closest:   a,
+			}
+			t.tracks[a.Hex] = track
+			events = append(events, Event{
+				Type:          EventEntered,
+				TrackID:       track.ID,
+				Time:          at,
+				Aircraft:      a,
+				MinDistanceKm: a.DistanceKm,
+			})
+		}
+
+		track.LastSeen = at
+		track.last = a
+		track.Points = append(track.Points, Point{
+			Time:       at,
+			Lat:        a.Lat,
+			Lon:        a.Lon,
+			AltBaro:    a.AltBaro,
+			DistanceKm: a.DistanceKm,
+		})
+		if len(track.Points) > maxTrackPoints {
+			track.Points = track.Points[len(track.Points)-maxTrackPoints:]
+		}
+
+		if a.DistanceKm < track.Closest.DistanceKm {
+			track.Closest = a
+		} else if !track.closestReported && a.DistanceKm > track.Closest.DistanceKm+closestApproachMargin {
+			track.closestReported = true
+			events = append(events, closestApproach(track, at))
+		}
+	}
+
+	for hex, track := range t.tracks {
+		if present[hex] || at.Sub(track.LastSeen) < t.exitAfter {
+			continue
+		}
+		if !track.closestReported {
+			events = append(events, closestApproach(track, at))
+		}
+		events = append(events, Event{
+			Type:          EventExited,
+			TrackID:       track.ID,
+			Time:          at,
+			Aircraft:      track.last,
+			MinDistanceKm: track.Closest.DistanceKm,
+			Dwell:         track.Dwell(),
+		})
+		delete(t.tracks, hex)
+	}
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Aircraft.Hex < events[j].Aircraft.Hex
+	})
+	return events
+}
+
+// Track returns the active track for hex, if any.
+func (t *Tracker) Track(hex string) (*Track, bool) {
+	track, ok := t.tracks[hex]
+	return track, ok
+}
+
+// Active returns the number of active tracks.
+func (t *Tracker) Active() int {
+	return len(t.tracks)
+}
+
+// closestApproach builds the closest approach event for a track.
+func closestApproach(track *Track, at time.Time) Event {
+	return Event{
+		Type:          EventClosestApproach,
+		TrackID:       track.ID,
+		Time:          at,
+		Aircraft:      track.Closest,
+		MinDistanceKm: track.Closest.DistanceKm,
+	}
+}
@@ -0,0 +1,135 @@
+package tracker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func near(hex string, distanceKm float64) piaware.NearbyAircraft {
+	return piaware.NearbyAircraft{
+		Aircraft:   piaware.Aircraft{Hex: hex, Lat: 40.7, Lon: -74.0},
+		DistanceKm: distanceKm,
+	}
+}
+
+func eventTypes(events []Event) []string {
+	types := make([]string, len(events))
+	for i, e := range events {
+		types[i] = e.Type
+	}
+	return types
+}
+
+func equalTypes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTrackerLifecycle(t *testing.T) {
+	tr := New(Config{ExitAfter: time.Minute})
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	steps := []struct {
+		offset time.Duration
+		nearby []piaware.NearbyAircraft
+		want   []string
+	}{
+		{0, []piaware.NearbyAircraft{near("aaa111", 20)}, []string{EventEntered}},
+		{30 * time.Second, []piaware.NearbyAircraft{near("aaa111", 10)}, nil},
+		{60 * time.Second, []piaware.NearbyAircraft{near("aaa111", 4)}, nil},
+		{90 * time.Second, []piaware.NearbyAircraft{near("aaa111", 4.05)}, nil}, // within noise margin
+		{120 * time.Second, []piaware.NearbyAircraft{near("aaa111", 9)}, []string{EventClosestApproach}},
+		{150 * time.Second, []piaware.NearbyAircraft{near("aaa111", 18)}, nil},
+		{180 * time.Second, nil, nil}, // missing, but not long enough to exit
+		{210 * time.Second, nil, []string{EventExited}},
+		{240 * time.Second, nil, nil},
+	}
+
+	var trackID string
+	for i, step := range steps {
+		events := tr.Update(start.Add(step.offset), step.nearby)
+		if got := eventTypes(events); !equalTypes(got, step.want) {
+			t.Fatalf("step %d: expected events %v, got %v", i, step.want, got)
+		}
+
+		for _, e := range events {
+			if trackID == "" {
+				trackID = e.TrackID
+			}
+			if e.TrackID != trackID {
+				t.Errorf("step %d: expected shared track ID %s, got %s", i, trackID, e.TrackID)
+			}
+			switch e.Type {
+			case EventClosestApproach:
+				if e.MinDistanceKm != 4 || e.Aircraft.DistanceKm != 4 {
+					t.Errorf("expected closest approach of 4 km, got %v", e.MinDistanceKm)
+				}
+			case EventExited:
+				if e.MinDistanceKm != 4 {
+					t.Errorf("expected exit to carry the minimum distance, got %v", e.MinDistanceKm)
+				}
+				if e.Dwell != 150*time.Second {
+					t.Errorf("expected dwell of 150s, got %v", e.Dwell)
+				}
+				if e.Aircraft.DistanceKm != 18 {
+					t.Errorf("expected exit to carry the last seen state, got %v km", e.Aircraft.DistanceKm)
+				}
+			}
+		}
+	}
+
+	if trackID != "aaa111-1714564800" {
+		t.Errorf("unexpected track ID %s", trackID)
+	}
+	if tr.Active() != 0 {
+		t.Errorf("expected no active tracks, got %d", tr.Active())
+	}
+}
+
+func TestTrackerReportsApproachOnExit(t *testing.T) {
+	tr := New(Config{ExitAfter: time.Minute})
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tr.Update(start, []piaware.NearbyAircraft{near("aaa111", 20)})
+	tr.Update(start.Add(30*time.Second), []piaware.NearbyAircraft{near("aaa111", 15)})
+
+	track, ok := tr.Track("aaa111")
+	if !ok || len(track.Points) != 2 {
+		t.Fatalf("expected a track with 2 points, got %+v", track)
+	}
+
+	// Still approaching when it drops out of the area: the closest point
+	// seen is reported before the exit.
+	events := tr.Update(start.Add(2*time.Minute), nil)
+	if got := eventTypes(events); !equalTypes(got, []string{EventClosestApproach, EventExited}) {
+		t.Fatalf("expected closest approach then exit, got %v", got)
+	}
+	if events[0].MinDistanceKm != 15 {
+		t.Errorf("expected closest approach of 15 km, got %v", events[0].MinDistanceKm)
+	}
+}
+
+func TestTrackerNewTrackAfterExit(t *testing.T) {
+	tr := New(Config{})
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	first := tr.Update(start, []piaware.NearbyAircraft{near("aaa111", 5)})
+	tr.Update(start.Add(5*time.Minute), nil)
+	second := tr.Update(start.Add(10*time.Minute), []piaware.NearbyAircraft{near("aaa111", 5)})
+
+	if len(first) != 1 || len(second) != 1 || second[0].Type != EventEntered {
+		t.Fatalf("expected a second entry, got %v and %v", eventTypes(first), eventTypes(second))
+	}
+	if first[0].TrackID == second[0].TrackID {
+		t.Error("expected a new track ID for a new pass")
+	}
+}
@@ -0,0 +1,92 @@
+// Package watchlist picks out particular aircraft, by ICAO hex,
+// registration or callsign pattern, wherever they are in the feed.
+package watchlist
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+// Config holds the watchlist.
+type Config struct {
+	// Hexes are ICAO addresses. A non-ICAO address, such as a TIS-B target,
+	// is only matched when listed with the "~" prefix readsb gives it.
+	Hexes         []string
+	Registrations []string
+	// Callsigns are regular expressions matched against the callsign,
+	// ignoring case and padding.
+	Callsigns []string
+	// BlockoutMin is how long a watched aircraft is not alerted again after
+	// it was seen; zero uses the global blockout.
+	BlockoutMin time.Duration
+}
+
+// Empty reports whether nothing is being watched.
+func (c Config) Empty() bool {
+	return len(c.Hexes) == 0 && len(c.Registrations) == 0 && len(c.Callsigns) == 0
+}
+
+// List is a compiled watchlist.
+type List struct {
+	hexes         map[string]bool
+	registrations map[string]bool
+	callsigns     []*regexp.Regexp
+}
+
+// New compiles the watchlist in cfg.
+func New(cfg Config) (*List, error) {
+	l := &List{
+		hexes:         make(map[string]bool, len(cfg.Hexes)),
+		registrations: make(map[string]bool, len(cfg.Registrations)),
+	}
+	for _, hex := range cfg.Hexes {
+		l.hexes[normalizeHex(hex)] = true
+	}
+	for _, reg := range cfg.Registrations {
+		l.registrations[normalizeRegistration(reg)] = true
+	}
+	for _, pattern := range cfg.Callsigns {
+		re, err := regexp.Compile("(?i)" + pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid callsign pattern %q: %w", pattern, err)
+		}
+		l.callsigns = append(l.callsigns, re)
+	}
+	return l, nil
+}
+
+// Match reports whether the aircraft is on the watchlist, and why, such as
+// "registration N12345". Hexes are checked first, then registrations and
+// then callsigns.
+func (l *List) Match(a piaware.Aircraft) (string, bool) {
+	if l.hexes[normalizeHex(a.Hex)] {
+		return "hex " + normalizeHex(a.Hex), true
+	}
+	if a.Registration != "" && l.registrations[normalizeRegistration(a.Registration)] {
+		return "registration " + a.Registration, true
+	}
+	if callsign := strings.TrimSpace(a.Flight); callsign != "" {
+		for _, re := range l.callsigns {
+			if re.MatchString(callsign) {
+				return "callsign " + callsign, true
+			}
+		}
+	}
+	return "", false
+}
+
+// normalizeHex lower-cases a hex, keeping the "~" of non-ICAO addresses so
+// they never match the airframe with the same ICAO address.
+func normalizeHex(hex string) string {
+	return strings.ToLower(strings.TrimSpace(hex))
+}
+
+// normalizeRegistration upper-cases a registration and drops the dash, as
+// registrations are written both with and without one.
+func normalizeRegistration(reg string) string {
+	return strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(reg), "-", ""))
+}
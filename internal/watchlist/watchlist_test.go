@@ -0,0 +1,62 @@
+package watchlist
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/benvon/whats-flying-over-me/internal/piaware"
+)
+
+func TestMatch(t *testing.T) {
+	list, err := New(Config{
+		Hexes:         []string{"A1B2C3", "~c0ffee"},
+		Registrations: []string{"n12345", "G-EUPT"},
+		Callsigns:     []string{"^AF1$", "^(SAM|VENUS)[0-9]+$"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		aircraft piaware.Aircraft
+		want     string
+		ok       bool
+	}{
+		{"hex", piaware.Aircraft{Hex: "a1b2c3"}, "hex a1b2c3", true},
+		{"non-ICAO hex", piaware.Aircraft{Hex: "~a1b2c3"}, "", false},
+		{"listed non-ICAO hex", piaware.Aircraft{Hex: "~C0FFEE"}, "hex ~c0ffee", true},
+		{"registration", piaware.Aircraft{Hex: "a00001", Registration: "N12345"}, "registration N12345", true},
+		{"registration without dash", piaware.Aircraft{Hex: "406a93", Registration: "GEUPT"}, "registration GEUPT", true},
+		{"callsign", piaware.Aircraft{Hex: "adfdf8", Flight: "af1     "}, "callsign af1", true},
+		{"callsign pattern", piaware.Aircraft{Hex: "adfdf9", Flight: "SAM28000"}, "callsign SAM28000", true},
+		{"partial callsign", piaware.Aircraft{Hex: "adfdfa", Flight: "AF12"}, "", false},
+		{"hex before callsign", piaware.Aircraft{Hex: "a1b2c3", Flight: "AF1"}, "hex a1b2c3", true},
+		{"not watched", piaware.Aircraft{Hex: "ffffff", Flight: "UAL123", Registration: "N54321"}, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := list.Match(tt.aircraft)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Match() = %q, %v; want %q, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	_, err := New(Config{Callsigns: []string{"(AF1"}})
+	if err == nil || !strings.Contains(err.Error(), `invalid callsign pattern "(AF1"`) {
+		t.Errorf("New() error = %v", err)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	if !(Config{}).Empty() {
+		t.Error("expected an empty watchlist")
+	}
+	if (Config{Callsigns: []string{"^AF1$"}}).Empty() {
+		t.Error("expected a non-empty watchlist")
+	}
+}